@@ -1,6 +1,13 @@
 package flow
 
 import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"time"
+
 	"github.com/unitoftime/ecs"
 )
 
@@ -14,42 +21,401 @@ import (
 // 	StageUpdate
 // )
 
+// App runs its own frame loop, so it doesn't put an ecs.Scheduler resource into the world. Code that used the scheduler resource should move to:
+//   - scheduler.SetFixedTimeStep: App.SetFixedTimeStep
+//   - scheduler.SetGameSpeed: Time.SetScale, using the Time resource
+//   - scheduler.GetRenderInterp: FixedTime.Alpha, using the FixedTime resource
+//   - scheduler.SetQuit: App.Exit, using the App resource
 type App struct {
-	world     *ecs.World
-	scheduler *ecs.Scheduler
+	world    *ecs.World
+	schedule *schedule
+
+	plugins      []Plugin
+	pluginKeys   map[string]bool
+	pluginErrors []error
+	replacements map[string]Plugin
+	initialized  []Plugin
+	systems      []stageSystems
+	built        bool
+	started      bool
+	quit         atomic.Bool
+
+	startupHooks []func(world *ecs.World)
+	exitHooks    []func(world *ecs.World)
+}
+
+type stageSystems struct {
+	stage   ecs.Stage
+	systems []ecs.SystemBuilder
 }
 
 func NewApp() *App {
 	world := ecs.NewWorld()
-	scheduler := ecs.NewScheduler(world)
-	ecs.PutResource(world, scheduler)
-	// scheduler.SetFixedTimeStep(4 * time.Millisecond)
 
 	app := &App{
-		world:     world,
-		scheduler: scheduler,
+		world:        world,
+		schedule:     newSchedule(),
+		pluginKeys:   make(map[string]bool),
+		replacements: make(map[string]Plugin),
 	}
+	ecs.PutResource(world, app)
+	ecs.PutResource(world, app.schedule.fixedTime)
+	ecs.PutResource(world, app.schedule.time)
+	ecs.PutResource(world, NewRng(time.Now().UnixNano()))
+
+	return app
+}
 
+// Returns the app stored in the world's resources. Panics if the world wasn't created by NewApp. Plugins can use this to add their systems from Initialize:
+//
+//	func (p Plugin) Initialize(world *ecs.World) {
+//		app := flow.MustApp(world)
+//		app.AddSystems(ecs.StageFixedUpdate, ...)
+//	}
+func MustApp(world *ecs.World) *App {
+	app := ecs.GetResource[App](world)
+	if app == nil {
+		panic("flow: world has no flow.App resource, use flow.NewApp to create your world")
+	}
 	return app
 }
 
+func (a *App) World() *ecs.World {
+	return a.world
+}
+
+// Initializes all of the added plugins in dependency order, then adds all of the queued systems. This is automatically called by Run, but you can call it yourself if you want to check for plugin errors first.
+func (a *App) Build() error {
+	if a.built {
+		return nil
+	}
+
+	err := errors.Join(a.pluginErrors...)
+	if err != nil {
+		return err
+	}
+
+	ordered, err := orderPlugins(a.plugins)
+	if err != nil {
+		return err
+	}
+
+	a.built = true
+	for _, plugin := range ordered {
+		plugin.Initialize(a.world)
+		a.initialized = append(a.initialized, plugin)
+	}
+
+	for _, s := range a.systems {
+		a.schedule.add(a.world, s.stage, s.systems...)
+	}
+	a.systems = nil
+
+	return nil
+}
+
+// Builds the app, then runs the startup hooks and startup systems. This only happens once.
+func (a *App) start() {
+	if a.started {
+		return
+	}
+
+	err := a.Build()
+	if err != nil {
+		panic(err)
+	}
+	a.started = true
+
+	for _, hook := range a.startupHooks {
+		hook(a.world)
+	}
+	a.schedule.runStartup()
+}
+
+// Returns true if an exit has been requested
+func (a *App) Exiting() bool {
+	return a.quit.Load()
+}
+
+// Runs the app in real time until an exit is requested
 func (a *App) Run() {
 	// fmt.Printf("%+v", a.startupSystems)
-	// fmt.Printf("%+v", a.scheduler)
 
-	a.scheduler.Run()
+	a.start()
+
+	frameStart := time.Now()
+	dt := a.schedule.fixedTimeStep
+	for !a.Exiting() {
+		a.schedule.runFrame(dt)
+
+		// Edge case for schedules with only fixed time steps (ie servers). Sleep until the next fixed step rather than spinning
+		if a.schedule.onlyFixed() {
+			// Note: This is guaranteed to be positive because the fixed loop runs until the accumulator is less than the fixed time step
+			time.Sleep(a.schedule.fixedTimeStep - a.schedule.accumulator)
+		}
+
+		now := time.Now()
+		dt = now.Sub(frameStart)
+		frameStart = now
+	}
+
+	a.shutdown()
+}
+
+// Runs the app without any real time sleeping. Every frame is simulated with the same dt. The app runs until an exit is requested, or until the number of frames has been run (if frames <= 0 it runs until an exit is requested).
+// This is useful for dedicated servers and for integration tests.
+func (a *App) RunHeadless(frames int, dt time.Duration) {
+	a.start()
+
+	for i := 0; frames <= 0 || i < frames; i++ {
+		if a.Exiting() {
+			break
+		}
+		a.schedule.runFrame(dt)
+	}
+
+	a.shutdown()
+}
+
+// Runs a single frame of the app using dt as the frame time. The first call also builds and starts the app. This lets tests step the app frame by frame and inspect the world in between.
+func (a *App) Step(dt time.Duration) {
+	a.start()
+	a.schedule.runFrame(dt)
+}
+
+// Sets the amount of time simulated by each run of the fixed update stage. Panics if dt isn't positive.
+func (a *App) SetFixedTimeStep(dt time.Duration) {
+	if dt <= 0 {
+		panic(fmt.Sprintf("flow: fixed time step must be positive, got %v", dt))
+	}
+	a.schedule.fixedTimeStep = dt
+}
+
+// Sets the longest amount of time that a single frame can advance the app by. Longer frames are clamped to this, which prevents the fixed update stage from falling into a spiral of death. The default value is 250ms, a value of 0 disables the clamp.
+func (a *App) SetMaxFrameTime(dt time.Duration) {
+	a.schedule.maxFrameTime = dt
+}
+
+// Sets the maximum number of fixed updates that can run in a single frame. If the app falls further behind than this, the extra fixed updates are dropped. The default value is 0, which will force every fixed update to run.
+func (a *App) SetMaxFixedLoopCount(count int) {
+	a.schedule.maxLoopCount = count
+}
+
+// Sets the profiler which is notified of the time spent in every system, stage, and frame. Set it to nil to disable profiling.
+func (a *App) SetProfiler(profiler Profiler) {
+	a.schedule.profiler = profiler
+}
+
+// Runs all of the exit hooks, then notifies plugins in the reverse order that they were initialized
+func (a *App) shutdown() {
+	for _, hook := range a.exitHooks {
+		hook(a.world)
+	}
+
+	for i := len(a.initialized) - 1; i >= 0; i-- {
+		exiter, ok := a.initialized[i].(PluginExit)
+		if !ok {
+			continue
+		}
+		exiter.Exit(a.world)
+	}
+}
+
+// Requests that the app exits. The app will finish executing the current frame, then run its exit hooks.
+// Systems can access this by injecting the app resource: `func(dt time.Duration, app *flow.App)`
+func (a *App) Exit() {
+	a.quit.Store(true)
+}
+
+// Adds a hook which runs once after the app is built, before any startup systems run
+func (a *App) OnStartup(hook func(world *ecs.World)) {
+	a.startupHooks = append(a.startupHooks, hook)
+}
+
+// Adds a hook which runs at the start of every frame, before any systems run. The hook receives the frame's dt after it has been clamped by the max frame time. Time.RealDelta holds the raw frame time.
+func (a *App) OnFrameStart(hook func(world *ecs.World, dt time.Duration)) {
+	a.schedule.first = append(a.schedule.first, func(dt time.Duration) {
+		hook(a.world, dt)
+	})
+}
+
+// Adds a hook which runs once when the app exits. Exit hooks run before plugins are notified of the exit.
+func (a *App) OnExit(hook func(world *ecs.World)) {
+	a.exitHooks = append(a.exitHooks, hook)
 }
 
 type Plugin interface {
 	Initialize(world *ecs.World)
 }
 
+// Plugins can optionally implement this to declare the plugins that must be initialized before them. Every dependency must also be added to the app.
+type PluginDependencies interface {
+	Dependencies() []Plugin
+}
+
+// Plugins can optionally implement this to be notified when the app exits
+type PluginExit interface {
+	Exit(world *ecs.World)
+}
+
+// Plugins are identified by their type, so adding two plugins of the same type is an error. Plugins that can be added more than once (for example, one per file path) can implement this to give each instance a unique name.
+type PluginName interface {
+	PluginName() string
+}
+
+func pluginKey(plugin Plugin) string {
+	named, ok := plugin.(PluginName)
+	if ok {
+		return named.PluginName()
+	}
+	return reflect.TypeOf(plugin).String()
+}
+
+// Replaces any plugin with the same key as target (whether it was added before or after this call) with the replacement. The replacement takes on the identity of the target, so plugins that depend on the target are still satisfied.
+// This must be called before the app is built.
+func (a *App) ReplacePlugin(target Plugin, replacement Plugin) {
+	key := pluginKey(target)
+	a.replacements[key] = replacement
+
+	for i := range a.plugins {
+		if pluginKey(a.plugins[i]) == key {
+			a.plugins[i] = replacedPlugin{key, replacement}
+		}
+	}
+}
+
+// Replaces the target plugin with a plugin that does nothing. For example, you can disable rendering in tests with: `app.DisablePlugin(render.DefaultPlugin{})`
+func (a *App) DisablePlugin(target Plugin) {
+	a.ReplacePlugin(target, NoopPlugin{})
+}
+
+// NoopPlugin is a plugin that does nothing
+type NoopPlugin struct{}
+
+func (p NoopPlugin) Initialize(world *ecs.World) {}
+
+// Wraps a replacement plugin so that it keeps the key of the plugin that it replaced
+type replacedPlugin struct {
+	key string
+	Plugin
+}
+
+func (p replacedPlugin) PluginName() string {
+	return p.key
+}
+
+func (p replacedPlugin) Dependencies() []Plugin {
+	deps, ok := p.Plugin.(PluginDependencies)
+	if !ok {
+		return nil
+	}
+	return deps.Dependencies()
+}
+
+func (p replacedPlugin) Exit(world *ecs.World) {
+	exiter, ok := p.Plugin.(PluginExit)
+	if !ok {
+		return
+	}
+	exiter.Exit(world)
+}
+
+// Adds a plugin to the app. Plugins are initialized when the app is built, after all of their dependencies have been initialized.
+// If the app has already been built, the plugin is initialized immediately, and it panics if the plugin is a duplicate or its dependencies are missing.
 func (a *App) AddPlugin(plugin Plugin) {
-	plugin.Initialize(a.world)
+	key := pluginKey(plugin)
+	replacement, ok := a.replacements[key]
+	if ok {
+		plugin = replacedPlugin{key, replacement}
+	}
+	if a.pluginKeys[key] {
+		err := fmt.Errorf("duplicate plugin: %s", key)
+		if a.built {
+			panic(err) // Note: Build has already returned, so there is nothing left to report the error
+		}
+		a.pluginErrors = append(a.pluginErrors, err)
+		return
+	}
+	a.pluginKeys[key] = true
+
+	if a.built {
+		// Note: We still verify dependencies for late plugins, we just can't reorder them
+		_, err := orderPlugins(append(a.initialized, plugin))
+		if err != nil {
+			panic(err)
+		}
+		plugin.Initialize(a.world)
+		a.initialized = append(a.initialized, plugin)
+		return
+	}
+
+	a.plugins = append(a.plugins, plugin)
 }
 
+// Sorts the plugins so that every plugin comes after its dependencies. Plugins otherwise keep the order they were added in.
+func orderPlugins(plugins []Plugin) ([]Plugin, error) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	byKey := make(map[string]Plugin, len(plugins))
+	for _, p := range plugins {
+		byKey[pluginKey(p)] = p
+	}
+
+	state := make(map[string]int, len(plugins))
+	ordered := make([]Plugin, 0, len(plugins))
+	var visit func(p Plugin, path []string) error
+	visit = func(p Plugin, path []string) error {
+		key := pluginKey(p)
+		path = append(path, key)
+		switch state[key] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("plugin dependency cycle: %s", strings.Join(path, " -> "))
+		}
+		state[key] = visiting
+
+		deps, ok := p.(PluginDependencies)
+		if ok {
+			for _, dep := range deps.Dependencies() {
+				depKey := pluginKey(dep)
+				registered, ok := byKey[depKey]
+				if !ok {
+					return fmt.Errorf("plugin %s depends on %s, which was not added", key, depKey)
+				}
+				err := visit(registered, path)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		state[key] = visited
+		ordered = append(ordered, p)
+		return nil
+	}
+
+	for _, p := range plugins {
+		err := visit(p, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+// Adds systems to the app. If the app hasn't been built yet, the systems are queued and added after all of the plugins are initialized.
 func (a *App) AddSystems(stage ecs.Stage, systems ...ecs.SystemBuilder) {
-	a.scheduler.AddSystems(stage, systems...)
+	if !a.built {
+		a.systems = append(a.systems, stageSystems{stage, systems})
+		return
+	}
+
+	a.schedule.add(a.world, stage, systems...)
 	// for _, sys := range systems {
 	// 	system := sys.Build(a.world)
 	// 	switch stage {
@@ -0,0 +1,312 @@
+package flow
+
+import (
+	"testing"
+	"time"
+
+	"github.com/unitoftime/ecs"
+)
+
+type orderLog struct {
+	order []string
+}
+
+type pluginA struct{ log *orderLog }
+
+func (p pluginA) Initialize(world *ecs.World) { p.log.order = append(p.log.order, "A") }
+func (p pluginA) Exit(world *ecs.World)       { p.log.order = append(p.log.order, "exitA") }
+
+type pluginB struct{ log *orderLog }
+
+func (p pluginB) Initialize(world *ecs.World) { p.log.order = append(p.log.order, "B") }
+func (p pluginB) Dependencies() []Plugin      { return []Plugin{pluginA{}} }
+func (p pluginB) Exit(world *ecs.World)       { p.log.order = append(p.log.order, "exitB") }
+
+type pluginC struct{ log *orderLog }
+
+func (p pluginC) Initialize(world *ecs.World) { p.log.order = append(p.log.order, "C") }
+func (p pluginC) Dependencies() []Plugin      { return []Plugin{pluginB{}} }
+
+type cycleA struct{}
+
+func (p cycleA) Initialize(world *ecs.World) {}
+func (p cycleA) Dependencies() []Plugin      { return []Plugin{cycleB{}} }
+
+type cycleB struct{}
+
+func (p cycleB) Initialize(world *ecs.World) {}
+func (p cycleB) Dependencies() []Plugin      { return []Plugin{cycleA{}} }
+
+func checkOrder(t *testing.T, got []string, want ...string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+}
+
+func TestPluginDependencyOrder(t *testing.T) {
+	log := &orderLog{}
+	app := NewApp()
+	app.AddPlugin(pluginC{log})
+	app.AddPlugin(pluginB{log})
+	app.AddPlugin(pluginA{log})
+
+	err := app.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkOrder(t, log.order, "A", "B", "C")
+
+	app.shutdown()
+	checkOrder(t, log.order, "A", "B", "C", "exitB", "exitA")
+}
+
+func TestPluginMissingDependency(t *testing.T) {
+	log := &orderLog{}
+	app := NewApp()
+	app.AddPlugin(pluginB{log})
+
+	err := app.Build()
+	if err == nil {
+		t.Fatal("expected missing dependency error")
+	}
+	if len(log.order) != 0 {
+		t.Errorf("expected no plugins to initialize, got %v", log.order)
+	}
+}
+
+func TestPluginDuplicate(t *testing.T) {
+	log := &orderLog{}
+	app := NewApp()
+	app.AddPlugin(pluginA{log})
+	app.AddPlugin(pluginA{log})
+
+	err := app.Build()
+	if err == nil {
+		t.Fatal("expected duplicate plugin error")
+	}
+}
+
+func TestPluginDuplicateAfterBuild(t *testing.T) {
+	log := &orderLog{}
+	app := NewApp()
+	app.AddPlugin(pluginA{log})
+	err := app.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected late duplicate plugin to panic")
+		}
+		checkOrder(t, log.order, "A")
+	}()
+	app.AddPlugin(pluginA{log})
+}
+
+func TestPluginCycle(t *testing.T) {
+	app := NewApp()
+	app.AddPlugin(cycleA{})
+	app.AddPlugin(cycleB{})
+
+	err := app.Build()
+	if err == nil {
+		t.Fatal("expected dependency cycle error")
+	}
+}
+
+func TestAppResource(t *testing.T) {
+	app := NewApp()
+	if ecs.GetResource[App](app.World()) != app {
+		t.Errorf("expected app to be stored as a resource")
+	}
+	if MustApp(app.World()) != app {
+		t.Errorf("expected MustApp to return the app")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected MustApp to panic without an app")
+		}
+	}()
+	MustApp(ecs.NewWorld())
+}
+
+type counters struct {
+	startup, preFixed, fixed, postFixed, update int
+}
+
+func newCountingApp() (*App, *counters) {
+	app := NewApp()
+	c := &counters{}
+	app.AddSystems(ecs.StageStartup, ecs.NewSystem(func(dt time.Duration) { c.startup++ }))
+	app.AddSystems(ecs.StagePreFixedUpdate, ecs.NewSystem(func(dt time.Duration) { c.preFixed++ }))
+	app.AddSystems(ecs.StageFixedUpdate, ecs.NewSystem(func(dt time.Duration) { c.fixed++ }))
+	app.AddSystems(ecs.StagePostFixedUpdate, ecs.NewSystem(func(dt time.Duration) { c.postFixed++ }))
+	app.AddSystems(ecs.StageUpdate, ecs.NewSystem(func(dt time.Duration) { c.update++ }))
+	return app, c
+}
+
+func TestStep(t *testing.T) {
+	app, c := newCountingApp()
+	app.SetFixedTimeStep(10 * time.Millisecond)
+
+	app.Step(10 * time.Millisecond)
+	if *c != (counters{1, 1, 1, 1, 1}) {
+		t.Errorf("unexpected counts after first step: %+v", *c)
+	}
+
+	// Two frames that only add up to one fixed step
+	app.Step(5 * time.Millisecond)
+	app.Step(5 * time.Millisecond)
+	if *c != (counters{1, 3, 2, 3, 3}) {
+		t.Errorf("unexpected counts after half steps: %+v", *c)
+	}
+
+	// One frame that spans three fixed steps
+	app.Step(30 * time.Millisecond)
+	if *c != (counters{1, 4, 5, 4, 4}) {
+		t.Errorf("unexpected counts after long step: %+v", *c)
+	}
+}
+
+func TestRunHeadless(t *testing.T) {
+	app, c := newCountingApp()
+	app.SetFixedTimeStep(10 * time.Millisecond)
+	exited := false
+	app.OnExit(func(world *ecs.World) { exited = true })
+
+	app.RunHeadless(5, 10*time.Millisecond)
+	if c.fixed != 5 || c.update != 5 {
+		t.Errorf("expected 5 frames, got %+v", *c)
+	}
+	if !exited {
+		t.Errorf("expected exit hook to run")
+	}
+}
+
+func TestRunHeadlessExit(t *testing.T) {
+	app, c := newCountingApp()
+	app.AddSystems(ecs.StageUpdate, ecs.NewSystem1(func(dt time.Duration, app *App) {
+		app.Exit()
+	}))
+
+	app.RunHeadless(0, 16*time.Millisecond)
+	if c.update != 1 {
+		t.Errorf("expected app to exit after one frame, got %+v", *c)
+	}
+}
+
+func TestDisablePlugin(t *testing.T) {
+	log := &orderLog{}
+	app := NewApp()
+	app.AddPlugin(pluginB{log})
+	app.AddPlugin(pluginA{log})
+	app.DisablePlugin(pluginA{})
+
+	err := app.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkOrder(t, log.order, "B")
+}
+
+func TestReplacePlugin(t *testing.T) {
+	log := &orderLog{}
+	app := NewApp()
+	app.ReplacePlugin(pluginA{}, pluginC{log})
+	app.AddPlugin(pluginA{log})
+
+	// pluginC depends on pluginB, which wasn't added
+	err := app.Build()
+	if err == nil {
+		t.Fatal("expected replacement dependencies to be checked")
+	}
+}
+
+func TestFixedTime(t *testing.T) {
+	app, c := newCountingApp()
+	app.SetFixedTimeStep(10 * time.Millisecond)
+	fixedTime := ecs.GetResource[FixedTime](app.World())
+
+	app.Step(15 * time.Millisecond)
+	if fixedTime.FrameTicks != 1 || fixedTime.Alpha != 0.5 {
+		t.Errorf("unexpected fixed time: %+v", *fixedTime)
+	}
+
+	// Long frames are clamped to prevent a spiral of death
+	var preFixedDt, updateDt time.Duration
+	app.AddSystems(ecs.StagePreFixedUpdate, ecs.NewSystem(func(dt time.Duration) { preFixedDt = dt }))
+	app.AddSystems(ecs.StageUpdate, ecs.NewSystem(func(dt time.Duration) { updateDt = dt }))
+	app.SetMaxFrameTime(50 * time.Millisecond)
+	app.Step(10 * time.Second)
+	if fixedTime.FrameTicks != 5 || c.fixed != 6 || fixedTime.Ticks != 6 {
+		t.Errorf("expected frame time to be clamped: %+v", *fixedTime)
+	}
+	if preFixedDt != 50*time.Millisecond || updateDt != 50*time.Millisecond {
+		t.Errorf("expected every stage to see the clamped frame time, got %v %v", preFixedDt, updateDt)
+	}
+}
+
+func TestFixedTimeStepMustBePositive(t *testing.T) {
+	for _, dt := range []time.Duration{0, -time.Millisecond} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%v: expected fixed time step to panic", dt)
+				}
+			}()
+			NewApp().SetFixedTimeStep(dt)
+		}()
+	}
+}
+
+func TestTime(t *testing.T) {
+	app, c := newCountingApp()
+	app.SetFixedTimeStep(10 * time.Millisecond)
+	clock := ecs.GetResource[Time](app.World())
+
+	var fixedDelta time.Duration
+	app.AddSystems(ecs.StageFixedUpdate, ecs.NewSystem1(func(dt time.Duration, clock *Time) {
+		fixedDelta = clock.Delta
+	}))
+
+	// Half speed
+	clock.SetScale(0.5)
+	app.Step(40 * time.Millisecond)
+	if c.fixed != 2 || clock.VirtualDelta != 20*time.Millisecond || clock.RealDelta != 40*time.Millisecond {
+		t.Errorf("expected time to be scaled: %d %+v", c.fixed, *clock)
+	}
+	if fixedDelta != 10*time.Millisecond || clock.Delta != clock.VirtualDelta {
+		t.Errorf("expected delta to follow the current stage: %v %v", fixedDelta, clock.Delta)
+	}
+
+	// Paused
+	clock.SetScale(1)
+	clock.Pause()
+	app.Step(40 * time.Millisecond)
+	if c.fixed != 2 || c.update != 2 || clock.VirtualElapsed != 20*time.Millisecond || clock.RealElapsed != 80*time.Millisecond {
+		t.Errorf("expected pause to stop virtual time: %d %+v", c.fixed, *clock)
+	}
+
+	// Stepping while paused runs exactly one fixed update per frame
+	clock.Step(2)
+	app.Step(40 * time.Millisecond)
+	app.Step(40 * time.Millisecond)
+	app.Step(40 * time.Millisecond)
+	if c.fixed != 4 || clock.FixedElapsed != 40*time.Millisecond {
+		t.Errorf("expected two stepped fixed updates: %d %+v", c.fixed, *clock)
+	}
+
+	clock.Resume()
+	app.Step(10 * time.Millisecond)
+	if c.fixed != 5 || clock.Frame != 6 {
+		t.Errorf("expected time to resume: %d %+v", c.fixed, *clock)
+	}
+}
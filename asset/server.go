@@ -104,6 +104,7 @@ type Server struct {
 	fsMap        map[string]Filesystem   // Maps a prefix to a filesystem
 	extToLoader  map[string]any          // Map file extension strings to the loader that loads them
 	nameToHandle map[string]assetHandler // Map the full filepath name to the asset handle
+	pending      atomic.Int64            // The number of loads and reloads that are in progress
 }
 
 // func NewServerFromPath(fsPath string) *Server {
@@ -132,6 +133,11 @@ func NewServer() *Server {
 	}
 }
 
+// Returns the number of loads and reloads that are currently in progress
+func (s *Server) Pending() int {
+	return int(s.pending.Load())
+}
+
 func (s *Server) RegisterFilesystem(prefix string, fs Filesystem) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -387,9 +393,11 @@ func Load[T any](server *Server, name string) *Handle[T] {
 		panic(fmt.Sprintf("wrong type for registered loader on extension: %s", ext))
 	}
 
+	server.pending.Add(1)
 	go func() {
 		// TODO: Recover?
 		defer func() {
+			server.pending.Add(-1)
 			handle.done.Store(true)
 			close(handle.doneChan)
 		}()
@@ -435,8 +443,10 @@ func Reload[T any](server *Server, handle *Handle[T]) {
 		panic(fmt.Sprintf("wrong type for registered loader on extension: %s", ext))
 	}
 
+	server.pending.Add(1)
 	go func() {
 		// TODO: Recover?
+		defer server.pending.Add(-1)
 
 		modTime, err := server.getModTime(name)
 		if err != nil {
@@ -0,0 +1,308 @@
+package behavior
+
+import (
+	"slices"
+	"time"
+
+	"github.com/unitoftime/ecs"
+	"github.com/unitoftime/flow/asset"
+	"github.com/unitoftime/flow/ds"
+)
+
+//go:generate go run ../../cod/cmd/cod
+
+// Status is the result of ticking a node
+type Status uint8
+
+const (
+	Invalid Status = iota // The node hasn't been ticked
+	Running
+	Success
+	Failure
+)
+
+func (s Status) String() string {
+	switch s {
+	case Running:
+		return "running"
+	case Success:
+		return "success"
+	case Failure:
+		return "failure"
+	}
+	return "invalid"
+}
+
+//--------------------------------------------------------------------------------
+// - Blackboard
+//--------------------------------------------------------------------------------
+
+// Blackboard holds the values that an agent's leaves share with each other, and with the rest of the game (ie a sensor system writes "target" and a Chase leaf reads it)
+type Blackboard struct {
+	values map[string]any
+}
+
+func NewBlackboard() *Blackboard {
+	return &Blackboard{
+		values: make(map[string]any),
+	}
+}
+
+func (b *Blackboard) Set(key string, val any) {
+	b.values[key] = val
+}
+
+func (b *Blackboard) Get(key string) (any, bool) {
+	val, ok := b.values[key]
+	return val, ok
+}
+
+func (b *Blackboard) Delete(key string) {
+	delete(b.values, key)
+}
+
+// Returns every key, in sorted order
+func (b *Blackboard) Keys() []string {
+	keys := make([]string, 0, len(b.values))
+	for k := range b.values {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// Returns the value of the key, if it is set and has the type T
+func Get[T any](b *Blackboard, key string) (T, bool) {
+	val, ok := b.values[key].(T)
+	return val, ok
+}
+
+//--------------------------------------------------------------------------------
+// - Context
+//--------------------------------------------------------------------------------
+
+// Context is passed to leaves when they are ticked
+type Context struct {
+	World      *ecs.World
+	Entity     ecs.Id
+	Blackboard *Blackboard
+	Args       ds.Args
+	Dt         time.Duration
+
+	state *nodeState
+}
+
+// Returns how long the leaf has been running for, including this tick
+func (c *Context) Elapsed() time.Duration {
+	return c.state.elapsed
+}
+
+// Returns the value that the leaf stored with Remember. The value is cleared when the leaf finishes.
+func (c *Context) Memory() any {
+	return c.state.memory
+}
+
+// Stores a value for the leaf, which it can read on later ticks while it is running
+func (c *Context) Remember(val any) {
+	c.state.memory = val
+}
+
+//--------------------------------------------------------------------------------
+// - Agent
+//--------------------------------------------------------------------------------
+
+// The per agent state of a node
+type nodeState struct {
+	index   int           // The running child of sequences and selectors
+	count   int           // The iterations of repeat and retry
+	elapsed time.Duration // How long a leaf has been running
+	memory  any
+	results []Status // The finished children of parallels
+}
+
+// Agent runs a behavior tree for its entity. Trees are shared, but every agent has its own blackboard and node states. If the tree is reloaded, the agent restarts from the root with its blackboard intact.
+//
+//cod:component
+type Agent struct {
+	Tree       *asset.Handle[Tree]
+	Blackboard *Blackboard
+	Disabled   bool
+
+	tree     *Tree
+	gen      int
+	states   []nodeState
+	last     []Status // The status that each node returned the last time it was ticked
+	lastTick []uint64 // The tick that each node was last ticked on
+	ticks    uint64
+}
+
+func NewAgent(tree *asset.Handle[Tree]) Agent {
+	return Agent{
+		Tree:       tree,
+		Blackboard: NewBlackboard(),
+	}
+}
+
+// Returns the status of the root node from the last tick
+func (a *Agent) Status() Status {
+	if len(a.last) == 0 {
+		return Invalid
+	}
+	return a.last[0]
+}
+
+// Restarts the tree from the root on the next tick
+func (a *Agent) Reset() {
+	a.tree = nil
+}
+
+// Ticks the tree once, and returns the status of the root. Trees that are still loading (or failed their first load) aren't ticked, and return Invalid. Once the root finishes, the tree starts over on the next tick.
+func (a *Agent) Tick(world *ecs.World, id ecs.Id, dt time.Duration) Status {
+	if a.Disabled || a.Tree == nil || !a.Tree.Done() {
+		return Invalid
+	}
+	if a.Blackboard == nil {
+		a.Blackboard = NewBlackboard()
+	}
+
+	// Note: If a reload fails, the handle keeps the last tree that loaded, so the agent keeps running it
+	gen := a.Tree.Gen()
+	if a.tree == nil || a.gen != gen {
+		tree, _ := a.Tree.Get()
+		if tree == nil {
+			return Invalid
+		}
+		a.tree = tree
+		a.gen = gen
+		a.states = make([]nodeState, len(tree.nodes))
+		a.last = make([]Status, len(tree.nodes))
+		a.lastTick = make([]uint64, len(tree.nodes))
+	}
+	if len(a.tree.nodes) == 0 {
+		return Invalid
+	}
+
+	a.ticks++
+	ctx := &Context{
+		World:      world,
+		Entity:     id,
+		Blackboard: a.Blackboard,
+		Dt:         dt,
+	}
+	return a.run(ctx, 0)
+}
+
+// Ticks the node, and resets its subtree once it finishes
+func (a *Agent) run(ctx *Context, i int) Status {
+	n := &a.tree.nodes[i]
+	st := &a.states[i]
+
+	var status Status
+	switch n.kind {
+	case kindLeaf:
+		st.elapsed += ctx.Dt
+		ctx.Args = n.def.Args
+		ctx.state = st
+		status = n.leaf(ctx)
+
+	case kindSequence:
+		status = a.runChildren(ctx, n, st, Success)
+	case kindSelector:
+		status = a.runChildren(ctx, n, st, Failure)
+	case kindParallel:
+		status = a.runParallel(ctx, n, st)
+
+	case kindInvert:
+		status = a.runChildren(ctx, n, st, Success)
+		switch status {
+		case Success:
+			status = Failure
+		case Failure:
+			status = Success
+		}
+	case kindSucceed:
+		status = a.runChildren(ctx, n, st, Success)
+		if status != Running {
+			status = Success
+		}
+	case kindFail:
+		status = a.runChildren(ctx, n, st, Success)
+		if status != Running {
+			status = Failure
+		}
+
+	case kindRepeat, kindRetry:
+		// Note: Only one iteration runs per tick, so a child that finishes instantly can't loop forever
+		again := Success
+		if n.kind == kindRetry {
+			again = Failure
+		}
+		status = a.runChildren(ctx, n, st, Success)
+		if status == again {
+			st.count++
+			if n.count > 0 && st.count >= n.count {
+				break
+			}
+			a.resetChildren(i)
+			st.index = 0
+			status = Running
+		}
+	}
+
+	a.last[i] = status
+	a.lastTick[i] = a.ticks
+	if status != Running {
+		a.resetChildren(i)
+		*st = nodeState{results: st.results[:0]}
+	}
+	return status
+}
+
+// Runs the children in order, starting from the running child, while they return the status next. Sequences continue on Success and selectors continue on Failure.
+func (a *Agent) runChildren(ctx *Context, n *node, st *nodeState, next Status) Status {
+	for st.index < len(n.children) {
+		status := a.run(ctx, n.children[st.index])
+		if status != next {
+			return status
+		}
+		st.index++
+	}
+	return next
+}
+
+func (a *Agent) runParallel(ctx *Context, n *node, st *nodeState) Status {
+	if len(st.results) != len(n.children) {
+		st.results = make([]Status, len(n.children))
+	}
+
+	successes, failures := 0, 0
+	for c, child := range n.children {
+		if st.results[c] == Invalid || st.results[c] == Running {
+			st.results[c] = a.run(ctx, child)
+		}
+		switch st.results[c] {
+		case Success:
+			successes++
+		case Failure:
+			failures++
+		}
+	}
+
+	// The all policy needs every child to succeed, and the one policy needs every child to fail
+	need := len(n.children)
+	switch {
+	case n.anyPolicy && successes > 0, !n.anyPolicy && successes == need:
+		return Success
+	case n.anyPolicy && failures == need, !n.anyPolicy && failures > 0:
+		return Failure
+	}
+	return Running
+}
+
+// Resets the states of every node under node i
+func (a *Agent) resetChildren(i int) {
+	end := a.tree.nodes[i].end
+	for j := i + 1; j < end; j++ {
+		a.states[j] = nodeState{results: a.states[j].results[:0]}
+	}
+}
@@ -0,0 +1,349 @@
+package behavior
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/unitoftime/ecs"
+	"github.com/unitoftime/flow"
+	"github.com/unitoftime/flow/asset"
+	"github.com/unitoftime/flow/ds"
+)
+
+// Returns a registry with a "status" leaf. It returns the status stored in the blackboard under its {key}, or Running if there isn't one, and counts its ticks under "ticks.{key}".
+func statusLeaves() *Registry {
+	r := NewRegistry()
+	r.Register("status", func(ctx *Context) Status {
+		key := ctx.Args.String("key", "")
+		ticks, _ := Get[int](ctx.Blackboard, "ticks."+key)
+		ctx.Blackboard.Set("ticks."+key, ticks+1)
+
+		status, ok := Get[Status](ctx.Blackboard, key)
+		if !ok {
+			return Running
+		}
+		return status
+	})
+	return r
+}
+
+// Loads the tree file through an asset server, and returns an agent which runs it
+func newAgent(t *testing.T, src string) (*Agent, *ecs.World) {
+	t.Helper()
+	server := asset.NewServer()
+	server.RegisterFilesystem("", asset.NewFilesystem("", fstest.MapFS{
+		"agent.tree.yaml": {Data: []byte(src)},
+	}))
+	asset.Register(server, Loader{Registry: statusLeaves()})
+
+	handle := asset.Load[Tree](server, "agent.tree.yaml")
+	_, err := handle.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	agent := NewAgent(handle)
+	return &agent, ecs.NewWorld()
+}
+
+func ticks(agent *Agent, key string) int {
+	n, _ := Get[int](agent.Blackboard, "ticks."+key)
+	return n
+}
+
+func TestSequenceAndSelector(t *testing.T) {
+	agent, world := newAgent(t, `
+root:
+  type: selector
+  children:
+    - type: sequence
+      children:
+        - {type: status, args: {key: a}}
+        - {type: status, args: {key: b}}
+    - {type: status, args: {key: c}}
+`)
+
+	// The running child is resumed instead of restarting the sequence
+	agent.Blackboard.Set("a", Success)
+	if s := agent.Tick(world, 0, 0); s != Running {
+		t.Errorf("expected running, got %v", s)
+	}
+	agent.Tick(world, 0, 0)
+	if ticks(agent, "a") != 1 || ticks(agent, "b") != 2 {
+		t.Errorf("expected b to be resumed, got a=%d b=%d", ticks(agent, "a"), ticks(agent, "b"))
+	}
+
+	// A failed sequence falls through to the next child of the selector
+	agent.Blackboard.Set("b", Failure)
+	agent.Blackboard.Set("c", Success)
+	if s := agent.Tick(world, 0, 0); s != Success {
+		t.Errorf("expected success, got %v", s)
+	}
+	if ticks(agent, "c") != 1 {
+		t.Errorf("expected selector to fall through to c")
+	}
+
+	// A finished tree starts again from the root
+	agent.Tick(world, 0, 0)
+	if ticks(agent, "a") != 2 {
+		t.Errorf("expected tree to restart, got a=%d", ticks(agent, "a"))
+	}
+}
+
+func TestParallel(t *testing.T) {
+	tests := []struct {
+		policy   string
+		a, b     Status
+		expected Status
+	}{
+		{"all", Success, Running, Running},
+		{"all", Success, Success, Success},
+		{"all", Running, Failure, Failure},
+		{"one", Success, Running, Success},
+		{"one", Failure, Running, Running},
+		{"one", Failure, Failure, Failure},
+	}
+	for _, test := range tests {
+		agent, world := newAgent(t, `
+root:
+  type: parallel
+  args: {policy: `+test.policy+`}
+  children:
+    - {type: status, args: {key: a}}
+    - {type: status, args: {key: b}}
+`)
+		agent.Blackboard.Set("a", test.a)
+		agent.Blackboard.Set("b", test.b)
+		if s := agent.Tick(world, 0, 0); s != test.expected {
+			t.Errorf("%s %v %v: expected %v, got %v", test.policy, test.a, test.b, test.expected, s)
+		}
+	}
+
+	// Finished children aren't ticked again while the others run
+	agent, world := newAgent(t, `
+root:
+  type: parallel
+  children:
+    - {type: status, args: {key: a}}
+    - {type: status, args: {key: b}}
+`)
+	agent.Blackboard.Set("a", Success)
+	agent.Tick(world, 0, 0)
+	agent.Tick(world, 0, 0)
+	if ticks(agent, "a") != 1 || ticks(agent, "b") != 2 {
+		t.Errorf("expected only b to be ticked again, got a=%d b=%d", ticks(agent, "a"), ticks(agent, "b"))
+	}
+}
+
+func TestDecorators(t *testing.T) {
+	tests := []struct {
+		node     string
+		a        Status
+		expected Status
+		ticks    int
+	}{
+		{"invert", Success, Failure, 1},
+		{"invert", Failure, Success, 1},
+		{"succeed", Failure, Success, 1},
+		{"fail", Success, Failure, 1},
+		{"repeat", Success, Success, 3},
+		{"repeat", Failure, Failure, 1},
+		{"retry", Failure, Failure, 3},
+		{"retry", Success, Success, 1},
+	}
+	for _, test := range tests {
+		agent, world := newAgent(t, `
+root:
+  type: `+test.node+`
+  args: {count: 3}
+  children:
+    - {type: status, args: {key: a}}
+`)
+		agent.Blackboard.Set("a", test.a)
+		var s Status
+		for i := 0; i < 10; i++ {
+			s = agent.Tick(world, 0, 0)
+			if s != Running {
+				break
+			}
+		}
+		if s != test.expected || ticks(agent, "a") != test.ticks {
+			t.Errorf("%s %v: expected %v after %d ticks, got %v after %d", test.node, test.a, test.expected, test.ticks, s, ticks(agent, "a"))
+		}
+	}
+}
+
+func TestWait(t *testing.T) {
+	agent, world := newAgent(t, `
+root:
+  type: sequence
+  children:
+    - {type: wait, args: {seconds: 1}}
+    - {type: has, args: {key: target}}
+`)
+	agent.Blackboard.Set("target", ecs.Id(5))
+	if agent.Tick(world, 0, 600*time.Millisecond) != Running {
+		t.Errorf("expected wait to be running")
+	}
+	if agent.Tick(world, 0, 600*time.Millisecond) != Success {
+		t.Errorf("expected wait to finish")
+	}
+	if agent.Tick(world, 0, 600*time.Millisecond) != Running {
+		t.Errorf("expected wait to restart with the tree")
+	}
+
+	agent.Blackboard.Delete("target")
+	if agent.Tick(world, 0, 600*time.Millisecond) != Failure {
+		t.Errorf("expected has to fail")
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	wait := NodeDef{Type: "wait"}
+	tests := []struct {
+		root NodeDef
+		err  string
+	}{
+		{NodeDef{Type: "Missing"}, `unregistered node type "Missing"`},
+		{NodeDef{Type: "sequence"}, "needs at least one child"},
+		{NodeDef{Type: "selector", Children: []NodeDef{{Type: "wait", Children: []NodeDef{wait}}}}, "root/selector[0]: leaf wait can't have children"},
+		{NodeDef{Type: "parallel", Args: ds.Args{"policy": "most"}, Children: []NodeDef{wait}}, "unknown parallel policy"},
+	}
+	for _, test := range tests {
+		_, err := NewTree("bad", test.root, statusLeaves())
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("expected error containing %q, got %v", test.err, err)
+		}
+	}
+
+	// Tree files are checked the same way when they are loaded
+	_, err := Loader{Registry: statusLeaves()}.Load(nil, []byte("root: {type: sequence}"))
+	if err == nil {
+		t.Errorf("expected tree file to fail to load")
+	}
+}
+
+func TestRegisterDuplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected duplicate leaf to panic")
+		}
+	}()
+	r := NewRegistry()
+	r.Register("sequence", func(ctx *Context) Status { return Success })
+}
+
+func TestDebug(t *testing.T) {
+	agent, world := newAgent(t, `
+root:
+  type: selector
+  children:
+    - type: sequence
+      name: attack
+      children:
+        - {type: has, args: {key: target}}
+        - {type: status, args: {key: a}}
+    - {type: status, args: {key: b}}
+`)
+	if agent.Debug() != nil {
+		t.Errorf("expected no debug info before the first tick")
+	}
+
+	agent.Blackboard.Set("target", true)
+	agent.Tick(world, 0, 0)
+	agent.Blackboard.Set("a", Failure)
+	agent.Tick(world, 0, 0)
+
+	infos := agent.Debug()
+	if len(infos) != 5 || infos[4].Status != Running || !infos[4].Active {
+		t.Errorf("unexpected debug info: %v", infos)
+	}
+
+	expected := `selector: running
+  sequence (attack): failure
+    has: success (stale)
+    status: failure
+  status: running
+`
+	if s := agent.DebugString(); s != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, s)
+	}
+}
+
+// A new tree restarts the agent from the root, but keeps its blackboard
+func TestTreeSwap(t *testing.T) {
+	agent, world := newAgent(t, `
+root:
+  type: sequence
+  children:
+    - {type: status, args: {key: a}}
+    - {type: status, args: {key: b}}
+`)
+	agent.Blackboard.Set("a", Success)
+	agent.Tick(world, 0, 0)
+	if ticks(agent, "b") != 1 {
+		t.Fatalf("expected b to be running")
+	}
+
+	swapped, err := NewTree("swapped", NodeDef{Type: "status", Args: ds.Args{"key": "c"}}, statusLeaves())
+	if err != nil {
+		t.Fatal(err)
+	}
+	agent.Tree.Set(swapped)
+	agent.Blackboard.Set("c", Success)
+
+	if s := agent.Tick(world, 0, 0); s != Success || ticks(agent, "c") != 1 || ticks(agent, "b") != 1 {
+		t.Errorf("expected the new tree to run instead of resuming b, got %v", s)
+	}
+	if a, _ := Get[Status](agent.Blackboard, "a"); a != Success {
+		t.Errorf("expected the blackboard to be kept")
+	}
+}
+
+func TestPluginReload(t *testing.T) {
+	files := fstest.MapFS{
+		"guard.tree.yaml": {
+			Data:    []byte("root: {type: status, args: {key: a}}"),
+			ModTime: time.Unix(1, 0),
+		},
+	}
+	server := asset.NewServer()
+	server.RegisterFilesystem("", asset.NewFilesystem("", files))
+	asset.Register(server, Loader{Registry: statusLeaves()})
+
+	app := flow.NewApp()
+	app.SetFixedTimeStep(10 * time.Millisecond)
+	app.AddPlugin(Plugin{Server: server, ReloadInterval: 10 * time.Millisecond})
+	world := app.World()
+
+	handle := asset.Load[Tree](server, "guard.tree.yaml")
+	handle.Wait()
+	id := world.NewId()
+	agent := NewAgent(handle)
+	agent.Blackboard.Set("b", Success)
+	world.Write(id, agent)
+
+	// Runs one tick, then waits for any reloads that the tick started
+	step := func() Agent {
+		app.Step(10 * time.Millisecond)
+		for server.Pending() > 0 {
+			time.Sleep(time.Millisecond)
+		}
+		agent, _ := ecs.Read[Agent](world, id)
+		return agent
+	}
+
+	if agent := step(); agent.Status() != Running || ticks(&agent, "a") != 1 {
+		t.Fatalf("expected the plugin to tick the agent")
+	}
+
+	files["guard.tree.yaml"] = &fstest.MapFile{
+		Data:    []byte("root: {type: status, args: {key: b}}"),
+		ModTime: time.Unix(2, 0),
+	}
+	step() // Notices the change, and reloads the tree
+	if agent := step(); agent.Status() != Success {
+		t.Errorf("expected the reloaded tree to run, got %v", agent.Status())
+	}
+}
@@ -0,0 +1,15 @@
+package behavior
+
+import (
+	"github.com/unitoftime/ecs"
+)
+
+var AgentComp = ecs.NewComp[Agent]()
+
+func (c Agent) CompId() ecs.CompId {
+	return AgentComp.CompId()
+}
+
+func (c Agent) CompWrite(w ecs.W) {
+	AgentComp.WriteVal(w, c)
+}
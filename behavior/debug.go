@@ -0,0 +1,59 @@
+package behavior
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NodeInfo describes one node of an agent's tree, for debuggers and editors
+type NodeInfo struct {
+	Index  int    // The index of the node, in depth first order
+	Depth  int    // The depth of the node, the root is 0
+	Type   string // The node type, ie sequence or a leaf name
+	Name   string // The label from the tree file, if it had one
+	Status Status // The status that the node returned the last time it was ticked
+	Active bool   // True if the node was ticked on the agent's last tick
+}
+
+// Returns the state of every node in the agent's tree, in depth first order. Returns nil if the agent hasn't ticked yet.
+func (a *Agent) Debug() []NodeInfo {
+	if a.tree == nil {
+		return nil
+	}
+	infos := make([]NodeInfo, len(a.tree.nodes))
+	for i, n := range a.tree.nodes {
+		infos[i] = NodeInfo{
+			Index:  i,
+			Depth:  n.depth,
+			Type:   n.def.Type,
+			Name:   n.def.Name,
+			Status: a.last[i],
+			Active: a.ticks > 0 && a.lastTick[i] == a.ticks,
+		}
+	}
+	return infos
+}
+
+// Returns the agent's tree as indented text, with the last status of every node. Nodes which weren't ticked on the last tick are marked as stale.
+//
+//	selector: running
+//	  sequence (attack): failure
+//	    CanSeePlayer: failure
+//	    Chase: invalid (stale)
+//	  Wander: running
+func (a *Agent) DebugString() string {
+	var b strings.Builder
+	for _, info := range a.Debug() {
+		b.WriteString(strings.Repeat("  ", info.Depth))
+		b.WriteString(info.Type)
+		if info.Name != "" {
+			fmt.Fprintf(&b, " (%s)", info.Name)
+		}
+		fmt.Fprintf(&b, ": %s", info.Status)
+		if !info.Active {
+			b.WriteString(" (stale)")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
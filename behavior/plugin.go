@@ -0,0 +1,80 @@
+package behavior
+
+import (
+	"time"
+
+	"github.com/unitoftime/ecs"
+	"github.com/unitoftime/flow"
+	"github.com/unitoftime/flow/asset"
+)
+
+// Ticks every agent once per run, with the scaled game time. If server is set, then the tree files of every agent are checked for changes once per interval.
+func TickSystem(world *ecs.World, server *asset.Server, interval time.Duration) ecs.System {
+	query := ecs.Query1[Agent](world)
+	clock := ecs.GetResource[flow.Time](world)
+	var elapsed time.Duration
+	return ecs.System{
+		Name: "behavior.TickSystem",
+		Func: func(dt time.Duration) {
+			elapsed += dt
+			if server != nil && elapsed >= interval {
+				elapsed = 0
+				pollAgents(server, query)
+			}
+
+			if clock != nil {
+				dt = clock.Delta
+			}
+
+			// Note: Leaves can change the world, so collect the ids first
+			ids := make([]ecs.Id, 0)
+			query.MapId(func(id ecs.Id, agent *Agent) {
+				if !agent.Disabled {
+					ids = append(ids, id)
+				}
+			})
+
+			for _, id := range ids {
+				agent, ok := ecs.Read[Agent](world, id)
+				if !ok {
+					continue
+				}
+				agent.Tick(world, id, dt)
+				if world.Exists(id) {
+					world.Write(id, agent)
+				}
+			}
+		},
+	}
+}
+
+// Asks the asset server to reload every tree used by an agent. Reloads only happen if the file has changed.
+func pollAgents(server *asset.Server, query *ecs.View1[Agent]) {
+	handles := make(map[*asset.Handle[Tree]]struct{})
+	query.MapId(func(id ecs.Id, agent *Agent) {
+		if agent.Tree != nil {
+			handles[agent.Tree] = struct{}{}
+		}
+	})
+
+	for handle := range handles {
+		asset.Reload(server, handle)
+	}
+}
+
+// Plugin adds the TickSystem to the app
+// Note: The behavior Loader must still be registered with your asset server: `asset.Register(server, behavior.Loader{})`
+type Plugin struct {
+	Server         *asset.Server // Optional: If set, then the tree files of agents are checked for changes and hot reloaded
+	ReloadInterval time.Duration // How often to check for changes. Defaults to 1 second
+}
+
+func (p Plugin) Initialize(world *ecs.World) {
+	app := flow.MustApp(world)
+
+	interval := p.ReloadInterval
+	if interval <= 0 {
+		interval = 1 * time.Second
+	}
+	app.AddSystems(ecs.StageFixedUpdate, TickSystem(world, p.Server, interval))
+}
@@ -0,0 +1,267 @@
+package behavior
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/unitoftime/flow/asset"
+	"github.com/unitoftime/flow/ds"
+)
+
+// Tree is a behavior tree asset. Trees are written in YAML (or JSON, which is a subset of YAML) and loaded with the asset server:
+//
+//	name: goblin
+//	root:
+//	  type: selector
+//	  children:
+//	    - type: sequence
+//	      name: attack
+//	      children:
+//	        - type: CanSeePlayer
+//	        - type: Chase
+//	          args: {speed: 2}
+//	    - type: repeat
+//	      args: {count: 3}
+//	      children:
+//	        - type: Wander
+//	        - type: wait
+//	          args: {seconds: 1}
+//
+// Every node has a type, which is either one of the built in composites and decorators, or the name of a leaf that was registered in Go:
+//   - sequence: Runs its children in order until one fails
+//   - selector: Runs its children in order until one succeeds
+//   - parallel: Runs every child each tick. Succeeds once every child succeeds (or any child, with args {policy: one}), fails once any child fails (or every child, with {policy: one})
+//   - invert: Swaps its child's success and failure
+//   - succeed, fail: Succeeds or fails once its child finishes, regardless of how it finished
+//   - repeat: Runs its child again each time it succeeds, {count} times or forever if count is 0. Fails if its child fails
+//   - retry: Runs its child again each time it fails, {count} times or forever if count is 0. Succeeds if its child succeeds
+//
+// Nodes with more than one child are sequences of those children, so decorators can be written without nesting an extra sequence.
+//
+// Composites resume their running child on the next tick, rather than ticking the children before it again. To re-check a condition every tick, put it in a parallel next to the running branch.
+type Tree struct {
+	Name string  `yaml:"name,omitempty"`
+	Root NodeDef `yaml:"root"`
+
+	nodes []node // The nodes in depth first order
+}
+
+// NodeDef is the definition of one node of a tree
+type NodeDef struct {
+	Type     string    `yaml:"type"`
+	Name     string    `yaml:"name,omitempty"` // Optional: A label which is shown in the debugger
+	Args     ds.Args   `yaml:"args,omitempty"`
+	Children []NodeDef `yaml:"children,omitempty"`
+}
+
+type kind uint8
+
+const (
+	kindLeaf kind = iota
+	kindSequence
+	kindSelector
+	kindParallel
+	kindInvert
+	kindSucceed
+	kindFail
+	kindRepeat
+	kindRetry
+)
+
+var kinds = map[string]kind{
+	"sequence": kindSequence,
+	"selector": kindSelector,
+	"parallel": kindParallel,
+	"invert":   kindInvert,
+	"succeed":  kindSucceed,
+	"fail":     kindFail,
+	"repeat":   kindRepeat,
+	"retry":    kindRetry,
+}
+
+// A compiled node
+type node struct {
+	kind     kind
+	def      *NodeDef
+	leaf     LeafFunc
+	children []int
+	end      int // One past the index of the last node in this node's subtree
+	depth    int
+
+	count     int  // repeat and retry
+	anyPolicy bool // parallel
+}
+
+// Builds a tree in Go, and checks that every leaf is registered in the registry. If registry is nil, DefaultRegistry is used.
+func NewTree(name string, root NodeDef, registry *Registry) (*Tree, error) {
+	tree := &Tree{
+		Name: name,
+		Root: root,
+	}
+	err := tree.compile(registry)
+	if err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+// Returns the number of nodes in the tree
+func (t *Tree) Len() int {
+	return len(t.nodes)
+}
+
+func (t *Tree) compile(registry *Registry) error {
+	if registry == nil {
+		registry = DefaultRegistry
+	}
+	t.nodes = t.nodes[:0]
+	return t.add(registry, &t.Root, 0, "root")
+}
+
+// Adds the node and its subtree to the flat list of nodes
+func (t *Tree) add(registry *Registry, def *NodeDef, depth int, path string) error {
+	idx := len(t.nodes)
+	t.nodes = append(t.nodes, node{def: def, depth: depth})
+	n := node{def: def, depth: depth}
+
+	k, ok := kinds[def.Type]
+	if ok {
+		n.kind = k
+		if len(def.Children) == 0 {
+			return fmt.Errorf("behavior: %s: %s needs at least one child", path, def.Type)
+		}
+	} else {
+		leaf, ok := registry.leaves[def.Type]
+		if !ok {
+			return fmt.Errorf("behavior: %s: unregistered node type %q", path, def.Type)
+		}
+		if len(def.Children) > 0 {
+			return fmt.Errorf("behavior: %s: leaf %s can't have children", path, def.Type)
+		}
+		n.kind = kindLeaf
+		n.leaf = leaf
+	}
+
+	switch n.kind {
+	case kindRepeat, kindRetry:
+		n.count = def.Args.Int("count", 0)
+	case kindParallel:
+		policy := def.Args.String("policy", "all")
+		if policy != "all" && policy != "one" {
+			return fmt.Errorf("behavior: %s: unknown parallel policy %q", path, policy)
+		}
+		n.anyPolicy = policy == "one"
+	}
+
+	for i := range def.Children {
+		n.children = append(n.children, len(t.nodes))
+		err := t.add(registry, &def.Children[i], depth+1, fmt.Sprintf("%s/%s[%d]", path, def.Type, i))
+		if err != nil {
+			return err
+		}
+	}
+	n.end = len(t.nodes)
+	t.nodes[idx] = n
+	return nil
+}
+
+//--------------------------------------------------------------------------------
+// - Loader
+//--------------------------------------------------------------------------------
+
+// Loader loads trees through the asset server
+type Loader struct {
+	Registry *Registry // Optional: The registry used to find leaves. Defaults to DefaultRegistry
+}
+
+func (l Loader) Ext() []string {
+	return []string{".tree.yaml", ".tree.yml", ".tree.json"}
+}
+
+func (l Loader) Load(server *asset.Server, data []byte) (*Tree, error) {
+	var tree Tree
+	err := yaml.Unmarshal(data, &tree)
+	if err != nil {
+		return nil, err
+	}
+	err = tree.compile(l.Registry)
+	if err != nil {
+		return nil, err
+	}
+	return &tree, nil
+}
+
+func (l Loader) Store(server *asset.Server, tree *Tree) ([]byte, error) {
+	return yaml.Marshal(tree)
+}
+
+//--------------------------------------------------------------------------------
+// - Registry
+//--------------------------------------------------------------------------------
+
+// LeafFunc runs a leaf for one tick, and returns its status. Leaves that return Running are ticked again next tick, until they succeed or fail.
+type LeafFunc func(ctx *Context) Status
+
+// Registry maps the leaf names used in tree files to their functions
+type Registry struct {
+	leaves map[string]LeafFunc
+}
+
+// Returns a registry which holds the built in leaves:
+//   - wait: Runs for {seconds}, then succeeds
+//   - has: Succeeds if the blackboard has the value {key}, else fails
+func NewRegistry() *Registry {
+	r := &Registry{
+		leaves: make(map[string]LeafFunc),
+	}
+	r.Register("wait", waitLeaf)
+	r.Register("has", hasLeaf)
+	return r
+}
+
+// The registry used by the package level functions
+var DefaultRegistry = NewRegistry()
+
+// Registers a leaf into the default registry
+func Register(name string, fn LeafFunc) {
+	DefaultRegistry.Register(name, fn)
+}
+
+// Registers a leaf. Panics if the name is already used by a leaf or a built in node.
+func (r *Registry) Register(name string, fn LeafFunc) {
+	_, exists := r.leaves[name]
+	_, builtin := kinds[name]
+	if exists || builtin {
+		panic(fmt.Sprintf("behavior: leaf name already registered: %s", name))
+	}
+	r.leaves[name] = fn
+}
+
+// Returns the names of every registered leaf, in sorted order
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.leaves))
+	for name := range r.leaves {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func waitLeaf(ctx *Context) Status {
+	wait := time.Duration(ctx.Args.Float("seconds", 0) * float64(time.Second))
+	if ctx.Elapsed() >= wait {
+		return Success
+	}
+	return Running
+}
+
+func hasLeaf(ctx *Context) Status {
+	_, ok := ctx.Blackboard.Get(ctx.Args.String("key", ""))
+	if ok {
+		return Success
+	}
+	return Failure
+}
@@ -0,0 +1,405 @@
+package console
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/unitoftime/flow/storage"
+)
+
+// The default number of lines kept in the history
+const DefaultMaxHistory = 100
+
+// Command is a console command. Commands are run with the arguments that follow the command name.
+type Command struct {
+	Name    string
+	Usage   string // The arguments, for example: "<entity> [amount]"
+	Help    string
+	MinArgs int
+	MaxArgs int // A negative value allows any number of arguments
+
+	Run func(args []string) (string, error)
+
+	// Optional: Returns the completions for the last argument. args holds every argument typed so far, including the partial last argument.
+	Complete func(args []string) []string
+}
+
+// Console holds the console variables and commands, and executes lines of text against them. It has no user interface, frontends such as Serve write lines to it and display its output.
+type Console struct {
+	MaxHistory int
+
+	vars     map[string]cvar
+	commands map[string]*Command
+	history  []string
+	saved    map[string]string // Persisted values, which are applied when their var is marked persistent
+
+	requests  chan request
+	done      chan struct{} // Closed by Close, to release the frontends
+	closeOnce sync.Once
+}
+
+func New() *Console {
+	c := &Console{
+		MaxHistory: DefaultMaxHistory,
+		vars:       make(map[string]cvar),
+		commands:   make(map[string]*Command),
+		saved:      make(map[string]string),
+		requests:   make(chan request, 16),
+		done:       make(chan struct{}),
+	}
+	c.addBuiltins()
+	return c
+}
+
+func (c *Console) addName(name string) {
+	if name == "" || strings.ContainsAny(name, " \t\";") {
+		panic(fmt.Sprintf("console: invalid name: %q", name))
+	}
+	_, isVar := c.vars[name]
+	_, isCmd := c.commands[name]
+	if isVar || isCmd {
+		panic(fmt.Sprintf("console: name already registered: %s", name))
+	}
+}
+
+// Registers a command. Panics if the name is already used by a var or a command.
+func (c *Console) AddCommand(cmd Command) {
+	c.addName(cmd.Name)
+	if cmd.Run == nil {
+		panic("console: command has no Run function: " + cmd.Name)
+	}
+	c.commands[cmd.Name] = &cmd
+}
+
+// Returns the names of every var and command, sorted
+func (c *Console) Names() []string {
+	names := slices.Collect(maps.Keys(c.vars))
+	names = slices.AppendSeq(names, maps.Keys(c.commands))
+	slices.Sort(names)
+	return names
+}
+
+// Returns the current value of a var as a string
+func (c *Console) Get(name string) (string, bool) {
+	v, ok := c.vars[name]
+	if !ok {
+		return "", false
+	}
+	return v.String(), true
+}
+
+// Parses and sets the value of a var
+func (c *Console) Set(name, value string) error {
+	v, ok := c.vars[name]
+	if !ok {
+		return fmt.Errorf("unknown var: %s", name)
+	}
+	return v.Set(value)
+}
+
+// Returns the executed lines, oldest first
+func (c *Console) History() []string {
+	return c.history
+}
+
+func (c *Console) addHistory(line string) {
+	if len(c.history) > 0 && c.history[len(c.history)-1] == line {
+		return
+	}
+	c.history = append(c.history, line)
+	if c.MaxHistory > 0 && len(c.history) > c.MaxHistory {
+		c.history = slices.Delete(c.history, 0, len(c.history)-c.MaxHistory)
+	}
+}
+
+// Executes a line of text and returns its output. Several statements can be separated by semicolons. Every statement is either:
+//   - A command and its arguments: `spawn goblin 3`
+//   - A var name, which prints the var: `phys.gravity`
+//   - A var name and a value, which sets the var: `phys.gravity 9.8`
+//
+// Arguments are separated by whitespace, and can be quoted: `say "hello world"`
+func (c *Console) Exec(line string) (string, error) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return "", nil
+	}
+	c.addHistory(line)
+
+	statements, err := split(line)
+	if err != nil {
+		return "", err
+	}
+
+	var out []string
+	for _, args := range statements {
+		if len(args) == 0 {
+			continue
+		}
+		res, err := c.exec(args[0], args[1:])
+		if res != "" {
+			out = append(out, res)
+		}
+		if err != nil {
+			return strings.Join(out, "\n"), err
+		}
+	}
+	return strings.Join(out, "\n"), nil
+}
+
+func (c *Console) exec(name string, args []string) (string, error) {
+	v, ok := c.vars[name]
+	if ok {
+		switch len(args) {
+		case 0:
+			return describe(v), nil
+		case 1:
+			return "", v.Set(args[0])
+		}
+		return "", fmt.Errorf("usage: %s [value]", name)
+	}
+
+	cmd, ok := c.commands[name]
+	if !ok {
+		return "", fmt.Errorf("unknown command: %s", name)
+	}
+	if len(args) < cmd.MinArgs || (cmd.MaxArgs >= 0 && len(args) > cmd.MaxArgs) {
+		return "", fmt.Errorf("usage: %s %s", cmd.Name, cmd.Usage)
+	}
+	return cmd.Run(args)
+}
+
+func describe(v cvar) string {
+	return fmt.Sprintf("%s = %s (%s, default %s)", v.Name(), v.String(), v.Type(), v.Default())
+}
+
+// Splits a line into statements, and the statements into arguments
+func split(line string) ([][]string, error) {
+	var statements [][]string
+	var args []string
+	var arg strings.Builder
+	inArg, quoted := false, false
+
+	endArg := func() {
+		if inArg {
+			args = append(args, arg.String())
+			arg.Reset()
+			inArg = false
+		}
+	}
+
+	for _, r := range line {
+		switch {
+		case quoted && r == '"':
+			quoted = false
+		case quoted:
+			arg.WriteRune(r)
+		case r == '"':
+			quoted, inArg = true, true
+		case r == ';':
+			endArg()
+			statements = append(statements, args)
+			args = nil
+		case r == ' ' || r == '\t':
+			endArg()
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quoted {
+		return nil, errors.New("unterminated quote")
+	}
+	endArg()
+	return append(statements, args), nil
+}
+
+//--------------------------------------------------------------------------------
+// - Completion
+//--------------------------------------------------------------------------------
+
+// Returns the completions for the last word of the line. The line is extended by the longest prefix that every completion shares, so a frontend can replace its input with the returned line.
+func (c *Console) Complete(line string) (string, []string) {
+	// Only complete the last statement
+	start := strings.LastIndexByte(line, ';') + 1
+	statements, err := split(line[start:])
+	if err != nil {
+		return line, nil // Don't complete inside quotes
+	}
+	args := statements[len(statements)-1]
+	if len(args) == 0 || strings.HasSuffix(line, " ") || strings.HasSuffix(line, "\t") {
+		args = append(args, "") // Completing a new word
+	}
+
+	word := args[len(args)-1]
+	var options []string
+	if len(args) == 1 {
+		options = c.Names()
+	} else {
+		options = c.completeArgs(args[0], args[1:])
+	}
+
+	var matches []string
+	for _, opt := range options {
+		if strings.HasPrefix(opt, word) {
+			matches = append(matches, opt)
+		}
+	}
+	if len(matches) == 0 {
+		return line, nil
+	}
+
+	prefix := matches[0]
+	for _, m := range matches[1:] {
+		for !strings.HasPrefix(m, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	line += prefix[len(word):]
+	if len(matches) == 1 {
+		line += " "
+	}
+	return line, matches
+}
+
+func (c *Console) completeArgs(name string, args []string) []string {
+	v, ok := c.vars[name]
+	if ok {
+		if len(args) == 1 && v.Type() == "bool" {
+			return []string{"false", "true"}
+		}
+		return nil
+	}
+
+	cmd, ok := c.commands[name]
+	if !ok || cmd.Complete == nil {
+		return nil
+	}
+	return cmd.Complete(args)
+}
+
+// Completes the first argument with a var name
+func completeVar(c *Console) func(args []string) []string {
+	return func(args []string) []string {
+		if len(args) != 1 {
+			return nil
+		}
+		names := slices.Collect(maps.Keys(c.vars))
+		slices.Sort(names)
+		return names
+	}
+}
+
+//--------------------------------------------------------------------------------
+// - Builtins
+//--------------------------------------------------------------------------------
+
+func (c *Console) addBuiltins() {
+	c.AddCommand(Command{
+		Name:    "help",
+		Usage:   "[name]",
+		Help:    "Lists every var and command, or describes one of them",
+		MaxArgs: 1,
+		Run: func(args []string) (string, error) {
+			if len(args) == 1 {
+				return c.help(args[0])
+			}
+			var lines []string
+			for _, name := range c.Names() {
+				help, _ := c.help(name)
+				lines = append(lines, help)
+			}
+			return strings.Join(lines, "\n"), nil
+		},
+		Complete: func(args []string) []string {
+			if len(args) != 1 {
+				return nil
+			}
+			return c.Names()
+		},
+	})
+	c.AddCommand(Command{
+		Name:     "reset",
+		Usage:    "<var>",
+		Help:     "Sets a var back to its default value",
+		MinArgs:  1,
+		MaxArgs:  1,
+		Complete: completeVar(c),
+		Run: func(args []string) (string, error) {
+			v, ok := c.vars[args[0]]
+			if !ok {
+				return "", fmt.Errorf("unknown var: %s", args[0])
+			}
+			v.Reset()
+			return describe(v), nil
+		},
+	})
+	c.AddCommand(Command{
+		Name:    "history",
+		Help:    "Lists the executed lines",
+		MaxArgs: 0,
+		Run: func(args []string) (string, error) {
+			return strings.Join(c.history, "\n"), nil
+		},
+	})
+}
+
+func (c *Console) help(name string) (string, error) {
+	v, ok := c.vars[name]
+	if ok {
+		return fmt.Sprintf("%s - %s", describe(v), v.Help()), nil
+	}
+	cmd, ok := c.commands[name]
+	if ok {
+		return strings.TrimSpace(fmt.Sprintf("%s %s", cmd.Name, cmd.Usage)) + " - " + cmd.Help, nil
+	}
+	return "", fmt.Errorf("unknown name: %s", name)
+}
+
+//--------------------------------------------------------------------------------
+// - Persistence
+//--------------------------------------------------------------------------------
+
+// Returns the values of every persistent var
+func (c *Console) Saved() map[string]string {
+	ret := maps.Clone(c.saved)
+	for name, v := range c.vars {
+		if v.Persisted() {
+			ret[name] = v.String()
+		}
+	}
+	return ret
+}
+
+// Restores saved values. Values for vars that are already persistent are applied immediately, the rest are applied when their var is marked persistent.
+func (c *Console) Restore(saved map[string]string) error {
+	var errs []error
+	for name, value := range saved {
+		c.saved[name] = value
+		v, ok := c.vars[name]
+		if ok && v.Persisted() {
+			errs = append(errs, v.Set(value))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Saves every persistent var to storage under the key
+func (c *Console) Save(key string) error {
+	return storage.SetItem(key, c.Saved())
+}
+
+// Loads the persistent vars from storage under the key
+func (c *Console) Load(key string) error {
+	saved, err := storage.GetItem[map[string]string](key)
+	if err != nil {
+		return err
+	}
+	if saved == nil {
+		return nil // Nothing has been saved yet
+	}
+	return c.Restore(*saved)
+}
@@ -0,0 +1,248 @@
+package console
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestConsole() (*Console, *Var[float64], *Var[bool]) {
+	c := New()
+	gravity := NewVar(c, "phys.gravity", 9.8, "Downwards acceleration")
+	colliders := NewVar(c, "render.showColliders", false, "Draws the colliders")
+	NewVar(c, "phys.step", 16*time.Millisecond, "Fixed time step")
+	c.AddCommand(Command{
+		Name:    "spawn",
+		Usage:   "<prefab> [count]",
+		Help:    "Spawns prefabs",
+		MinArgs: 1,
+		MaxArgs: 2,
+		Run: func(args []string) (string, error) {
+			return "spawned " + strings.Join(args, " "), nil
+		},
+		Complete: func(args []string) []string {
+			return []string{"goblin", "golem", "orc"}
+		},
+	})
+	return c, gravity, colliders
+}
+
+func TestExec(t *testing.T) {
+	c, gravity, colliders := newTestConsole()
+
+	changes := 0
+	gravity.OnChange(func(float64) { changes++ })
+
+	out, err := c.Exec(`phys.gravity 12.5; render.showColliders true; spawn "big goblin" 3`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gravity.Get() != 12.5 || !colliders.Get() || changes != 1 {
+		t.Errorf("expected vars to be set: %v %v %d", gravity.Get(), colliders.Get(), changes)
+	}
+	if out != "spawned big goblin 3" {
+		t.Errorf("unexpected output: %q", out)
+	}
+
+	out, _ = c.Exec("phys.gravity")
+	if out != "phys.gravity = 12.5 (float64, default 9.8)" {
+		t.Errorf("unexpected var description: %q", out)
+	}
+	c.Exec("reset phys.gravity")
+	if gravity.Get() != 9.8 {
+		t.Errorf("expected reset to restore the default, got %v", gravity.Get())
+	}
+
+	failures := []string{
+		"phys.gravity heavy",
+		"phys.step 5",
+		"spawn",
+		"spawn a b c",
+		"nope",
+		`spawn "goblin`,
+	}
+	for _, line := range failures {
+		_, err := c.Exec(line)
+		if err == nil {
+			t.Errorf("expected error for %q", line)
+		}
+	}
+
+	c.Exec("phys.gravity")
+	c.Exec("phys.gravity")
+	history := c.History()
+	if len(history) != 3+len(failures)+1 || history[len(history)-1] != "phys.gravity" {
+		t.Errorf("expected history without repeated lines, got %v", history)
+	}
+}
+
+func TestComplete(t *testing.T) {
+	c, _, _ := newTestConsole()
+
+	tests := []struct {
+		line, completed string
+		candidates      []string
+	}{
+		{"phys.", "phys.", []string{"phys.gravity", "phys.step"}},
+		{"phys.g", "phys.gravity ", []string{"phys.gravity"}},
+		{"render.showColliders t", "render.showColliders true ", []string{"true"}},
+		{"spawn go", "spawn go", []string{"goblin", "golem"}},
+		{"reset phys.s", "reset phys.step ", []string{"phys.step"}},
+		{"spawn orc; re", "spawn orc; re", []string{"render.showColliders", "reset"}},
+		{"zzz", "zzz", nil},
+	}
+	for _, test := range tests {
+		completed, candidates := c.Complete(test.line)
+		if completed != test.completed || !slices.Equal(candidates, test.candidates) {
+			t.Errorf("%q: expected %q %v, got %q %v", test.line, test.completed, test.candidates, completed, candidates)
+		}
+	}
+}
+
+func TestPersist(t *testing.T) {
+	c, gravity, _ := newTestConsole()
+	gravity.Persistent(c)
+	c.Exec("phys.gravity 3")
+	c.Exec("render.showColliders true")
+
+	saved := c.Saved()
+	if len(saved) != 1 || saved["phys.gravity"] != "3" {
+		t.Errorf("expected only persistent vars to be saved, got %v", saved)
+	}
+
+	// Saved values apply once the var is registered and marked persistent
+	c2 := New()
+	err := c2.Restore(saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gravity2 := NewVar(c2, "phys.gravity", 9.8, "")
+	if gravity2.Get() != 9.8 {
+		t.Errorf("expected non persistent var to keep its default")
+	}
+	gravity2.Persistent(c2)
+	if gravity2.Get() != 3 {
+		t.Errorf("expected saved value to be applied, got %v", gravity2.Get())
+	}
+}
+
+func TestServe(t *testing.T) {
+	c, gravity, _ := newTestConsole()
+	c.AddCommand(Command{
+		Name:    "say",
+		MaxArgs: 3,
+		Run: func(args []string) (string, error) {
+			return strings.Join(args, "\n"), nil
+		},
+	})
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Serve(inR, outW)
+	}()
+
+	// Note: Requests only execute when the console is updated, like the game loop would do every frame
+	stop := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				c.Update()
+				time.Sleep(time.Millisecond)
+			}
+		}
+	}()
+	defer close(stop)
+
+	out := bufio.NewScanner(outR)
+	request := func(line string) []string {
+		io.WriteString(inW, line+"\n")
+		var lines []string
+		for out.Scan() {
+			lines = append(lines, out.Text())
+			if out.Text() == "ok" || strings.HasPrefix(out.Text(), "err ") {
+				break
+			}
+		}
+		return lines
+	}
+
+	if res := request("phys.gravity 1.5"); !slices.Equal(res, []string{"ok"}) {
+		t.Errorf("unexpected response: %v", res)
+	}
+	if gravity.Get() != 1.5 {
+		t.Errorf("expected var to be set over the protocol")
+	}
+	if res := request("spawn"); !slices.Equal(res, []string{"err usage: spawn <prefab> [count]"}) {
+		t.Errorf("unexpected response: %v", res)
+	}
+	if res := request("?spawn g"); !slices.Equal(res, []string{"> spawn go", "> goblin", "> golem", "ok"}) {
+		t.Errorf("unexpected completion response: %v", res)
+	}
+	// Output which looks like a status line doesn't end the response early
+	if res := request(`say ok "err nope" done`); !slices.Equal(res, []string{"> ok", "> err nope", "> done", "ok"}) {
+		t.Errorf("unexpected output response: %v", res)
+	}
+
+	inW.Close()
+	err := <-done
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServeClose(t *testing.T) {
+	c, _, _ := newTestConsole()
+
+	inR, inW := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Serve(inR, io.Discard)
+	}()
+
+	// Update is never called, like after the app has exited, so the request waits until the console is closed
+	io.WriteString(inW, "phys.gravity 2\n")
+	c.Close()
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrClosed) {
+			t.Errorf("expected closed error, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected Serve to return once the console is closed")
+	}
+	inW.Close()
+}
+
+func TestListenClose(t *testing.T) {
+	c, _, _ := newTestConsole()
+	listener, err := c.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Skip("can't listen on localhost:", err)
+	}
+	defer listener.Close()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	io.WriteString(conn, "phys.gravity 2\n")
+
+	c.Close()
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = conn.Read(make([]byte, 1))
+	var netErr net.Error
+	if err == nil || (errors.As(err, &netErr) && netErr.Timeout()) {
+		t.Errorf("expected the connection to be closed, got %v", err)
+	}
+}
@@ -0,0 +1,220 @@
+package console
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/unitoftime/ecs"
+	"github.com/unitoftime/flow"
+)
+
+// A line which was sent by a frontend, and the channel that its response is sent back on
+type request struct {
+	line  string
+	reply chan []string
+}
+
+// ErrClosed is returned by Serve when the console is closed
+var ErrClosed = errors.New("console: closed")
+
+// Serve runs the text protocol over a reader and writer until the reader or the console is closed. Requests are queued, and are only executed when Update is called, so that they run on the same goroutine as the game's systems.
+//
+// Every line read is a request, and every request gets a response of zero or more output lines, followed by a status line. Output lines start with "> ", so that they can't be mistaken for the status line, which is either "ok" or "err <message>". Request lines that start with "?" are completion requests, which respond with the completed line and then the candidates, one per output line.
+//
+//	client: phys.gravity 12
+//	server: ok
+//	client: ?phys.gr
+//	server: > phys.gravity
+//	server: > phys.gravity
+//	server: ok
+func (c *Console) Serve(r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	reply := make(chan []string, 1)
+	for scanner.Scan() {
+		select {
+		case c.requests <- request{scanner.Text(), reply}:
+		case <-c.done:
+			return ErrClosed
+		}
+
+		var lines []string
+		select {
+		case lines = <-reply:
+		case <-c.done:
+			return ErrClosed
+		}
+
+		_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+		if err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// Executes every queued request from the frontends. This must be called regularly (the Plugin calls it once per frame) or the frontends block until the console is closed.
+func (c *Console) Update() {
+	for {
+		select {
+		case req := <-c.requests:
+			req.reply <- c.handle(req.line)
+		default:
+			return
+		}
+	}
+}
+
+// Closes the console's frontends. Serve returns ErrClosed, and the connections accepted by Listen are closed. The Plugin calls this on exit.
+func (c *Console) Close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+}
+
+// The prefix of every output line in a response
+const outputPrefix = "> "
+
+func (c *Console) handle(line string) []string {
+	var lines []string
+	output := func(out string) {
+		lines = append(lines, outputPrefix+out)
+	}
+
+	if strings.HasPrefix(line, "?") {
+		completed, candidates := c.Complete(line[1:])
+		output(completed)
+		for _, candidate := range candidates {
+			output(candidate)
+		}
+		return append(lines, "ok")
+	}
+
+	out, err := c.Exec(line)
+	if out != "" {
+		for _, l := range strings.Split(out, "\n") {
+			output(l)
+		}
+	}
+	if err != nil {
+		return append(lines, "err "+err.Error())
+	}
+	return append(lines, "ok")
+}
+
+// Serves the text protocol on stdin and stdout in the background
+func (c *Console) ServeStdin() {
+	go func() {
+		err := c.Serve(os.Stdin, os.Stdout)
+		if err != nil && !errors.Is(err, ErrClosed) {
+			fmt.Println("Error Console Stdin:", err)
+		}
+	}()
+}
+
+// Listens on the address, and serves the text protocol to every connection in the background. Close the listener to stop accepting connections. Closing the console also closes the connections.
+// Note: The console has no authentication, so only listen on addresses that you trust (ie "localhost:7777")
+func (c *Console) Listen(network, addr string) (net.Listener, error) {
+	listener, err := net.Listen(network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return // The listener was closed
+			}
+			go func() {
+				defer conn.Close()
+				served := make(chan struct{})
+				defer close(served)
+				go func() {
+					select {
+					case <-c.done:
+						conn.Close() // Unblocks Serve if it is waiting to read
+					case <-served:
+					}
+				}()
+				c.Serve(conn, conn)
+			}()
+		}
+	}()
+	return listener, nil
+}
+
+// Plugin adds the Console resource, and a system which executes the frontends' requests once per frame
+type Plugin struct {
+	Stdin      bool   // If set, the console is served on stdin and stdout
+	Addr       string // If set, the console is served on this tcp address
+	StorageKey string // If set, the persistent vars are loaded from storage on startup, and saved on exit
+
+	listener net.Listener
+}
+
+func (p *Plugin) Initialize(world *ecs.World) {
+	app := flow.MustApp(world)
+
+	c := New()
+	ecs.PutResource(world, c)
+	c.AddCommand(Command{
+		Name: "quit",
+		Help: "Exits the app",
+		Run: func(args []string) (string, error) {
+			app.Exit()
+			return "", nil
+		},
+	})
+
+	if p.StorageKey != "" {
+		err := c.Load(p.StorageKey)
+		if err != nil {
+			fmt.Println("Error Loading Console:", err)
+		}
+	}
+
+	app.AddSystems(ecs.StagePreFixedUpdate, ecs.System{
+		Name: "console.UpdateSystem",
+		Func: func(dt time.Duration) {
+			c.Update()
+		},
+	})
+
+	if p.Stdin {
+		c.ServeStdin()
+	}
+	if p.Addr != "" {
+		listener, err := c.Listen("tcp", p.Addr)
+		if err != nil {
+			fmt.Println("Error Console Listen:", err)
+		}
+		p.listener = listener
+	}
+}
+
+func (p *Plugin) Exit(world *ecs.World) {
+	if p.listener != nil {
+		p.listener.Close()
+	}
+
+	c := ecs.GetResource[Console](world)
+	if c == nil {
+		return
+	}
+	// Note: Update won't be called again, so release any frontends that are waiting on it
+	c.Close()
+
+	if p.StorageKey == "" {
+		return
+	}
+	err := c.Save(p.StorageKey)
+	if err != nil {
+		fmt.Println("Error Saving Console:", err)
+	}
+}
@@ -0,0 +1,155 @@
+package console
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Value is the set of types that a console variable can hold
+type Value interface {
+	bool | int | int64 | float64 | string | time.Duration
+}
+
+// Var is a typed console variable (cvar). Vars are registered on a console with NewVar, then read by game code with Get.
+type Var[T Value] struct {
+	name     string
+	help     string
+	def      T
+	value    T
+	persist  bool
+	onChange []func(T)
+}
+
+// The untyped view of a Var that the console uses
+type cvar interface {
+	Name() string
+	Help() string
+	String() string
+	Default() string
+	Type() string
+	Set(string) error
+	Reset()
+	Persisted() bool
+}
+
+// Registers a new console variable. Panics if the name is already used by a var or a command.
+func NewVar[T Value](c *Console, name string, def T, help string) *Var[T] {
+	v := &Var[T]{
+		name:  name,
+		help:  help,
+		def:   def,
+		value: def,
+	}
+	c.addName(name)
+	c.vars[name] = v
+	return v
+}
+
+func (v *Var[T]) Name() string {
+	return v.name
+}
+
+func (v *Var[T]) Help() string {
+	return v.help
+}
+
+// Returns the current value
+func (v *Var[T]) Get() T {
+	return v.value
+}
+
+// Sets the value and notifies the change hooks
+func (v *Var[T]) SetValue(val T) {
+	if val == v.value {
+		return
+	}
+	v.value = val
+	for _, hook := range v.onChange {
+		hook(val)
+	}
+}
+
+// Parses and sets the value
+func (v *Var[T]) Set(s string) error {
+	val, err := parse[T](s)
+	if err != nil {
+		return fmt.Errorf("%s: %w", v.name, err)
+	}
+	v.SetValue(val)
+	return nil
+}
+
+// Sets the value back to its default
+func (v *Var[T]) Reset() {
+	v.SetValue(v.def)
+}
+
+func (v *Var[T]) String() string {
+	return format(v.value)
+}
+
+func (v *Var[T]) Default() string {
+	return format(v.def)
+}
+
+// Returns the name of the value type
+func (v *Var[T]) Type() string {
+	return fmt.Sprintf("%T", v.def)
+}
+
+// Adds a hook which is called every time the value changes
+func (v *Var[T]) OnChange(hook func(T)) *Var[T] {
+	v.onChange = append(v.onChange, hook)
+	return v
+}
+
+// Marks the var to be saved by Console.Save. If the console has a saved value for the var, it is applied immediately.
+func (v *Var[T]) Persistent(c *Console) *Var[T] {
+	v.persist = true
+	saved, ok := c.saved[v.name]
+	if ok {
+		err := v.Set(saved)
+		if err != nil {
+			fmt.Println("Error Loading Console Var:", err)
+		}
+	}
+	return v
+}
+
+func (v *Var[T]) Persisted() bool {
+	return v.persist
+}
+
+func parse[T Value](s string) (T, error) {
+	var ret T
+	var err error
+	switch p := any(&ret).(type) {
+	case *bool:
+		*p, err = strconv.ParseBool(s)
+	case *int:
+		*p, err = strconv.Atoi(s)
+	case *int64:
+		*p, err = strconv.ParseInt(s, 10, 64)
+	case *float64:
+		*p, err = strconv.ParseFloat(s, 64)
+	case *string:
+		*p = s
+	case *time.Duration:
+		*p, err = time.ParseDuration(s)
+	}
+	if err != nil {
+		return ret, fmt.Errorf("invalid %T value: %q", ret, s)
+	}
+	return ret, nil
+}
+
+func format[T Value](v T) string {
+	switch v := any(v).(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case string:
+		return v
+	}
+	return fmt.Sprint(v)
+}
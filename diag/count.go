@@ -0,0 +1,74 @@
+package diag
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+
+	"github.com/unitoftime/ecs"
+)
+
+// The number of entities with a tracked component, sampled every frame
+type counter struct {
+	name    string
+	count   func(world *ecs.World) int
+	samples *series
+}
+
+// Tracks the number of entities that have component T, sampled every frame and reported under the name of T
+func Track[T any](d *Diagnostics) {
+	var query *ecs.View1[T]
+	d.counters = append(d.counters, &counter{
+		name: reflect.TypeFor[T]().String(),
+		count: func(world *ecs.World) int {
+			if query == nil {
+				query = ecs.Query1[T](world)
+			}
+			return query.Count()
+		},
+	})
+}
+
+// Counts the entities and archetypes in a world, through the ecs package's exported api
+// Note: The ecs package doesn't export these counts. So entities are found through the ids that World.NewId hands out, and are grouped into archetypes by the components that ecs.ReadEntity returns for them. Entities written with ids that didn't come from NewId, or written again after they were deleted, aren't counted.
+type census struct {
+	next ecs.Id              // The first id that hasn't been scanned yet
+	live map[ecs.Id]struct{} // The entities found by earlier scans
+	keys map[string]struct{} // Reused between counts
+	ids  []ecs.CompId        // Reused between counts
+}
+
+func newCensus() *census {
+	return &census{
+		next: ecs.InvalidEntity + 1,
+		live: make(map[ecs.Id]struct{}),
+		keys: make(map[string]struct{}),
+	}
+}
+
+// Returns the number of entities and archetypes in the world. Only the ids that were handed out since the last count are scanned, along with the entities that were alive then.
+// Note: This takes an id from the world, to find out how many ids have been handed out
+func (c *census) count(world *ecs.World) (entities, archetypes int) {
+	end := world.NewId()
+	for id := c.next; id < end; id++ {
+		c.live[id] = struct{}{}
+	}
+	c.next = end + 1
+
+	clear(c.keys)
+	for id := range c.live {
+		ent := ecs.ReadEntity(world, id)
+		if ent == nil {
+			delete(c.live, id)
+			continue
+		}
+
+		c.ids = c.ids[:0]
+		for _, comp := range ent.Comps() {
+			c.ids = append(c.ids, comp.CompId())
+		}
+		slices.Sort(c.ids)
+		c.keys[fmt.Sprint(c.ids)] = struct{}{}
+	}
+	return len(c.live), len(c.keys)
+}
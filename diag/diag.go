@@ -0,0 +1,368 @@
+package diag
+
+import (
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/unitoftime/ecs"
+	"github.com/unitoftime/flow"
+	"github.com/unitoftime/flow/asset"
+)
+
+// The default number of frames that the rolling statistics are computed over
+const DefaultWindow = 120
+
+// The default number of frames between counts of the world's entities and archetypes
+const DefaultCountInterval = 30
+
+// Diagnostics is a plugin which tracks how long every system, stage, and frame takes, along with the number of entities, archetypes, and in progress asset loads, and the number of entities with each component added with Track. It is stored as a resource so that systems can query it.
+// Note: All times are reported in milliseconds, summed over the frame. The fixed update stage can run several times in a frame, or not at all.
+type Diagnostics struct {
+	Window int           // The number of frames that the rolling statistics are computed over
+	Server *asset.Server // If set, the asset server's queue depth is tracked
+
+	// The number of frames between counts of the world's entities and archetypes. Counting reads every entity, so it isn't done every frame.
+	// Note: The ecs package doesn't export these counts, so each count takes an id from World.NewId to find the ids that have been handed out. Entities written with your own ids aren't counted.
+	CountInterval int
+
+	world  *ecs.World
+	frames int
+	census *census
+
+	frame      *series
+	entities   *series
+	archetypes *series
+	assets     *series
+
+	stages   map[ecs.Stage]*timing
+	systems  map[systemKey]*timing
+	counters []*counter
+}
+
+type systemKey struct {
+	stage ecs.Stage
+	index int
+}
+
+// The time spent in a stage or system, accumulated over the current frame
+type timing struct {
+	stage   ecs.Stage
+	index   int
+	name    string
+	total   time.Duration
+	samples *series
+}
+
+func New() *Diagnostics {
+	return &Diagnostics{
+		Window:        DefaultWindow,
+		CountInterval: DefaultCountInterval,
+		stages:        make(map[ecs.Stage]*timing),
+		systems:       make(map[systemKey]*timing),
+	}
+}
+
+func (d *Diagnostics) Initialize(world *ecs.World) {
+	app := flow.MustApp(world)
+
+	if d.Window <= 0 {
+		d.Window = DefaultWindow
+	}
+	if d.CountInterval <= 0 {
+		d.CountInterval = DefaultCountInterval
+	}
+	d.world = world
+	d.census = newCensus()
+	d.frame = newSeries(d.Window)
+	d.entities = newSeries(d.Window)
+	d.archetypes = newSeries(d.Window)
+	d.assets = newSeries(d.Window)
+
+	ecs.PutResource(world, d)
+	app.SetProfiler(d)
+}
+
+func (d *Diagnostics) ProfileSystem(stage ecs.Stage, index int, name string, elapsed time.Duration) {
+	key := systemKey{stage, index}
+	t, ok := d.systems[key]
+	if !ok {
+		t = &timing{stage: stage, index: index, samples: newSeries(d.Window)}
+		d.systems[key] = t
+	}
+	t.name = name
+	t.total += elapsed
+}
+
+func (d *Diagnostics) ProfileStage(stage ecs.Stage, elapsed time.Duration) {
+	t, ok := d.stages[stage]
+	if !ok {
+		t = &timing{stage: stage, name: StageName(stage), samples: newSeries(d.Window)}
+		d.stages[stage] = t
+	}
+	t.total += elapsed
+}
+
+func (d *Diagnostics) ProfileFrame(elapsed time.Duration) {
+	d.frames++
+	d.frame.add(milliseconds(elapsed))
+
+	for _, t := range d.stages {
+		t.samples.add(milliseconds(t.total))
+		t.total = 0
+	}
+	for _, t := range d.systems {
+		t.samples.add(milliseconds(t.total))
+		t.total = 0
+	}
+
+	// Note: The first frame is always counted
+	if (d.frames-1)%d.CountInterval == 0 {
+		entities, archetypes := d.census.count(d.world)
+		d.entities.add(float64(entities))
+		d.archetypes.add(float64(archetypes))
+	}
+	for _, c := range d.counters {
+		if c.samples == nil {
+			c.samples = newSeries(d.Window)
+		}
+		c.samples.add(float64(c.count(d.world)))
+	}
+	if d.Server != nil {
+		d.assets.add(float64(d.Server.Pending()))
+	}
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// Returns the name of the stage
+func StageName(stage ecs.Stage) string {
+	switch stage {
+	case ecs.StageStartup:
+		return "Startup"
+	case ecs.StagePreFixedUpdate:
+		return "PreFixedUpdate"
+	case ecs.StageFixedUpdate:
+		return "FixedUpdate"
+	case ecs.StagePostFixedUpdate:
+		return "PostFixedUpdate"
+	case ecs.StageUpdate:
+		return "Update"
+	}
+	return "Stage" + strconv.Itoa(int(stage))
+}
+
+//--------------------------------------------------------------------------------
+// - Queries
+//--------------------------------------------------------------------------------
+
+// Entry holds the timing statistics of a stage or system
+type Entry struct {
+	Stage string
+	Name  string // The system name. For stages this is the stage name.
+	Summary
+}
+
+// Count holds the statistics of the number of entities with a tracked component
+type Count struct {
+	Name string // The name of the component type
+	Summary
+}
+
+// Report is a point in time copy of every statistic
+type Report struct {
+	Frames        int // The total number of frames that have been profiled
+	Frame         Summary
+	Stages        []Entry // Sorted in stage order
+	Systems       []Entry // Sorted in stage and then schedule order
+	Entities      Summary
+	Archetypes    Summary
+	Counts        []Count // Sorted in the order that they were tracked
+	AssetsPending Summary
+}
+
+// Returns the number of frames that have been profiled
+func (d *Diagnostics) Frames() int {
+	return d.frames
+}
+
+// Returns the frame time statistics
+func (d *Diagnostics) Frame() Summary {
+	return d.frame.summary()
+}
+
+// Returns the timing statistics of a stage
+func (d *Diagnostics) Stage(stage ecs.Stage) (Summary, bool) {
+	t, ok := d.stages[stage]
+	if !ok {
+		return Summary{}, false
+	}
+	return t.samples.summary(), true
+}
+
+// Returns the timing statistics of the first system with the given name
+func (d *Diagnostics) System(name string) (Summary, bool) {
+	for _, e := range entries(d.systems) {
+		if e.Name == name {
+			return e.Summary, true
+		}
+	}
+	return Summary{}, false
+}
+
+// Returns the timing statistics of every system, in stage and then schedule order
+func (d *Diagnostics) Systems() []Entry {
+	return entries(d.systems)
+}
+
+// Returns the n systems with the highest average time
+func (d *Diagnostics) Slowest(n int) []Entry {
+	slowest := entries(d.systems)
+	slices.SortStableFunc(slowest, func(a, b Entry) int {
+		return cmp.Compare(b.Avg, a.Avg)
+	})
+	return slowest[:min(n, len(slowest))]
+}
+
+// Returns the number of entities in the world, sampled every CountInterval frames
+func (d *Diagnostics) Entities() Summary {
+	return d.entities.summary()
+}
+
+// Returns the number of archetypes in the world, sampled every CountInterval frames
+func (d *Diagnostics) Archetypes() Summary {
+	return d.archetypes.summary()
+}
+
+// Returns the number of entities that have component T. Returns false if T isn't tracked.
+func Entities[T any](d *Diagnostics) (Summary, bool) {
+	name := reflect.TypeFor[T]().String()
+	for _, c := range d.Counts() {
+		if c.Name == name {
+			return c.Summary, true
+		}
+	}
+	return Summary{}, false
+}
+
+// Returns the entity count statistics of every tracked component
+func (d *Diagnostics) Counts() []Count {
+	ret := make([]Count, len(d.counters))
+	for i, c := range d.counters {
+		ret[i] = Count{Name: c.name}
+		if c.samples != nil {
+			ret[i].Summary = c.samples.summary()
+		}
+	}
+	return ret
+}
+
+// Returns the number of in progress asset loads. This is empty if Server isn't set.
+func (d *Diagnostics) AssetsPending() Summary {
+	return d.assets.summary()
+}
+
+// Returns a copy of every statistic
+func (d *Diagnostics) Report() Report {
+	return Report{
+		Frames:        d.frames,
+		Frame:         d.Frame(),
+		Stages:        entries(d.stages),
+		Systems:       entries(d.systems),
+		Entities:      d.Entities(),
+		Archetypes:    d.Archetypes(),
+		Counts:        d.Counts(),
+		AssetsPending: d.AssetsPending(),
+	}
+}
+
+func entries[K comparable](timings map[K]*timing) []Entry {
+	sorted := make([]*timing, 0, len(timings))
+	for _, t := range timings {
+		sorted = append(sorted, t)
+	}
+	slices.SortFunc(sorted, func(a, b *timing) int {
+		return cmp.Or(cmp.Compare(a.stage, b.stage), cmp.Compare(a.index, b.index))
+	})
+
+	ret := make([]Entry, len(sorted))
+	for i, t := range sorted {
+		ret[i] = Entry{
+			Stage:   StageName(t.stage),
+			Name:    t.name,
+			Summary: t.samples.summary(),
+		}
+	}
+	return ret
+}
+
+//--------------------------------------------------------------------------------
+// - Export
+//--------------------------------------------------------------------------------
+
+// Writes the report as indented JSON
+func (r Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// Writes the report as CSV, with one row per statistic. Times are in milliseconds.
+func (r Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"kind", "stage", "name", "samples", "last", "avg", "min", "max", "p50", "p95", "p99"})
+
+	row := func(kind, stage, name string, s Summary) {
+		cw.Write([]string{
+			kind, stage, name,
+			strconv.Itoa(s.Samples),
+			formatFloat(s.Last),
+			formatFloat(s.Avg),
+			formatFloat(s.Min),
+			formatFloat(s.Max),
+			formatFloat(s.P50),
+			formatFloat(s.P95),
+			formatFloat(s.P99),
+		})
+	}
+
+	row("frame", "", "", r.Frame)
+	for _, e := range r.Stages {
+		row("stage", e.Stage, e.Name, e.Summary)
+	}
+	for _, e := range r.Systems {
+		row("system", e.Stage, e.Name, e.Summary)
+	}
+	row("count", "", "entities", r.Entities)
+	row("count", "", "archetypes", r.Archetypes)
+	for _, c := range r.Counts {
+		row("count", "", c.Name, c.Summary)
+	}
+	row("count", "", "assets_pending", r.AssetsPending)
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// Returns a short human readable summary of the report
+func (r Report) String() string {
+	var counts strings.Builder
+	for _, c := range r.Counts {
+		fmt.Fprintf(&counts, ", %d %s", int(c.Last), c.Name)
+	}
+	return fmt.Sprintf("frame avg %.2fms p99 %.2fms, %d entities, %d archetypes%s, %d assets pending",
+		r.Frame.Avg, r.Frame.P99, int(r.Entities.Last), int(r.Archetypes.Last), counts.String(), int(r.AssetsPending.Last))
+}
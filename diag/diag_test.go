@@ -0,0 +1,153 @@
+package diag
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/unitoftime/ecs"
+	"github.com/unitoftime/flow"
+)
+
+type position struct {
+	X, Y int
+}
+
+type velocity struct {
+	X, Y int
+}
+
+func TestPercentiles(t *testing.T) {
+	s := newSeries(100)
+	for i := 1; i <= 200; i++ {
+		s.add(float64(i))
+	}
+
+	sum := s.summary()
+	if sum.Samples != 100 {
+		t.Fatalf("expected the window to hold 100 samples, got %d", sum.Samples)
+	}
+	if sum.Min != 101 || sum.Max != 200 || sum.Last != 200 {
+		t.Errorf("unexpected min/max/last: %+v", sum)
+	}
+	if sum.Avg != 150.5 || sum.P50 != 150 || sum.P95 != 195 || sum.P99 != 199 {
+		t.Errorf("unexpected avg/percentiles: %+v", sum)
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	app := flow.NewApp()
+	app.SetFixedTimeStep(10 * time.Millisecond)
+
+	d := New()
+	d.Window = 10
+	d.CountInterval = 5
+	Track[position](d)
+	Track[velocity](d)
+	app.AddPlugin(d)
+
+	var first ecs.Id
+	app.OnStartup(func(world *ecs.World) {
+		first = world.NewId()
+		world.Write(first, ecs.C(position{}))
+		for i := 0; i < 4; i++ {
+			world.Write(world.NewId(), ecs.C(position{}))
+		}
+		world.Write(world.NewId(), ecs.C(position{}), ecs.C(velocity{}))
+	})
+	app.AddSystems(ecs.StageFixedUpdate, ecs.System{
+		Name: "test.SlowSystem",
+		Func: func(dt time.Duration) {
+			time.Sleep(time.Millisecond)
+		},
+	})
+	app.AddSystems(ecs.StageUpdate, ecs.System{
+		Name: "test.FastSystem",
+		Func: func(dt time.Duration) {},
+	})
+
+	for i := 0; i < 20; i++ {
+		app.Step(20 * time.Millisecond)
+	}
+
+	if d.Frames() != 20 {
+		t.Errorf("expected 20 frames, got %d", d.Frames())
+	}
+	if got := ecs.GetResource[Diagnostics](app.World()); got != d {
+		t.Errorf("expected diagnostics to be a resource")
+	}
+
+	slow, ok := d.System("test.SlowSystem")
+	if !ok {
+		t.Fatalf("expected slow system to be profiled")
+	}
+	// Note: The fixed stage runs twice per frame
+	if slow.Samples != 10 || slow.Min < 2 {
+		t.Errorf("expected slow system to be summed over the frame: %+v", slow)
+	}
+	if slowest := d.Slowest(1); len(slowest) != 1 || slowest[0].Name != "test.SlowSystem" {
+		t.Errorf("expected slow system to be the slowest: %+v", slowest)
+	}
+	fixed, _ := d.Stage(ecs.StageFixedUpdate)
+	if fixed.Avg < slow.Avg || d.Frame().Avg < fixed.Avg {
+		t.Errorf("expected stage and frame times to include the system: %+v %+v", fixed, d.Frame())
+	}
+
+	positions, ok := Entities[position](d)
+	if !ok || positions.Last != 6 {
+		t.Errorf("expected 6 entities with a position, got %+v", positions)
+	}
+	velocities, _ := Entities[velocity](d)
+	if velocities.Last != 1 {
+		t.Errorf("expected 1 entity with a velocity, got %+v", velocities)
+	}
+	if _, ok := Entities[int](d); ok {
+		t.Errorf("expected untracked component to have no count")
+	}
+	if d.Entities().Samples != 4 || d.Entities().Last != 6 || d.Archetypes().Last != 2 {
+		t.Errorf("expected 6 entities in 2 archetypes, counted every 5 frames: %+v %+v", d.Entities(), d.Archetypes())
+	}
+
+	// Entities found by an earlier count are dropped once they are deleted
+	world := app.World()
+	ecs.Delete(world, first)
+	world.Write(world.NewId(), ecs.C(velocity{}))
+	for i := 0; i < 5; i++ {
+		app.Step(20 * time.Millisecond)
+	}
+	if d.Entities().Last != 6 || d.Archetypes().Last != 3 {
+		t.Errorf("expected 6 entities in 3 archetypes, got %+v %+v", d.Entities(), d.Archetypes())
+	}
+
+	report := d.Report()
+	var buf bytes.Buffer
+	err := report.WriteJSON(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Report
+	err = json.Unmarshal(buf.Bytes(), &decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Frames != 25 || len(decoded.Systems) != len(report.Systems) {
+		t.Errorf("expected json report to round trip: %+v", decoded)
+	}
+
+	buf.Reset()
+	err = report.WriteCSV(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Header, frame, stages, systems, the entity and archetype counts, the two tracked counts, and the asset count
+	expected := 1 + 1 + len(report.Stages) + len(report.Systems) + 5
+	if len(rows) != expected {
+		t.Errorf("expected %d csv rows, got %d", expected, len(rows))
+	}
+}
@@ -0,0 +1,75 @@
+package diag
+
+import (
+	"math"
+	"slices"
+
+	"github.com/unitoftime/flow/ds"
+)
+
+// Summary holds the rolling statistics of a series of samples
+type Summary struct {
+	Samples int
+	Last    float64
+	Avg     float64
+	Min     float64
+	Max     float64
+	P50     float64
+	P95     float64
+	P99     float64
+}
+
+// Returns the value at percentile p (in the range [0, 1]) of the sorted samples, using the nearest rank
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	rank = max(0, min(rank, len(sorted)-1))
+	return sorted[rank]
+}
+
+// A rolling window of samples
+type series struct {
+	samples *ds.RingBuffer[float64]
+	scratch []float64
+}
+
+func newSeries(window int) *series {
+	// Note: The ring buffer holds one less element than its capacity
+	return &series{
+		samples: ds.NewRingBuffer[float64](window + 1),
+	}
+}
+
+func (s *series) add(v float64) {
+	s.samples.Add(v)
+}
+
+func (s *series) summary() Summary {
+	n := s.samples.Len()
+	if n == 0 {
+		return Summary{}
+	}
+
+	s.scratch = s.scratch[:0]
+	total := 0.0
+	for i := 0; i < n; i++ {
+		v := s.samples.Get(i)
+		s.scratch = append(s.scratch, v)
+		total += v
+	}
+	last := s.scratch[n-1]
+	slices.Sort(s.scratch)
+
+	return Summary{
+		Samples: n,
+		Last:    last,
+		Avg:     total / float64(n),
+		Min:     s.scratch[0],
+		Max:     s.scratch[n-1],
+		P50:     percentile(s.scratch, 0.50),
+		P95:     percentile(s.scratch, 0.95),
+		P99:     percentile(s.scratch, 0.99),
+	}
+}
@@ -0,0 +1,295 @@
+package dialogue
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/unitoftime/flow/asset"
+	"github.com/unitoftime/flow/asset/serde"
+)
+
+const guard = `
+title: Start
+tags: entry
+---
+<<declare $gold = 10>>
+// Greet the player
+Guard: Halt! Who goes there? #angry
+-> A traveler.
+    Guard: Move along then.
+-> Someone with gold. <<if $gold >= 5>>
+    <<set $gold = $gold - 5>>
+    Guard: Welcome, friend. You have {$gold} gold left.
+    <<jump Market>>
+-> A thief. <<if $gold > 100>>
+<<shake 0.5>>
+Guard: Begone!
+===
+
+title: Market
+---
+<<if $gold == 0>>
+    Merchant: You're broke.
+<<elseif $gold < 10>>
+    Merchant: Browse all you want.
+<<else>>
+    Merchant: Welcome!
+<<endif>>
+===
+`
+
+// Returns a runner for the guard script, and the shake commands it has run
+func newGuard(t *testing.T) (*Runner, *[]string) {
+	t.Helper()
+	script, err := Parse([]byte(guard))
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := new([]string)
+	r := NewRegistry()
+	r.Register("shake", func(run *Runner, args []string) error {
+		*calls = append(*calls, "shake "+strings.Join(args, " "))
+		return nil
+	})
+	return NewRunner(script, r), calls
+}
+
+// Returns the text of every line until options are shown or the dialogue ends
+func readLines(t *testing.T, r *Runner) ([]string, Event) {
+	t.Helper()
+	var lines []string
+	for {
+		event, err := r.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if event.Kind != EventLine {
+			return lines, event
+		}
+		text := event.Line.Text
+		if event.Line.Speaker != "" {
+			text = event.Line.Speaker + ": " + text
+		}
+		lines = append(lines, text)
+	}
+}
+
+func TestExpressions(t *testing.T) {
+	vars := map[string]Value{"gold": Number(7), "name": String("Ann"), "met": Bool(true)}
+	lookup := func(name string) (Value, bool) {
+		v, ok := vars[name]
+		return v, ok
+	}
+	tests := []struct {
+		src      string
+		expected Value
+	}{
+		{"1 + 2 * 3", Number(7)},
+		{"(1 + 2) * 3", Number(9)},
+		{"-$gold + 10 % 4", Number(-5)},
+		{"$gold >= 5 and not $met", Bool(false)},
+		{"$gold > 100 || $met", Bool(true)},
+		{`$name is "Ann"`, Bool(true)},
+		{`"Hi " + $name + "!"`, String("Hi Ann!")},
+		{`"a\"b"`, String(`a"b`)},
+		{"$gold != 7", Bool(false)},
+		{"false and $missing", Bool(false)},
+	}
+	for _, test := range tests {
+		e, err := parseExpr(test.src)
+		if err != nil {
+			t.Errorf("%s: %v", test.src, err)
+			continue
+		}
+		val, err := e.eval(lookup)
+		if err != nil || val != test.expected {
+			t.Errorf("%s: expected %v, got %v (%v)", test.src, test.expected, val, err)
+		}
+	}
+
+	for _, src := range []string{"1 +", "(1", "gold", "1 = 2", `"open`} {
+		if _, err := parseExpr(src); err == nil {
+			t.Errorf("%s: expected parse error", src)
+		}
+	}
+	for _, src := range []string{"$missing", "1 + true", "not 1", "1 / 0"} {
+		e, _ := parseExpr(src)
+		if _, err := e.eval(lookup); err == nil {
+			t.Errorf("%s: expected eval error", src)
+		}
+	}
+}
+
+func TestRunner(t *testing.T) {
+	r, calls := newGuard(t)
+	if err := r.Start("Start"); err != nil {
+		t.Fatal(err)
+	}
+
+	event, err := r.Next()
+	if err != nil || event.Kind != EventLine || event.Line.Speaker != "Guard" || event.Line.Text != "Halt! Who goes there?" || !slices.Equal(event.Line.Tags, []string{"angry"}) {
+		t.Fatalf("unexpected first line: %+v %v", event, err)
+	}
+
+	_, event = readLines(t, r)
+	if event.Kind != EventOptions || len(event.Options) != 3 {
+		t.Fatalf("expected options, got %+v", event)
+	}
+	if !event.Options[1].Available || event.Options[2].Available || event.Options[1].Text != "Someone with gold." {
+		t.Errorf("unexpected option availability: %+v", event.Options)
+	}
+	if _, err := r.Next(); !errors.Is(err, ErrWaitingForChoice) {
+		t.Errorf("expected Next to wait for a choice, got %v", err)
+	}
+	if r.Choose(2) == nil {
+		t.Errorf("expected unavailable option to be rejected")
+	}
+
+	if err := r.Choose(1); err != nil {
+		t.Fatal(err)
+	}
+	lines, event := readLines(t, r)
+	expected := []string{"Guard: Welcome, friend. You have 5 gold left.", "Merchant: Browse all you want."}
+	if !slices.Equal(lines, expected) || event.Kind != EventEnd {
+		t.Errorf("expected %q then end, got %q %+v", expected, lines, event)
+	}
+	if r.Running() || r.Visits("Market") != 1 {
+		t.Errorf("expected dialogue to end after visiting the market")
+	}
+
+	// Variables are kept between dialogues, and the other option continues after the group
+	r.Start("Start")
+	readLines(t, r)
+	r.Choose(0)
+	lines, _ = readLines(t, r)
+	expected = []string{"Guard: Move along then.", "Guard: Begone!"}
+	if !slices.Equal(lines, expected) || !slices.Equal(*calls, []string{"shake 0.5"}) {
+		t.Errorf("expected %q with a shake, got %q %q", expected, lines, *calls)
+	}
+	if gold, _ := r.Var("gold"); gold != Number(5) {
+		t.Errorf("expected gold to be kept, got %v", gold)
+	}
+}
+
+func TestSaveRestore(t *testing.T) {
+	r, _ := newGuard(t)
+	r.Start("Start")
+	r.Next()
+	_, shown := readLines(t, r)
+
+	dat, err := serde.Marshal(r.Save())
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err := serde.Unmarshal[State](dat)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored, _ := newGuard(t)
+	if err := restored.Restore(state); err != nil {
+		t.Fatal(err)
+	}
+	event, err := restored.Next()
+	if err != nil || event.Kind != EventOptions || len(event.Options) != len(shown.Options) {
+		t.Fatalf("expected the options to be shown again, got %+v %v", event, err)
+	}
+	restored.Choose(1)
+	lines, _ := readLines(t, restored)
+	if len(lines) == 0 || lines[0] != "Guard: Welcome, friend. You have 5 gold left." || restored.Visits("Start") != 1 {
+		t.Errorf("unexpected restored dialogue: %q", lines)
+	}
+
+	bad := state
+	bad.Node = "Missing"
+	if restored.Restore(bad) == nil {
+		t.Errorf("expected unknown saved node to fail")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		src string
+		err string
+	}{
+		{"title: A\n", "missing ---"},
+		{"title: A\nHello\n===", "expected a header"},
+		{"title: A\n---\nHello", "missing ==="},
+		{"---\nHello\n===", "no title"},
+		{"title: A\n---\n===\ntitle: A\n---\n===", "duplicate node A"},
+		{"title: A\n---\n<<if $x>>\nHello\n===", "missing <<endif>>"},
+		{"title: A\n---\n<<endif>>\n===", "unexpected <<endif>>"},
+		{"title: A\n---\n<<set gold = 1>>\n===", "line 3: expected <<set $variable = value>>"},
+		{"title: A\n---\nYou have {$gold gold\n===", "missing }"},
+	}
+	for _, test := range tests {
+		_, err := Parse([]byte(test.src))
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("expected error containing %q, got %v", test.err, err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	script, err := Parse([]byte(`
+title: Start
+---
+<<if $quest_done>>
+    <<jump Reward>>
+<<endif>>
+Hi {$player}!
+<<jump Missing>>
+<<dance>>
+===
+title: Reward
+---
+Here you go.
+===
+title: Secret
+---
+Nobody comes here.
+===
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewRegistry()
+	r.Declare("player", String("Ann"))
+
+	var messages []string
+	for _, p := range Validate(script, r) {
+		messages = append(messages, p.Error())
+	}
+	expected := []string{
+		"dialogue: Start: line 4: undefined variable $quest_done",
+		"dialogue: Start: line 8: jump to unknown node Missing",
+		"dialogue: Start: line 9: unregistered command dance",
+		"dialogue: Secret: line 15: node can't be reached",
+	}
+	if !slices.Equal(messages, expected) {
+		t.Errorf("expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(messages, "\n"))
+	}
+}
+
+func TestStrictLoader(t *testing.T) {
+	r := NewRegistry()
+	r.Register("shake", func(run *Runner, args []string) error { return nil })
+	server := asset.NewServer()
+	server.RegisterFilesystem("", asset.NewFilesystem("", fstest.MapFS{
+		"guard.dialogue": {Data: []byte(guard)},
+		"bad.dialogue":   {Data: []byte("title: A\n---\n<<jump B>>\n===")},
+	}))
+	asset.Register(server, Loader{Registry: r, Strict: true})
+
+	script, err := asset.Load[Script](server, "guard.dialogue").Get()
+	if err != nil || script.Node("Market") == nil {
+		t.Errorf("expected script to load: %v", err)
+	}
+	_, err = asset.Load[Script](server, "bad.dialogue").Get()
+	if err == nil || !strings.Contains(err.Error(), "jump to unknown node B") {
+		t.Errorf("expected strict loader to fail validation, got %v", err)
+	}
+}
@@ -0,0 +1,385 @@
+package dialogue
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expressions are used by conditions, <<set>> and {interpolation}. They support numbers, "strings", true and false, $variables, parentheses and these operators, from lowest to highest precedence:
+//
+//	or ||
+//	and &&
+//	== != (also is)
+//	< <= > >=
+//	+ -
+//	* / %
+//	not ! - (unary)
+type expr interface {
+	eval(vars func(string) (Value, bool)) (Value, error)
+	visit(fn func(variable string)) // Calls fn with every variable that the expression reads
+}
+
+type literalExpr struct {
+	val Value
+}
+
+func (e literalExpr) eval(vars func(string) (Value, bool)) (Value, error) {
+	return e.val, nil
+}
+func (e literalExpr) visit(fn func(string)) {}
+
+type varExpr struct {
+	name string
+}
+
+func (e varExpr) eval(vars func(string) (Value, bool)) (Value, error) {
+	val, ok := vars(e.name)
+	if !ok {
+		return Value{}, fmt.Errorf("undefined variable $%s", e.name)
+	}
+	return val, nil
+}
+func (e varExpr) visit(fn func(string)) {
+	fn(e.name)
+}
+
+type unaryExpr struct {
+	op string
+	a  expr
+}
+
+func (e unaryExpr) eval(vars func(string) (Value, bool)) (Value, error) {
+	a, err := e.a.eval(vars)
+	if err != nil {
+		return Value{}, err
+	}
+	switch e.op {
+	case "not":
+		if a.Kind != KindBool {
+			return Value{}, fmt.Errorf("not needs a bool, got %s", a.Kind)
+		}
+		return Bool(!a.Bool), nil
+	case "-":
+		if a.Kind != KindNumber {
+			return Value{}, fmt.Errorf("- needs a number, got %s", a.Kind)
+		}
+		return Number(-a.Num), nil
+	}
+	return Value{}, fmt.Errorf("unknown operator %s", e.op)
+}
+func (e unaryExpr) visit(fn func(string)) {
+	e.a.visit(fn)
+}
+
+type binaryExpr struct {
+	op   string
+	a, b expr
+}
+
+func (e binaryExpr) eval(vars func(string) (Value, bool)) (Value, error) {
+	a, err := e.a.eval(vars)
+	if err != nil {
+		return Value{}, err
+	}
+
+	// Note: and/or short circuit, so the right side can read variables that are only set when the left side allows it
+	switch e.op {
+	case "and", "or":
+		if a.Kind != KindBool {
+			return Value{}, fmt.Errorf("%s needs bools, got %s", e.op, a.Kind)
+		}
+		if a.Bool == (e.op == "or") {
+			return a, nil
+		}
+		b, err := e.b.eval(vars)
+		if err != nil {
+			return Value{}, err
+		}
+		if b.Kind != KindBool {
+			return Value{}, fmt.Errorf("%s needs bools, got %s", e.op, b.Kind)
+		}
+		return b, nil
+	}
+
+	b, err := e.b.eval(vars)
+	if err != nil {
+		return Value{}, err
+	}
+
+	switch e.op {
+	case "==":
+		return Bool(a == b), nil
+	case "!=":
+		return Bool(a != b), nil
+	case "+":
+		if a.Kind == KindString || b.Kind == KindString {
+			return String(a.String() + b.String()), nil
+		}
+	}
+
+	if a.Kind != KindNumber || b.Kind != KindNumber {
+		return Value{}, fmt.Errorf("%s needs numbers, got %s and %s", e.op, a.Kind, b.Kind)
+	}
+	switch e.op {
+	case "<":
+		return Bool(a.Num < b.Num), nil
+	case "<=":
+		return Bool(a.Num <= b.Num), nil
+	case ">":
+		return Bool(a.Num > b.Num), nil
+	case ">=":
+		return Bool(a.Num >= b.Num), nil
+	case "+":
+		return Number(a.Num + b.Num), nil
+	case "-":
+		return Number(a.Num - b.Num), nil
+	case "*":
+		return Number(a.Num * b.Num), nil
+	case "/":
+		if b.Num == 0 {
+			return Value{}, fmt.Errorf("division by zero")
+		}
+		return Number(a.Num / b.Num), nil
+	case "%":
+		if b.Num == 0 {
+			return Value{}, fmt.Errorf("division by zero")
+		}
+		return Number(float64(int64(a.Num) % int64(b.Num))), nil
+	}
+	return Value{}, fmt.Errorf("unknown operator %s", e.op)
+}
+func (e binaryExpr) visit(fn func(string)) {
+	e.a.visit(fn)
+	e.b.visit(fn)
+}
+
+//--------------------------------------------------------------------------------
+// - Lexer
+//--------------------------------------------------------------------------------
+
+type tokenKind uint8
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokString
+	tokVar
+	tokIdent
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// Aliases of the word operators
+var opAliases = map[string]string{
+	"&&": "and",
+	"||": "or",
+	"!":  "not",
+	"is": "==",
+}
+
+func lex(src string) ([]token, error) {
+	var tokens []token
+	r := []rune(src)
+	for i := 0; i < len(r); {
+		c := r[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case unicode.IsDigit(c) || (c == '.' && i+1 < len(r) && unicode.IsDigit(r[i+1])):
+			start := i
+			for i < len(r) && (unicode.IsDigit(r[i]) || r[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{tokNumber, string(r[start:i])})
+		case c == '"':
+			var b strings.Builder
+			i++
+			for ; i < len(r) && r[i] != '"'; i++ {
+				if r[i] == '\\' && i+1 < len(r) {
+					i++
+				}
+				b.WriteRune(r[i])
+			}
+			if i >= len(r) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			i++
+			tokens = append(tokens, token{tokString, b.String()})
+		case c == '$' || isIdentRune(c, true):
+			start := i
+			i++
+			for i < len(r) && isIdentRune(r[i], false) {
+				i++
+			}
+			word := string(r[start:i])
+			switch {
+			case c == '$':
+				if len(word) == 1 {
+					return nil, fmt.Errorf("missing variable name after $")
+				}
+				tokens = append(tokens, token{tokVar, word[1:]})
+			case word == "and" || word == "or" || word == "not" || word == "is":
+				tokens = append(tokens, token{tokOp, aliasOp(word)})
+			default:
+				tokens = append(tokens, token{tokIdent, word})
+			}
+		default:
+			two := ""
+			if i+1 < len(r) {
+				two = string(r[i : i+2])
+			}
+			switch two {
+			case "==", "!=", "<=", ">=", "&&", "||":
+				tokens = append(tokens, token{tokOp, aliasOp(two)})
+				i += 2
+				continue
+			}
+			if !strings.ContainsRune("+-*/%<>()!", c) {
+				return nil, fmt.Errorf("unexpected character %q", c)
+			}
+			tokens = append(tokens, token{tokOp, aliasOp(string(c))})
+			i++
+		}
+	}
+	return append(tokens, token{kind: tokEOF}), nil
+}
+
+func aliasOp(op string) string {
+	if alias, ok := opAliases[op]; ok {
+		return alias
+	}
+	return op
+}
+
+func isIdentRune(c rune, first bool) bool {
+	if c == '_' || unicode.IsLetter(c) {
+		return true
+	}
+	return !first && unicode.IsDigit(c)
+}
+
+//--------------------------------------------------------------------------------
+// - Parser
+//--------------------------------------------------------------------------------
+
+// The binary operators of each precedence level, from lowest to highest
+var precedence = [][]string{
+	{"or"},
+	{"and"},
+	{"==", "!="},
+	{"<", "<=", ">", ">="},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func parseExpr(src string) (expr, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	e, err := p.binary(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q in expression", p.peek().text)
+	}
+	return e, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) binary(level int) (expr, error) {
+	if level >= len(precedence) {
+		return p.unary()
+	}
+	a, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if t.kind != tokOp || !slices.Contains(precedence[level], t.text) {
+			return a, nil
+		}
+		p.next()
+		b, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		a = binaryExpr{op: t.text, a: a, b: b}
+	}
+}
+
+func (p *parser) unary() (expr, error) {
+	t := p.peek()
+	if t.kind == tokOp && (t.text == "not" || t.text == "-") {
+		p.next()
+		a, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return unaryExpr{op: t.text, a: a}, nil
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (expr, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNumber:
+		n, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", t.text)
+		}
+		return literalExpr{Number(n)}, nil
+	case tokString:
+		return literalExpr{String(t.text)}, nil
+	case tokVar:
+		return varExpr{t.text}, nil
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return literalExpr{Bool(true)}, nil
+		case "false":
+			return literalExpr{Bool(false)}, nil
+		}
+		return nil, fmt.Errorf("unknown identifier %q, variables start with $", t.text)
+	case tokOp:
+		if t.text == "(" {
+			e, err := p.binary(0)
+			if err != nil {
+				return nil, err
+			}
+			if end := p.next(); end.kind != tokOp || end.text != ")" {
+				return nil, fmt.Errorf("missing )")
+			}
+			return e, nil
+		}
+		return nil, fmt.Errorf("unexpected %q in expression", t.text)
+	}
+	return nil, fmt.Errorf("unexpected end of expression")
+}
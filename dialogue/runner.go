@@ -0,0 +1,431 @@
+package dialogue
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// CommandFunc runs a <<command>>. The args are split on spaces, and any {expressions} in them are replaced by their values.
+type CommandFunc func(r *Runner, args []string) error
+
+// Registry holds the commands that scripts can call, and the variables that the game provides
+type Registry struct {
+	commands map[string]CommandFunc
+	vars     map[string]Value
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		commands: make(map[string]CommandFunc),
+		vars:     make(map[string]Value),
+	}
+}
+
+// The registry used by the package level functions
+var DefaultRegistry = NewRegistry()
+
+// Registers a command into the default registry
+func Register(name string, fn CommandFunc) {
+	DefaultRegistry.Register(name, fn)
+}
+
+// Registers a command. Panics if the name is already used, or is a built in command.
+func (r *Registry) Register(name string, fn CommandFunc) {
+	_, exists := r.commands[name]
+	if exists || slices.Contains(builtinCommands, name) {
+		panic(fmt.Sprintf("dialogue: command name already registered: %s", name))
+	}
+	r.commands[name] = fn
+}
+
+// Declares a variable that the game provides, with its starting value. Declared variables pass validation, even if no script sets them.
+func (r *Registry) Declare(name string, val Value) {
+	r.vars[name] = val
+}
+
+// Returns the names of every registered command, in sorted order
+func (r *Registry) Names() []string {
+	return slices.Sorted(maps.Keys(r.commands))
+}
+
+var builtinCommands = []string{"if", "elseif", "else", "endif", "set", "declare", "jump", "stop"}
+
+//--------------------------------------------------------------------------------
+// - Runner
+//--------------------------------------------------------------------------------
+
+// EventKind is the kind of an Event
+type EventKind uint8
+
+const (
+	EventLine    EventKind = iota // A line should be shown. Call Next once the player has read it
+	EventOptions                  // Options should be shown. Call Choose with the option that the player picked
+	EventEnd                      // The dialogue has ended
+)
+
+// Event is returned by the runner whenever the dialogue needs the game to show something. The runner doesn't know about the UI, so any UI can show the events.
+type Event struct {
+	Kind    EventKind
+	Line    Line     // Set for EventLine
+	Options []Option // Set for EventOptions
+}
+
+type Line struct {
+	Node    string
+	Speaker string // Empty if the line has no speaker
+	Text    string
+	Tags    []string
+}
+
+type Option struct {
+	Index     int
+	Text      string
+	Tags      []string
+	Available bool // False if the option's condition failed. Unavailable options can be shown greyed out, or hidden
+}
+
+// State is the saveable state of a runner. It only holds plain data, so it can be serialized with serde.
+type State struct {
+	Node   string // The current node, empty if the dialogue isn't running
+	PC     int    // The instruction to resume from. It is only valid for the version of the script that it was saved with
+	Vars   map[string]Value
+	Visits map[string]int // The number of times each node has been entered
+}
+
+// ErrWaitingForChoice is returned by Next while the runner is waiting for an option to be chosen
+var ErrWaitingForChoice = errors.New("dialogue: waiting for an option to be chosen")
+
+// The maximum number of instructions that can run between two events, to catch scripts which jump in a loop without showing anything
+const maxSteps = 100_000
+
+type waitKind uint8
+
+const (
+	waitNone waitKind = iota
+	waitLine
+	waitOptions
+)
+
+// Runner runs a script. Call Start, then call Next to get each event, and Choose when options are shown:
+//
+//	runner.Start("Start")
+//	event, err := runner.Next()
+//	for err == nil && event.Kind != dialogue.EventEnd {
+//		if event.Kind == dialogue.EventOptions {
+//			err = runner.Choose(ui.Pick(event.Options))
+//		} else {
+//			ui.Show(event.Line)
+//		}
+//		event, err = runner.Next()
+//	}
+type Runner struct {
+	script   *Script
+	registry *Registry
+	state    State
+	node     *Node
+	waiting  waitKind
+	options  []Option
+	targets  []int // The instructions that each option jumps to
+}
+
+// Returns a runner for the script. The variables start with the values declared by the registry, then by the script. If registry is nil, DefaultRegistry is used.
+func NewRunner(script *Script, registry *Registry) *Runner {
+	if registry == nil {
+		registry = DefaultRegistry
+	}
+	r := &Runner{
+		script:   script,
+		registry: registry,
+		state: State{
+			Vars:   make(map[string]Value),
+			Visits: make(map[string]int),
+		},
+	}
+	maps.Copy(r.state.Vars, registry.vars)
+	maps.Copy(r.state.Vars, script.Vars)
+	return r
+}
+
+// Starts the dialogue at the node. Variables and visit counts are kept from earlier dialogues.
+func (r *Runner) Start(node string) error {
+	return r.Jump(node)
+}
+
+func (r *Runner) enter(title string) error {
+	node := r.script.Node(title)
+	if node == nil {
+		r.stop()
+		return fmt.Errorf("unknown node %s", title)
+	}
+	r.node = node
+	r.state.Node = title
+	r.state.PC = 0
+	r.state.Visits[title]++
+	r.waiting = waitNone
+	return nil
+}
+
+func (r *Runner) stop() {
+	r.node = nil
+	r.state.Node = ""
+	r.state.PC = 0
+	r.waiting = waitNone
+	r.options = nil
+	r.targets = nil
+}
+
+// Returns true if the dialogue is running
+func (r *Runner) Running() bool {
+	return r.node != nil
+}
+
+// Returns the value of the variable
+func (r *Runner) Var(name string) (Value, bool) {
+	val, ok := r.state.Vars[name]
+	return val, ok
+}
+
+// Sets the variable
+func (r *Runner) SetVar(name string, val Value) {
+	r.state.Vars[name] = val
+}
+
+// Returns the number of times that the node has been entered
+func (r *Runner) Visits(node string) int {
+	return r.state.Visits[node]
+}
+
+// Runs the dialogue until the next line or group of options, or until it ends. Calling Next after a line moves past it.
+func (r *Runner) Next() (Event, error) {
+	switch r.waiting {
+	case waitLine:
+		r.state.PC++
+		r.waiting = waitNone
+	case waitOptions:
+		return Event{}, ErrWaitingForChoice
+	}
+
+	for steps := 0; r.node != nil; steps++ {
+		if steps > maxSteps {
+			err := fmt.Errorf("dialogue: %s: ran %d instructions without showing anything", r.state.Node, maxSteps)
+			r.stop()
+			return Event{}, err
+		}
+		if r.state.PC < 0 || r.state.PC >= len(r.node.code) {
+			r.stop()
+			break
+		}
+
+		node, line := r.state.Node, r.node.code[r.state.PC].line
+		event, ok, err := r.step()
+		if err != nil {
+			err = fmt.Errorf("dialogue: %s: line %d: %w", node, line, err)
+			r.stop()
+			return Event{}, err
+		}
+		if ok {
+			return event, nil
+		}
+	}
+	return Event{Kind: EventEnd}, nil
+}
+
+// Runs the current instruction. Returns true if it produced an event.
+func (r *Runner) step() (Event, bool, error) {
+	in := &r.node.code[r.state.PC]
+	switch in.op {
+	case opLine:
+		text, err := in.text.eval(r.Var)
+		if err != nil {
+			return Event{}, false, err
+		}
+		r.waiting = waitLine
+		return Event{
+			Kind: EventLine,
+			Line: Line{
+				Node:    r.state.Node,
+				Speaker: in.speaker,
+				Text:    text,
+				Tags:    in.tags,
+			},
+		}, true, nil
+
+	case opOption:
+		// Note: PC is left on the first option while they are shown, so that a saved state shows them again when it is restored
+		start := r.state.PC
+		r.options = r.options[:0]
+		r.targets = r.targets[:0]
+		pc := start
+		for ; r.node.code[pc].op == opOption; pc++ {
+			opt := &r.node.code[pc]
+			text, err := opt.text.eval(r.Var)
+			if err != nil {
+				return Event{}, false, err
+			}
+			available := true
+			if opt.cond != nil {
+				available, err = r.evalCond(opt.cond)
+				if err != nil {
+					return Event{}, false, err
+				}
+			}
+			r.options = append(r.options, Option{
+				Index:     len(r.options),
+				Text:      text,
+				Tags:      opt.tags,
+				Available: available,
+			})
+			r.targets = append(r.targets, opt.target)
+		}
+
+		show := &r.node.code[pc]
+		if !slices.ContainsFunc(r.options, func(o Option) bool { return o.Available }) {
+			r.state.PC = show.target
+			return Event{}, false, nil
+		}
+		r.waiting = waitOptions
+		return Event{
+			Kind:    EventOptions,
+			Options: slices.Clone(r.options),
+		}, true, nil
+
+	case opShowOptions:
+		// Note: Only reached by jumping into the middle of a group, which the compiler never does
+		r.state.PC = in.target
+
+	case opJump:
+		r.state.PC = in.target
+
+	case opJumpIfFalse:
+		ok, err := r.evalCond(in.cond)
+		if err != nil {
+			return Event{}, false, err
+		}
+		if ok {
+			r.state.PC++
+		} else {
+			r.state.PC = in.target
+		}
+
+	case opSet:
+		val, err := in.expr.eval(r.Var)
+		if err != nil {
+			return Event{}, false, err
+		}
+		r.state.Vars[in.name] = val
+		r.state.PC++
+
+	case opCommand:
+		fn, ok := r.registry.commands[in.name]
+		if !ok {
+			return Event{}, false, fmt.Errorf("unregistered command %s", in.name)
+		}
+		args := make([]string, len(in.args))
+		for i, arg := range in.args {
+			val, err := arg.eval(r.Var)
+			if err != nil {
+				return Event{}, false, err
+			}
+			args[i] = val
+		}
+		// Note: Advance first, so that commands can jump the runner to another node
+		r.state.PC++
+		err := fn(r, args)
+		if err != nil {
+			return Event{}, false, fmt.Errorf("%s: %w", in.name, err)
+		}
+
+	case opGoto:
+		err := r.enter(in.name)
+		if err != nil {
+			return Event{}, false, err
+		}
+
+	case opStop:
+		r.stop()
+	}
+	return Event{}, false, nil
+}
+
+func (r *Runner) evalCond(cond expr) (bool, error) {
+	val, err := cond.eval(r.Var)
+	if err != nil {
+		return false, err
+	}
+	if val.Kind != KindBool {
+		return false, fmt.Errorf("condition must be a bool, got %s", val.Kind)
+	}
+	return val.Bool, nil
+}
+
+// Chooses one of the options that were shown, then call Next to continue
+func (r *Runner) Choose(index int) error {
+	if r.waiting != waitOptions {
+		return fmt.Errorf("dialogue: no options are being shown")
+	}
+	if index < 0 || index >= len(r.options) {
+		return fmt.Errorf("dialogue: option %d out of range", index)
+	}
+	if !r.options[index].Available {
+		return fmt.Errorf("dialogue: option %d is not available", index)
+	}
+	r.state.PC = r.targets[index]
+	r.waiting = waitNone
+	return nil
+}
+
+// Jumps to the node, ie from a command. Call Next to continue.
+func (r *Runner) Jump(node string) error {
+	err := r.enter(node)
+	if err != nil {
+		return fmt.Errorf("dialogue: %w", err)
+	}
+	return nil
+}
+
+// Ends the dialogue
+func (r *Runner) Stop() {
+	r.stop()
+}
+
+// Returns a copy of the runner's state. If a line or options are being shown, they are shown again when the state is restored.
+func (r *Runner) Save() State {
+	return State{
+		Node:   r.state.Node,
+		PC:     r.state.PC,
+		Vars:   maps.Clone(r.state.Vars),
+		Visits: maps.Clone(r.state.Visits),
+	}
+}
+
+// Restores a saved state. Call Next to show the line or options that were being shown when it was saved.
+func (r *Runner) Restore(state State) error {
+	var node *Node
+	if state.Node != "" {
+		node = r.script.Node(state.Node)
+		if node == nil {
+			return fmt.Errorf("dialogue: saved node %s is not in the script", state.Node)
+		}
+		if state.PC < 0 || state.PC >= len(node.code) {
+			return fmt.Errorf("dialogue: saved position %d is outside of node %s", state.PC, state.Node)
+		}
+	}
+
+	r.stop()
+	r.node = node
+	r.state = State{
+		Node:   state.Node,
+		PC:     state.PC,
+		Vars:   maps.Clone(state.Vars),
+		Visits: maps.Clone(state.Visits),
+	}
+	if r.state.Vars == nil {
+		r.state.Vars = make(map[string]Value)
+	}
+	if r.state.Visits == nil {
+		r.state.Visits = make(map[string]int)
+	}
+	return nil
+}
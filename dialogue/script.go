@@ -0,0 +1,561 @@
+package dialogue
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/unitoftime/flow/asset"
+)
+
+// Script is a dialogue asset. Scripts are written in a format based on Yarn Spinner, so that writers can edit them without touching Go. A script is a list of nodes, each with a header and a body:
+//
+//	title: Start
+//	tags: entry
+//	---
+//	<<declare $gold = 10>>
+//	Guard: Halt! Who goes there?
+//	-> A traveler.
+//	    Guard: Move along then.
+//	-> Someone with gold. <<if $gold >= 5>>
+//	    <<set $gold = $gold - 5>>
+//	    Guard: Welcome, friend. You have {$gold} gold left. #happy
+//	    <<jump Market>>
+//	<<shake_camera 0.5>>
+//	Guard: Begone!
+//	===
+//
+// The body is made of:
+//   - Lines: Optional "Speaker: " then the text. {expressions} in the text are replaced by their values, and trailing #tags are passed along with the line
+//   - Options: Lines starting with "->". The lines indented under an option run when it is chosen, then the dialogue continues after the group of options
+//   - <<if expr>>, <<elseif expr>>, <<else>>, <<endif>>
+//   - <<set $var = expr>>, and <<declare $var = value>> which gives a variable its starting value
+//   - <<jump Node>> continues at another node, and <<stop>> ends the dialogue
+//   - Any other <<command arg...>> calls the Go function registered with that name
+//
+// Lines and options can end with <<if expr>> to only show them when the condition is true. Lines starting with // are comments. The dialogue ends when it reaches the end of a node.
+type Script struct {
+	Nodes []*Node
+	Vars  map[string]Value // The starting values from <<declare>>
+
+	source []byte
+	nodes  map[string]*Node
+}
+
+// Node is one node of a script
+type Node struct {
+	Title   string
+	Tags    []string
+	Headers map[string]string
+	Line    int // The line of the title in the script file
+
+	code []instr
+}
+
+// Returns the node with the title, or nil
+func (s *Script) Node(title string) *Node {
+	return s.nodes[title]
+}
+
+// Parses a script
+func Parse(data []byte) (*Script, error) {
+	script := &Script{
+		Vars:   make(map[string]Value),
+		source: data,
+		nodes:  make(map[string]*Node),
+	}
+
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); {
+		// Skip the blank lines between nodes
+		if isBlank(lines[i]) {
+			i++
+			continue
+		}
+
+		node := &Node{Headers: make(map[string]string), Line: i + 1}
+		for ; i < len(lines) && strings.TrimSpace(lines[i]) != "---"; i++ {
+			if isBlank(lines[i]) {
+				continue
+			}
+			key, val, ok := strings.Cut(lines[i], ":")
+			if !ok {
+				return nil, fmt.Errorf("dialogue: line %d: expected a header (key: value) or ---", i+1)
+			}
+			node.Headers[strings.TrimSpace(key)] = strings.TrimSpace(val)
+		}
+		if i >= len(lines) {
+			return nil, fmt.Errorf("dialogue: line %d: node header is missing ---", node.Line)
+		}
+		i++ // Skip the ---
+
+		node.Title = node.Headers["title"]
+		node.Tags = strings.Fields(node.Headers["tags"])
+		if node.Title == "" {
+			return nil, fmt.Errorf("dialogue: line %d: node has no title", node.Line)
+		}
+		if strings.ContainsAny(node.Title, " \t") {
+			return nil, fmt.Errorf("dialogue: line %d: node title %q can't contain spaces", node.Line, node.Title)
+		}
+		if script.nodes[node.Title] != nil {
+			return nil, fmt.Errorf("dialogue: line %d: duplicate node %s", node.Line, node.Title)
+		}
+
+		start := i
+		for ; i < len(lines) && strings.TrimSpace(lines[i]) != "==="; i++ {
+		}
+		if i >= len(lines) {
+			return nil, fmt.Errorf("dialogue: line %d: node %s is missing ===", node.Line, node.Title)
+		}
+		body := lines[start:i]
+		i++ // Skip the ===
+
+		err := script.compile(node, body, start+1)
+		if err != nil {
+			return nil, err
+		}
+		script.Nodes = append(script.Nodes, node)
+		script.nodes[node.Title] = node
+	}
+	return script, nil
+}
+
+func isBlank(line string) bool {
+	line = strings.TrimSpace(line)
+	return line == "" || strings.HasPrefix(line, "//")
+}
+
+//--------------------------------------------------------------------------------
+// - Loader
+//--------------------------------------------------------------------------------
+
+// Loader loads scripts through the asset server
+type Loader struct {
+	Registry *Registry // Optional: The registry used to validate commands and variables. Defaults to DefaultRegistry
+	Strict   bool      // If set, scripts which fail validation fail to load
+}
+
+func (l Loader) Ext() []string {
+	return []string{".dialogue", ".yarn"}
+}
+
+func (l Loader) Load(server *asset.Server, data []byte) (*Script, error) {
+	script, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	if l.Strict {
+		problems := Validate(script, l.Registry)
+		if len(problems) > 0 {
+			return nil, problems[0]
+		}
+	}
+	return script, nil
+}
+
+func (l Loader) Store(server *asset.Server, script *Script) ([]byte, error) {
+	return script.source, nil
+}
+
+//--------------------------------------------------------------------------------
+// - Compiler
+//--------------------------------------------------------------------------------
+
+type opcode uint8
+
+const (
+	opLine        opcode = iota // Shows a line
+	opOption                    // Adds an option, which jumps to target when it is chosen
+	opShowOptions               // Shows the added options. If none are available, it jumps to target
+	opJump                      // Jumps to target
+	opJumpIfFalse               // Jumps to target if cond is false
+	opSet                       // Sets the variable name to expr
+	opCommand                   // Calls the command name with args
+	opGoto                      // Continues at the node name
+	opStop                      // Ends the dialogue
+)
+
+type instr struct {
+	op      opcode
+	line    int // The line in the script file
+	speaker string
+	text    text
+	tags    []string
+	cond    expr
+	expr    expr
+	name    string
+	args    []text
+	target  int
+}
+
+// text is a line of text with {expressions} in it
+type text []segment
+
+type segment struct {
+	literal string
+	expr    expr // If set, the segment is the value of the expression
+}
+
+func parseText(src string) (text, error) {
+	var t text
+	for {
+		open := strings.IndexByte(src, '{')
+		if open < 0 {
+			break
+		}
+		end := strings.IndexByte(src[open:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("missing } in text")
+		}
+		e, err := parseExpr(src[open+1 : open+end])
+		if err != nil {
+			return nil, err
+		}
+		if open > 0 {
+			t = append(t, segment{literal: src[:open]})
+		}
+		t = append(t, segment{expr: e})
+		src = src[open+end+1:]
+	}
+	if src != "" {
+		t = append(t, segment{literal: src})
+	}
+	return t, nil
+}
+
+func (t text) eval(vars func(string) (Value, bool)) (string, error) {
+	var b strings.Builder
+	for _, seg := range t {
+		if seg.expr == nil {
+			b.WriteString(seg.literal)
+			continue
+		}
+		val, err := seg.expr.eval(vars)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(val.String())
+	}
+	return b.String(), nil
+}
+
+func (t text) visit(fn func(string)) {
+	for _, seg := range t {
+		if seg.expr != nil {
+			seg.expr.visit(fn)
+		}
+	}
+}
+
+type bodyLine struct {
+	num    int // The line in the script file
+	indent int
+	text   string
+}
+
+// Compiles the body of a node into a flat list of instructions
+type compiler struct {
+	script *Script
+	node   *Node
+	lines  []bodyLine
+	pos    int
+}
+
+func (s *Script) compile(node *Node, body []string, first int) error {
+	c := &compiler{script: s, node: node}
+	for i, line := range body {
+		if isBlank(line) {
+			continue
+		}
+		trimmed := strings.TrimLeft(line, " \t")
+		indent := 0
+		for _, r := range line[:len(line)-len(trimmed)] {
+			if r == '\t' {
+				indent += 4
+			} else {
+				indent++
+			}
+		}
+		c.lines = append(c.lines, bodyLine{first + i, indent, strings.TrimSpace(trimmed)})
+	}
+
+	err := c.block(0)
+	if err != nil {
+		return fmt.Errorf("dialogue: %s: %w", node.Title, err)
+	}
+	if c.pos < len(c.lines) {
+		line := c.lines[c.pos]
+		return fmt.Errorf("dialogue: %s: line %d: unexpected %s", node.Title, line.num, line.text)
+	}
+	c.emit(instr{op: opStop})
+	return nil
+}
+
+func (c *compiler) emit(in instr) int {
+	c.node.code = append(c.node.code, in)
+	return len(c.node.code) - 1
+}
+
+// Compiles lines until the indentation drops below minIndent, or an <<elseif>>, <<else>> or <<endif>> is reached
+func (c *compiler) block(minIndent int) error {
+	for c.pos < len(c.lines) {
+		line := c.lines[c.pos]
+		if line.indent < minIndent {
+			return nil
+		}
+
+		if strings.HasPrefix(line.text, "->") {
+			err := c.options(line.indent)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if name, args, ok := parseCommand(line.text); ok {
+			switch name {
+			case "elseif", "else", "endif":
+				return nil
+			case "if":
+				err := c.ifBlock(minIndent)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			c.pos++
+			err := c.command(line, name, args)
+			if err != nil {
+				return fmt.Errorf("line %d: %w", line.num, err)
+			}
+			continue
+		}
+
+		c.pos++
+		err := c.line(line)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line.num, err)
+		}
+	}
+	return nil
+}
+
+// Splits "<<name args>>" into its name and args
+func parseCommand(line string) (string, string, bool) {
+	if !strings.HasPrefix(line, "<<") || !strings.HasSuffix(line, ">>") {
+		return "", "", false
+	}
+	inner := strings.TrimSpace(line[2 : len(line)-2])
+	name, args, _ := strings.Cut(inner, " ")
+	return name, strings.TrimSpace(args), true
+}
+
+// Splits a trailing "<<if expr>>" off of a line
+func cutCondition(line string) (string, expr, error) {
+	idx := strings.LastIndex(line, "<<if ")
+	if idx < 0 || !strings.HasSuffix(line, ">>") {
+		return line, nil, nil
+	}
+	cond, err := parseExpr(line[idx+len("<<if ") : len(line)-2])
+	if err != nil {
+		return "", nil, err
+	}
+	return strings.TrimSpace(line[:idx]), cond, nil
+}
+
+// Splits the trailing #tags off of a line
+func cutTags(line string) (string, []string) {
+	var tags []string
+	for {
+		line = strings.TrimRight(line, " \t")
+		idx := strings.LastIndexAny(line, " \t") + 1
+		word := line[idx:]
+		if len(word) < 2 || word[0] != '#' {
+			return line, tags
+		}
+		tags = append([]string{word[1:]}, tags...)
+		line = line[:idx]
+	}
+}
+
+// Returns true if the line is an <<elseif>>, <<else>> or <<endif>>
+func isBranch(line string) bool {
+	name, _, ok := parseCommand(line)
+	return ok && (name == "elseif" || name == "else" || name == "endif")
+}
+
+func (c *compiler) line(line bodyLine) error {
+	src, cond, err := cutCondition(line.text)
+	if err != nil {
+		return err
+	}
+	src, tags := cutTags(src)
+
+	speaker := ""
+	if name, rest, ok := strings.Cut(src, ":"); ok && name != "" && !strings.ContainsAny(name, " \t{") {
+		speaker = name
+		src = strings.TrimSpace(rest)
+	}
+	t, err := parseText(src)
+	if err != nil {
+		return err
+	}
+
+	skip := -1
+	if cond != nil {
+		skip = c.emit(instr{op: opJumpIfFalse, line: line.num, cond: cond})
+	}
+	c.emit(instr{op: opLine, line: line.num, speaker: speaker, text: t, tags: tags})
+	if skip >= 0 {
+		c.node.code[skip].target = len(c.node.code)
+	}
+	return nil
+}
+
+func (c *compiler) command(line bodyLine, name, args string) error {
+	switch name {
+	case "set", "declare":
+		variable, value, ok := strings.Cut(args, "=")
+		variable = strings.TrimSpace(variable)
+		if !ok || !strings.HasPrefix(variable, "$") || len(variable) < 2 {
+			return fmt.Errorf("expected <<%s $variable = value>>", name)
+		}
+		e, err := parseExpr(value)
+		if err != nil {
+			return err
+		}
+		if name == "set" {
+			c.emit(instr{op: opSet, line: line.num, name: variable[1:], expr: e})
+			return nil
+		}
+		val, err := e.eval(func(string) (Value, bool) { return Value{}, false })
+		if err != nil {
+			return fmt.Errorf("declare needs a constant value: %w", err)
+		}
+		c.script.Vars[variable[1:]] = val
+		return nil
+
+	case "jump":
+		if args == "" || strings.ContainsAny(args, " \t") {
+			return fmt.Errorf("expected <<jump Node>>")
+		}
+		c.emit(instr{op: opGoto, line: line.num, name: args})
+		return nil
+
+	case "stop":
+		c.emit(instr{op: opStop, line: line.num})
+		return nil
+
+	case "":
+		return fmt.Errorf("empty command")
+	}
+
+	in := instr{op: opCommand, line: line.num, name: name}
+	for _, arg := range strings.Fields(args) {
+		t, err := parseText(arg)
+		if err != nil {
+			return err
+		}
+		in.args = append(in.args, t)
+	}
+	c.emit(in)
+	return nil
+}
+
+func (c *compiler) ifBlock(minIndent int) error {
+	var ends []int
+	skip := -1
+	for {
+		line := c.lines[c.pos]
+		name, args, _ := parseCommand(line.text)
+		c.pos++
+
+		if skip >= 0 {
+			c.node.code[skip].target = len(c.node.code)
+			skip = -1
+		}
+		switch name {
+		case "if", "elseif":
+			cond, err := parseExpr(args)
+			if err != nil {
+				return fmt.Errorf("line %d: %w", line.num, err)
+			}
+			skip = c.emit(instr{op: opJumpIfFalse, line: line.num, cond: cond})
+		case "else":
+		case "endif":
+			for _, end := range ends {
+				c.node.code[end].target = len(c.node.code)
+			}
+			return nil
+		}
+
+		err := c.block(minIndent)
+		if err != nil {
+			return err
+		}
+		if c.pos >= len(c.lines) || !isBranch(c.lines[c.pos].text) {
+			return fmt.Errorf("line %d: <<%s>> is missing <<endif>>", line.num, name)
+		}
+		ends = append(ends, c.emit(instr{op: opJump, line: line.num}))
+	}
+}
+
+// Compiles a group of options, which are at the same indentation
+func (c *compiler) options(indent int) error {
+	type option struct {
+		line bodyLine
+		body []bodyLine
+	}
+
+	var group []option
+	for c.pos < len(c.lines) {
+		line := c.lines[c.pos]
+		if line.indent != indent || !strings.HasPrefix(line.text, "->") {
+			break
+		}
+		c.pos++
+		start := c.pos
+		for c.pos < len(c.lines) && c.lines[c.pos].indent > indent {
+			c.pos++
+		}
+		group = append(group, option{line, c.lines[start:c.pos]})
+	}
+
+	// Add every option, then show them. The bodies follow and each one jumps past the others when it finishes.
+	adds := make([]int, len(group))
+	for i, opt := range group {
+		src, cond, err := cutCondition(strings.TrimSpace(opt.line.text[2:]))
+		if err != nil {
+			return fmt.Errorf("line %d: %w", opt.line.num, err)
+		}
+		src, tags := cutTags(src)
+		t, err := parseText(src)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", opt.line.num, err)
+		}
+		adds[i] = c.emit(instr{op: opOption, line: opt.line.num, text: t, tags: tags, cond: cond})
+	}
+	show := c.emit(instr{op: opShowOptions, line: group[0].line.num})
+
+	var ends []int
+	for i, opt := range group {
+		c.node.code[adds[i]].target = len(c.node.code)
+
+		body := &compiler{script: c.script, node: c.node, lines: opt.body}
+		if len(opt.body) > 0 {
+			err := body.block(opt.body[0].indent)
+			if err != nil {
+				return err
+			}
+			if body.pos < len(body.lines) {
+				line := body.lines[body.pos]
+				return fmt.Errorf("line %d: unexpected %s", line.num, line.text)
+			}
+		}
+		ends = append(ends, c.emit(instr{op: opJump, line: opt.line.num}))
+	}
+	for _, end := range ends {
+		c.node.code[end].target = len(c.node.code)
+	}
+	c.node.code[show].target = len(c.node.code)
+	return nil
+}
@@ -0,0 +1,120 @@
+package dialogue
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Problem is an issue that Validate found in a script
+type Problem struct {
+	Node    string
+	Line    int
+	Message string
+}
+
+func (p Problem) Error() string {
+	return fmt.Sprintf("dialogue: %s: line %d: %s", p.Node, p.Line, p.Message)
+}
+
+// Checks a script for mistakes that parsing can't catch, and returns them in the order of the script:
+//   - Jumps to nodes that don't exist
+//   - Nodes which can't be reached. Dialogues are expected to start at nodes tagged "entry", or at the first node if no nodes are tagged
+//   - Variables which are read, but are never declared or set by the script, or declared by the registry
+//   - Commands which aren't registered
+//
+// If registry is nil, DefaultRegistry is used.
+func Validate(script *Script, registry *Registry) []Problem {
+	if registry == nil {
+		registry = DefaultRegistry
+	}
+
+	// Find every variable that is given a value
+	defined := make(map[string]bool)
+	for name := range registry.vars {
+		defined[name] = true
+	}
+	for name := range script.Vars {
+		defined[name] = true
+	}
+	for _, node := range script.Nodes {
+		for _, in := range node.code {
+			if in.op == opSet {
+				defined[in.name] = true
+			}
+		}
+	}
+
+	reachable := reachableNodes(script)
+
+	var problems []Problem
+	for _, node := range script.Nodes {
+		if !reachable[node.Title] {
+			problems = append(problems, Problem{node.Title, node.Line, "node can't be reached"})
+		}
+
+		for _, in := range node.code {
+			reported := make(map[string]bool)
+			checkVar := func(name string) {
+				if !defined[name] && !reported[name] {
+					reported[name] = true
+					problems = append(problems, Problem{node.Title, in.line, fmt.Sprintf("undefined variable $%s", name)})
+				}
+			}
+			in.text.visit(checkVar)
+			for _, arg := range in.args {
+				arg.visit(checkVar)
+			}
+			if in.cond != nil {
+				in.cond.visit(checkVar)
+			}
+			if in.expr != nil {
+				in.expr.visit(checkVar)
+			}
+
+			switch in.op {
+			case opGoto:
+				if script.Node(in.name) == nil {
+					problems = append(problems, Problem{node.Title, in.line, fmt.Sprintf("jump to unknown node %s", in.name)})
+				}
+			case opCommand:
+				if _, ok := registry.commands[in.name]; !ok {
+					problems = append(problems, Problem{node.Title, in.line, fmt.Sprintf("unregistered command %s", in.name)})
+				}
+			}
+		}
+	}
+	return problems
+}
+
+// Returns the titles of the nodes that can be reached from the entry nodes
+func reachableNodes(script *Script) map[string]bool {
+	var queue []*Node
+	for _, node := range script.Nodes {
+		if slices.Contains(node.Tags, "entry") {
+			queue = append(queue, node)
+		}
+	}
+	if len(queue) == 0 && len(script.Nodes) > 0 {
+		queue = append(queue, script.Nodes[0])
+	}
+
+	reachable := make(map[string]bool)
+	for _, node := range queue {
+		reachable[node.Title] = true
+	}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for _, in := range node.code {
+			if in.op != opGoto {
+				continue
+			}
+			next := script.Node(in.name)
+			if next != nil && !reachable[next.Title] {
+				reachable[next.Title] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+	return reachable
+}
@@ -0,0 +1,60 @@
+package dialogue
+
+import (
+	"strconv"
+)
+
+// Kind is the type of a variable's value
+type Kind uint8
+
+const (
+	KindNone Kind = iota // The value isn't set
+	KindNumber
+	KindString
+	KindBool
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindNumber:
+		return "number"
+	case KindString:
+		return "string"
+	case KindBool:
+		return "bool"
+	}
+	return "none"
+}
+
+// Value is the value of a dialogue variable. It is a plain struct so that saved dialogue state can be serialized without registering types.
+type Value struct {
+	Kind Kind
+	Num  float64
+	Str  string
+	Bool bool
+}
+
+func Number(n float64) Value {
+	return Value{Kind: KindNumber, Num: n}
+}
+
+func String(s string) Value {
+	return Value{Kind: KindString, Str: s}
+}
+
+func Bool(b bool) Value {
+	return Value{Kind: KindBool, Bool: b}
+}
+
+// Returns the value as it is written into lines
+func (v Value) String() string {
+	switch v.Kind {
+	case KindNumber:
+		return strconv.FormatFloat(v.Num, 'f', -1, 64)
+	case KindString:
+		return v.Str
+	case KindBool:
+		return strconv.FormatBool(v.Bool)
+	}
+	return ""
+}
@@ -0,0 +1,44 @@
+package ds
+
+// Args holds named arguments which were decoded from a data file (ie the args of a behavior tree node, or of a utility consideration). Values decoded from YAML and JSON have different number types, so the getters convert between them.
+type Args map[string]any
+
+// Returns the argument as a float, or def if it isn't set or isn't a number
+func (a Args) Float(name string, def float64) float64 {
+	switch v := a[name].(type) {
+	case float64:
+		return v
+	case int:
+		return float64(v)
+	}
+	return def
+}
+
+// Returns the argument as an int, or def if it isn't set or isn't a number
+func (a Args) Int(name string, def int) int {
+	switch v := a[name].(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	}
+	return def
+}
+
+// Returns the argument as a string, or def if it isn't set or isn't a string
+func (a Args) String(name string, def string) string {
+	v, ok := a[name].(string)
+	if !ok {
+		return def
+	}
+	return v
+}
+
+// Returns the argument as a bool, or def if it isn't set or isn't a bool
+func (a Args) Bool(name string, def bool) bool {
+	v, ok := a[name].(bool)
+	if !ok {
+		return def
+	}
+	return v
+}
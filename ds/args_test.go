@@ -0,0 +1,22 @@
+package ds
+
+import "testing"
+
+func TestArgs(t *testing.T) {
+	args := Args{"speed": 1.5, "count": 3, "name": "goblin", "angry": true}
+
+	compare(t, args.Float("speed", 0), 1.5)
+	compare(t, args.Float("count", 0), 3.0)
+	compare(t, args.Int("count", 0), 3)
+	compare(t, args.Int("speed", 0), 1)
+	compare(t, args.String("name", ""), "goblin")
+	compare(t, args.Bool("angry", false), true)
+
+	// Missing and mistyped arguments use the default
+	compare(t, args.Float("name", 2), 2.0)
+	compare(t, args.String("missing", "none"), "none")
+	compare(t, args.Bool("count", false), false)
+
+	var empty Args
+	compare(t, empty.Int("count", 7), 7)
+}
@@ -54,6 +54,23 @@ func (b *RingBuffer[T]) Remove() (T, bool) {
 	return ret, true
 }
 
+// Returns the element at idx, where index 0 is the oldest element in the buffer and index Len()-1 is the newest
+func (b *RingBuffer[T]) Get(idx int) T {
+	return b.buffer[b.bufferIdx(idx)]
+}
+
+// Replaces the element at idx, where index 0 is the oldest element in the buffer and index Len()-1 is the newest
+func (b *RingBuffer[T]) Set(idx int, t T) {
+	b.buffer[b.bufferIdx(idx)] = t
+}
+
+func (b *RingBuffer[T]) bufferIdx(idx int) int {
+	if idx < 0 || idx >= b.Len() {
+		panic("ringbuffer index out of range")
+	}
+	return (b.readIdx + idx) % len(b.buffer)
+}
+
 // TODO - Maybe convert this to an iterator
 func (b *RingBuffer[T]) Buffer() []T {
 	ret := make([]T, len(b.buffer))
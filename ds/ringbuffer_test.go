@@ -0,0 +1,23 @@
+package ds
+
+import "testing"
+
+func TestRingBufferGetSet(t *testing.T) {
+	b := NewRingBuffer[int](4)
+	for i := 0; i < 6; i++ {
+		b.Add(i)
+	}
+
+	// Note: The buffer holds one less than its capacity
+	compare(t, b.Len(), 3)
+	compare(t, b.Get(0), 3)
+	compare(t, b.Get(2), 5)
+
+	b.Set(1, 40)
+	compare(t, b.Get(1), 40)
+
+	v, ok := b.Remove()
+	compare(t, v, 3)
+	check(t, ok)
+	compare(t, b.Get(0), 40)
+}
@@ -0,0 +1,119 @@
+package flow
+
+import (
+	"iter"
+	"time"
+
+	"github.com/unitoftime/ecs"
+)
+
+// Events is a resource which holds a double buffered list of events of type T. Events are swapped at the start of every frame, so an event lives for two frames before it is dropped. This gives every system a chance to read the event, regardless of whether it runs before or after the system that sent it.
+// Any number of readers can read the same events, each reader tracks its own cursor.
+//
+// Systems can send events by injecting the resource: `func(dt time.Duration, events *flow.Events[Damage])`
+type Events[T any] struct {
+	older, newer           []T
+	olderStart, newerStart uint64 // The id of the first event in each buffer
+}
+
+// Sends an event
+func (e *Events[T]) Send(event T) {
+	e.newer = append(e.newer, event)
+}
+
+// Returns the number of events currently being held
+func (e *Events[T]) Len() int {
+	return len(e.older) + len(e.newer)
+}
+
+// Drops all events
+func (e *Events[T]) Clear() {
+	e.Update()
+	e.Update()
+}
+
+// Swaps the event buffers, dropping the events that were sent two updates ago. This is automatically called at the start of every frame for events that were added with AddEvent.
+func (e *Events[T]) Update() {
+	e.older, e.newer = e.newer, e.older[:0]
+	e.olderStart = e.newerStart
+	e.newerStart = e.olderStart + uint64(len(e.older))
+}
+
+// Returns a new reader which will read every event currently being held, and every event sent after this
+func (e *Events[T]) Reader() *EventReader[T] {
+	return &EventReader[T]{
+		events: e,
+		next:   e.olderStart,
+	}
+}
+
+func (e *Events[T]) count() uint64 {
+	return e.newerStart + uint64(len(e.newer))
+}
+
+func (e *Events[T]) get(id uint64) T {
+	if id < e.newerStart {
+		return e.older[id-e.olderStart]
+	}
+	return e.newer[id-e.newerStart]
+}
+
+// EventReader reads events from an Events resource. Each reader only returns the events that it hasn't read yet.
+type EventReader[T any] struct {
+	events *Events[T]
+	next   uint64 // The id of the next event to read
+}
+
+// Returns the number of events that haven't been read yet
+func (r *EventReader[T]) Len() int {
+	return int(r.events.count() - max(r.next, r.events.olderStart))
+}
+
+// Returns every event that hasn't been read yet, and marks them as read. Events that are sent while iterating will be read on the next call.
+func (r *EventReader[T]) Read() iter.Seq[T] {
+	start := max(r.next, r.events.olderStart)
+	end := r.events.count()
+	r.next = end
+
+	return func(yield func(T) bool) {
+		for id := start; id < end; id++ {
+			if !yield(r.events.get(id)) {
+				return
+			}
+		}
+	}
+}
+
+// Adds an event type to the app. The events are updated at the start of every frame.
+func AddEvent[T any](app *App) *Events[T] {
+	events := ecs.GetResource[Events[T]](app.world)
+	if events != nil {
+		return events // Already added
+	}
+
+	events = &Events[T]{}
+	ecs.PutResource(app.world, events)
+	app.schedule.first = append(app.schedule.first, func(dt time.Duration) {
+		events.Update()
+	})
+	return events
+}
+
+// Returns a new reader for events of type T. The event must already be added to the world, usually with AddEvent.
+// Readers are meant to be created once, when a system is built:
+//
+//	func DamageSystem(world *ecs.World) ecs.System {
+//		reader := flow.NewEventReader[Damage](world)
+//		return ecs.NewSystem(func(dt time.Duration) {
+//			for damage := range reader.Read() {
+//				// ...
+//			}
+//		})
+//	}
+func NewEventReader[T any](world *ecs.World) *EventReader[T] {
+	events := ecs.GetResource[Events[T]](world)
+	if events == nil {
+		panic("flow: event must be added with AddEvent before creating a reader")
+	}
+	return events.Reader()
+}
@@ -0,0 +1,106 @@
+package flow
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/unitoftime/ecs"
+)
+
+type damage struct {
+	Amount int
+}
+
+func readAll[T any](r *EventReader[T]) []T {
+	return slices.Collect(r.Read())
+}
+
+func TestEventsLifetime(t *testing.T) {
+	events := &Events[int]{}
+	reader := events.Reader()
+
+	events.Send(1)
+	events.Send(2)
+	if got := readAll(reader); !slices.Equal(got, []int{1, 2}) {
+		t.Errorf("expected [1 2], got %v", got)
+	}
+	if got := readAll(reader); len(got) != 0 {
+		t.Errorf("expected events to only be read once, got %v", got)
+	}
+
+	// A late reader still sees events from the last two updates
+	events.Update()
+	events.Send(3)
+	late := events.Reader()
+	if got := readAll(late); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf("expected [1 2 3], got %v", got)
+	}
+
+	events.Update()
+	if got := readAll(reader); !slices.Equal(got, []int{3}) {
+		t.Errorf("expected [3], got %v", got)
+	}
+
+	// Events older than two updates are dropped
+	events.Update()
+	if events.Len() != 0 {
+		t.Errorf("expected all events to be dropped, got %d", events.Len())
+	}
+	if reader.Len() != 0 {
+		t.Errorf("expected no unread events, got %d", reader.Len())
+	}
+}
+
+func TestEventsMissedByReader(t *testing.T) {
+	events := &Events[int]{}
+	reader := events.Reader()
+
+	events.Send(1)
+	events.Update()
+	events.Send(2)
+	events.Update()
+	events.Send(3)
+	events.Update()
+
+	if reader.Len() != 1 {
+		t.Errorf("expected 1 unread event, got %d", reader.Len())
+	}
+	if got := readAll(reader); !slices.Equal(got, []int{3}) {
+		t.Errorf("expected [3], got %v", got)
+	}
+}
+
+func TestEventsBetweenSystems(t *testing.T) {
+	app := NewApp()
+	AddEvent[damage](app)
+
+	received := 0
+	// The reader runs before the sender, so it receives events on the following frame
+	app.AddSystems(ecs.StageUpdate,
+		ecs.System{
+			Name: "reader",
+			Func: func() func(dt time.Duration) {
+				reader := NewEventReader[damage](app.World())
+				return func(dt time.Duration) {
+					for ev := range reader.Read() {
+						received += ev.Amount
+					}
+				}
+			}(),
+		},
+		ecs.NewSystem1(func(dt time.Duration, events *Events[damage]) {
+			events.Send(damage{1})
+		}),
+	)
+
+	for i := 0; i < 5; i++ {
+		app.Step(16 * time.Millisecond)
+	}
+	if received != 4 {
+		t.Errorf("expected 4 damage, got %d", received)
+	}
+	if ecs.GetResource[Events[damage]](app.World()).Len() != 2 {
+		t.Errorf("expected events to be cleared between frames")
+	}
+}
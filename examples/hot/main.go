@@ -29,11 +29,10 @@ func main() {
 		} // When this becomes true, it means a new plugin is loaded
 
 		// With our new plugin, we can lookup our symbol `HelloWorld`
-		sym, err := p.Lookup("HelloWorld")
+		hello, err := hot.Lookup[func()](p, "HelloWorld")
 		if err != nil {
 			panic(err)
 		}
-		hello := sym.(func())
 
 		// Then we can call our Looked up symbol
 		hello()
@@ -2,14 +2,26 @@ package plugin
 
 import (
 	"fmt"
+
+	"github.com/unitoftime/flow/hot"
 )
 
 // Note: Here you can change one function to the other while main.go is running.
 
+type State struct {
+	Calls int
+}
+
+// Exporting a Lifecycle lets the host carry this state over to the next version of the plugin
+var state = hot.NewState(State{})
+var Lifecycle hot.Lifecycle = state
+
 func HelloWorld() {
-	fmt.Println("Hello World")
+	state.Value.Calls++
+	fmt.Println("Hello World", state.Value.Calls)
 }
 
 // func HelloWorld() {
-// 	fmt.Println("Goodbye Moon")
+// 	state.Value.Calls++
+// 	fmt.Println("Goodbye Moon", state.Value.Calls)
 // }
@@ -71,11 +71,11 @@ func printStuff(dt time.Duration, query *ecs.View1[transform.Local]) {
 	// })
 }
 
-func escapeExit(dt time.Duration, scheduler *ecs.Scheduler, query *ecs.View1[render.Window]) {
+func escapeExit(dt time.Duration, app *flow.App, query *ecs.View1[render.Window]) {
 	query.MapId(func(_ ecs.Id, win *render.Window) {
 		if win.JustPressed(glitch.KeyEscape) {
 			win.Close()
-			scheduler.SetQuit(true)
+			app.Exit()
 		}
 	})
 }
@@ -192,6 +192,19 @@ func (r Rect) Intersects(r2 Rect) bool {
 		r.Max.Y >= r2.Min.Y)
 }
 
+// Returns the overlapping region of the two rects. If they don't overlap, the returned rect has zero width or height
+func (r Rect) Intersect(r2 Rect) Rect {
+	ret := R(
+		math.Max(r.Min.X, r2.Min.X),
+		math.Max(r.Min.Y, r2.Min.Y),
+		math.Min(r.Max.X, r2.Max.X),
+		math.Min(r.Max.Y, r2.Max.Y),
+	)
+	ret.Max.X = math.Max(ret.Min.X, ret.Max.X)
+	ret.Max.Y = math.Max(ret.Min.Y, ret.Max.Y)
+	return ret
+}
+
 // Layous out 'n' rectangles horizontally with specified padding between them and returns that rect
 // The returned rectangle has a min point of 0,0
 func (r Rect) LayoutHorizontal(n int, padding float64) Rect {
@@ -5,9 +5,12 @@ import (
 	"fmt"
 	"os"
 	"plugin"
+	"reflect"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/unitoftime/flow/asset/serde"
 )
 
 //--------------------------------------------------------------------------------
@@ -34,16 +37,105 @@ import (
 
 var cache map[string]*Plugin
 
+// The symbol that a plugin must export to take part in the Lifecycle contract
+const LifecycleSymbol = "Lifecycle"
+
+// Lifecycle is an optional contract that a plugin can implement to keep its state across reloads. To use it, export a variable named `Lifecycle` from your plugin.
+// When a new plugin is found:
+// 1. Save is called on the old plugin
+// 2. Restore is called on the new plugin with the saved data
+// 3. Shutdown is called on the old plugin
+// If Save or Restore return an error, the new plugin is discarded and the old plugin stays active.
+type Lifecycle interface {
+	Save() ([]byte, error)
+	Restore([]byte) error
+	Shutdown()
+}
+
+// State is a helper which implements Lifecycle by serializing a single value with serde.
+// Plugins can embed it, or export one directly: `var Lifecycle hot.Lifecycle = hot.NewState(MyState{})`
+type State[T any] struct {
+	Value T
+}
+
+func NewState[T any](val T) *State[T] {
+	return &State[T]{
+		Value: val,
+	}
+}
+
+func (s *State[T]) Save() ([]byte, error) {
+	return serde.Marshal(s.Value)
+}
+
+func (s *State[T]) Restore(dat []byte) error {
+	val, err := serde.Unmarshal[T](dat)
+	if err != nil {
+		return err
+	}
+	s.Value = val
+	return nil
+}
+
+func (s *State[T]) Shutdown() {}
+
+// This is the part of *plugin.Plugin that we use
+type symbolLookup interface {
+	Lookup(string) (plugin.Symbol, error)
+}
+
+// Finds the Lifecycle exported by the plugin. Returns nil if the plugin doesn't export one.
+func lookupLifecycle(p symbolLookup) (Lifecycle, error) {
+	sym, err := p.Lookup(LifecycleSymbol)
+	if err != nil {
+		if isSymbolNotFound(err, LifecycleSymbol) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to look up plugin symbol %s: %w", LifecycleSymbol, err)
+	}
+
+	switch lifecycle := sym.(type) {
+	case Lifecycle:
+		return lifecycle, nil
+	case *Lifecycle:
+		if *lifecycle == nil {
+			return nil, nil
+		}
+		return *lifecycle, nil
+	}
+	return nil, fmt.Errorf("plugin symbol %s has type %T, which doesn't implement hot.Lifecycle", LifecycleSymbol, sym)
+}
+
+// Returns true if the error is the plugin package's "symbol not found" error
+// Note: The plugin package doesn't give us a typed error, so we match its message: "plugin: symbol NAME not found in plugin PATH"
+func isSymbolNotFound(err error, symName string) bool {
+	return strings.Contains(err.Error(), "symbol "+symName+" not found")
+}
+
+// Opens the plugin at path. This is plugin.Open, but can be replaced in tests
+var openPlugin = func(path string) (symbolLookup, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, errors.New("plugin.Open returned nil")
+	}
+	return p, nil
+}
+
 // rm -f ../plugin/*.so && VAR=$RANDOM && echo $VAR && rm -rf ./build/* && mkdir ./build/tmp$VAR && cp reloader.go ./build/tmp$VAR && go build -buildmode=plugin -o ../plugin/tmp$VAR.so ./build/tmp$VAR
 
 type Plugin struct {
 	path      string
-	internal  *plugin.Plugin
+	internal  symbolLookup
+	lifecycle Lifecycle
 	startOnce sync.Once
-	// gen uint64
-	refresh chan struct{}
+	gen       uint64
+	refresh   chan struct{}
 
-	currentPlugin string
+	currentPlugin string // The plugin that is active
+	rejected      string // The last plugin that failed to open or swap. It isn't tried again until Retry is called
 }
 
 func NewPlugin(path string) *Plugin {
@@ -63,9 +155,10 @@ func NewPlugin(path string) *Plugin {
 	return &newPlugin
 }
 
-// func (p Plugin) Generation() uint64 {
-// 	return p.gen
-// }
+// Returns the number of times a new plugin has been swapped in
+func (p *Plugin) Generation() uint64 {
+	return p.gen
+}
 
 // func (p *Plugin) Refresh() chan struct{} {
 // 	return p.refresh
@@ -79,6 +172,26 @@ func (p *Plugin) Lookup(symName string) (any, error) {
 	return val, err
 }
 
+// Looks up a symbol in the plugin and converts it to type T. Exported variables are looked up as pointers by the plugin package, so you can request either the variable's type or a pointer to it.
+func Lookup[T any](p *Plugin, symName string) (T, error) {
+	var t T
+	sym, err := p.Lookup(symName)
+	if err != nil {
+		return t, err
+	}
+
+	switch val := sym.(type) {
+	case T:
+		return val, nil
+	case *T:
+		if val == nil {
+			return t, fmt.Errorf("plugin symbol %s in %s is a nil %T", symName, p.currentPlugin, sym)
+		}
+		return *val, nil
+	}
+	return t, fmt.Errorf("plugin symbol %s in %s has type %T, expected %v", symName, p.currentPlugin, sym, reflect.TypeFor[T]())
+}
+
 // Check to see if there is a new plugin to load
 // Returns true if there is a new one
 func (p *Plugin) Check() bool {
@@ -99,47 +212,75 @@ func (p *Plugin) Check() bool {
 		return false // Nothing new
 	}
 
-	samePlugin := nextPlugin == p.currentPlugin
+	samePlugin := nextPlugin == p.currentPlugin || nextPlugin == p.rejected
 	if samePlugin {
 		return false
 	}
-	p.currentPlugin = nextPlugin
 	fmt.Println("Found New Plugin:", nextPlugin)
 
-	// var iPlugin *plugin.Plugin
-	// func() {
-	// 	defer func() {
-	// 		if r := recover(); r != nil {
-	// 			fmt.Println("RECOVERED:", r)
-	// 		}
-	// 	}()
-	// 	time.Sleep(100 * time.Millisecond)
-	// 	// Note: I have to sleep here to ensure that all of the glitch CGO calls have completed for the frame. 100ms is arbitrary, and is unecessary if you dont make CGO calls.
-	// 	var err error
-	// 	iPlugin, err = plugin.Open(p.currentPlugin)
-	// 	if err != nil {
-	// 		fmt.Println("Error Loading Plugin:", err)
-	// 	}
-	// }()
-
 	// Note: I have to sleep here to ensure that all of the glitch CGO calls have completed for the frame. 100ms is arbitrary, and is unecessary if you dont make CGO calls.
 	time.Sleep(100 * time.Millisecond)
-	iPlugin, err := plugin.Open(p.currentPlugin)
+	iPlugin, err := openPlugin(nextPlugin)
 	if err != nil {
 		fmt.Println("Error Loading Plugin:", err)
+		p.rejected = nextPlugin
 		return false
 	}
 
-	if iPlugin == nil {
-		fmt.Println("Error Loading Plugin")
+	fmt.Println("Successfully Loaded Plugin:", nextPlugin)
+	err = p.swap(iPlugin)
+	if err != nil {
+		fmt.Println("Error Swapping Plugin:", err)
+		p.rejected = nextPlugin
 		return false
 	}
-
-	fmt.Println("Successfully Loaded Plugin:", p.currentPlugin)
-	p.internal = iPlugin
+	p.currentPlugin = nextPlugin
+	p.rejected = ""
 	return true
 }
 
+// Lets Check try the last rejected plugin again, ie after fixing whatever made its state fail to restore
+func (p *Plugin) Retry() {
+	p.rejected = ""
+}
+
+// Swaps the active plugin over to next. If the old plugin exports a Lifecycle, then its state is saved and restored into the new plugin, which must export one too. If any step fails, the previous plugin stays active.
+func (p *Plugin) swap(next symbolLookup) error {
+	nextLifecycle, err := lookupLifecycle(next)
+	if err != nil {
+		return err
+	}
+	if p.lifecycle != nil && nextLifecycle == nil {
+		// Note: The old plugin's state has nowhere to go, so this is treated the same as a failed restore
+		return fmt.Errorf("failed to restore plugin state, rolling back: new plugin doesn't export %s", LifecycleSymbol)
+	}
+
+	var state []byte
+	if p.lifecycle != nil {
+		state, err = p.lifecycle.Save()
+		if err != nil {
+			return fmt.Errorf("failed to save plugin state: %w", err)
+		}
+	}
+
+	if nextLifecycle != nil && state != nil {
+		err = nextLifecycle.Restore(state)
+		if err != nil {
+			// Note: Go plugins can't be unloaded, so rolling back just means we keep using the old one
+			return fmt.Errorf("failed to restore plugin state, rolling back: %w", err)
+		}
+	}
+
+	if p.lifecycle != nil {
+		p.lifecycle.Shutdown()
+	}
+
+	p.internal = next
+	p.lifecycle = nextLifecycle
+	p.gen++
+	return nil
+}
+
 // Old idea:
 // - Problem - can't synchronize with CGO execution which can cause SIGBUS: bus errors
 // // Starts a watcher process in the background
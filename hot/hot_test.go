@@ -0,0 +1,313 @@
+package hot
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"plugin"
+	"testing"
+	"time"
+
+	"github.com/unitoftime/ecs"
+	"github.com/unitoftime/flow/asset/serde"
+)
+
+type fakePlugin struct {
+	symbols map[string]any
+}
+
+func (p *fakePlugin) Lookup(name string) (plugin.Symbol, error) {
+	sym, ok := p.symbols[name]
+	if !ok {
+		return nil, fmt.Errorf("plugin: symbol %s not found in plugin fake", name)
+	}
+	return sym, nil
+}
+
+type counter struct {
+	Count    int
+	shutdown bool
+}
+
+type failingRestore struct {
+	State[counter]
+}
+
+func (f *failingRestore) Restore([]byte) error {
+	return errors.New("bad state")
+}
+
+func newFakePlugin(lifecycle Lifecycle) *fakePlugin {
+	return &fakePlugin{
+		symbols: map[string]any{
+			LifecycleSymbol: &lifecycle,
+		},
+	}
+}
+
+func TestSwapTransfersState(t *testing.T) {
+	p := &Plugin{}
+
+	first := NewState(counter{})
+	err := p.swap(newFakePlugin(first))
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.Value.Count = 5
+
+	second := NewState(counter{})
+	err = p.swap(newFakePlugin(second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if second.Value.Count != 5 {
+		t.Errorf("expected restored count 5, got %d", second.Value.Count)
+	}
+	if p.lifecycle != second {
+		t.Errorf("expected second plugin to be active")
+	}
+}
+
+func TestSwapRollsBackOnFailedRestore(t *testing.T) {
+	p := &Plugin{}
+
+	first := NewState(counter{Count: 3})
+	firstPlugin := newFakePlugin(first)
+	err := p.swap(firstPlugin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = p.swap(newFakePlugin(&failingRestore{}))
+	if err == nil {
+		t.Fatal("expected restore error")
+	}
+
+	if p.lifecycle != first {
+		t.Errorf("expected first plugin to still be active")
+	}
+	if p.internal != firstPlugin {
+		t.Errorf("expected first plugin symbols to still be active")
+	}
+}
+
+func TestSwapRollsBackOnMissingLifecycle(t *testing.T) {
+	p := &Plugin{}
+
+	first := NewState(counter{Count: 3})
+	firstPlugin := newFakePlugin(first)
+	err := p.swap(firstPlugin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = p.swap(&fakePlugin{})
+	if err == nil {
+		t.Fatal("expected missing lifecycle error")
+	}
+	if p.lifecycle != first || p.internal != firstPlugin {
+		t.Errorf("expected first plugin to still be active")
+	}
+}
+
+func TestSwapWithoutLifecycle(t *testing.T) {
+	p := &Plugin{}
+	err := p.swap(&fakePlugin{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.lifecycle != nil {
+		t.Errorf("expected no lifecycle")
+	}
+
+	_, err = p.Lookup("Missing")
+	if err == nil {
+		t.Errorf("expected missing symbol error")
+	}
+}
+
+func TestSwapRejectsWrongType(t *testing.T) {
+	p := &Plugin{}
+	bad := &fakePlugin{
+		symbols: map[string]any{
+			LifecycleSymbol: new(int),
+		},
+	}
+	err := p.swap(bad)
+	if err == nil {
+		t.Fatal("expected type error")
+	}
+}
+
+func TestStateRoundTrip(t *testing.T) {
+	dat, err := serde.Marshal(counter{Count: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewState(counter{})
+	err = s.Restore(dat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Value.Count != 7 {
+		t.Errorf("expected 7, got %d", s.Value.Count)
+	}
+}
+
+func TestLookupTyped(t *testing.T) {
+	p := &Plugin{}
+	_, err := Lookup[func()](p, "Hello")
+	if err == nil {
+		t.Fatal("expected not loaded error")
+	}
+
+	count := 5
+	err = p.swap(&fakePlugin{
+		symbols: map[string]any{
+			"Hello": func() {},
+			"Count": &count,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Lookup[func()](p, "Hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	val, err := Lookup[int](p, "Count")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val != 5 {
+		t.Errorf("expected 5, got %d", val)
+	}
+
+	_, err = Lookup[string](p, "Count")
+	if err == nil {
+		t.Fatal("expected type mismatch error")
+	}
+}
+
+func TestSystemSwap(t *testing.T) {
+	world := ecs.NewWorld()
+	p := &Plugin{}
+
+	called := ""
+	newSys := func(name string) SystemFunc {
+		return func(world *ecs.World) ecs.System {
+			return ecs.NewSystem(func(dt time.Duration) {
+				called = name
+			})
+		}
+	}
+
+	sys := System(p, "Sys", newSys("fallback")).Build(world)
+	sys.Run(0)
+	if called != "fallback" {
+		t.Errorf("expected fallback system, got %s", called)
+	}
+
+	err := p.swap(&fakePlugin{
+		symbols: map[string]any{
+			"Sys": newSys("first"),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sys.Run(0)
+	if called != "first" {
+		t.Errorf("expected first system, got %s", called)
+	}
+
+	// A plugin with a mismatched symbol keeps the last good system
+	err = p.swap(&fakePlugin{
+		symbols: map[string]any{
+			"Sys": func() {},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	called = ""
+	sys.Run(0)
+	if called != "first" {
+		t.Errorf("expected first system to be kept, got %s", called)
+	}
+}
+
+type brokenPlugin struct{}
+
+func (brokenPlugin) Lookup(name string) (plugin.Symbol, error) {
+	return nil, errors.New("plugin: broken")
+}
+
+func TestLookupLifecycleErrors(t *testing.T) {
+	lifecycle, err := lookupLifecycle(&fakePlugin{})
+	if err != nil || lifecycle != nil {
+		t.Errorf("expected a missing lifecycle to be allowed, got %v %v", lifecycle, err)
+	}
+
+	_, err = lookupLifecycle(brokenPlugin{})
+	if err == nil {
+		t.Errorf("expected lookup error to be returned")
+	}
+}
+
+func TestCheckRetriesRejectedPlugin(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "next.so"), nil, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nextPath := dir + "/next.so"
+
+	opened := make(map[string]*fakePlugin)
+	defer func(open func(string) (symbolLookup, error)) { openPlugin = open }(openPlugin)
+	openPlugin = func(path string) (symbolLookup, error) {
+		fake, ok := opened[path]
+		if !ok {
+			return nil, fmt.Errorf("no plugin at %s", path)
+		}
+		return fake, nil
+	}
+
+	p := &Plugin{path: dir + "/"}
+	first := NewState(counter{Count: 3})
+	err = p.swap(newFakePlugin(first))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The new plugin can't restore the state, so the first plugin stays active
+	opened[nextPath] = newFakePlugin(&failingRestore{})
+	if p.Check() {
+		t.Fatal("expected failed restore to be rejected")
+	}
+	if p.lifecycle != first || p.currentPlugin != "" {
+		t.Errorf("expected first plugin to still be current, got %q", p.currentPlugin)
+	}
+
+	// The rejected plugin isn't tried again on every check
+	second := NewState(counter{})
+	opened[nextPath] = newFakePlugin(second)
+	if p.Check() {
+		t.Fatal("expected rejected plugin to be skipped")
+	}
+
+	p.Retry()
+	if !p.Check() {
+		t.Fatal("expected retried plugin to be swapped in")
+	}
+	if p.lifecycle != second || p.currentPlugin != nextPath {
+		t.Errorf("expected second plugin to be current, got %q", p.currentPlugin)
+	}
+	if second.Value.Count != 3 {
+		t.Errorf("expected restored count 3, got %d", second.Value.Count)
+	}
+}
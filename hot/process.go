@@ -0,0 +1,455 @@
+package hot
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/unitoftime/ecs"
+	"github.com/unitoftime/flow"
+	"github.com/unitoftime/flow/asset/serde"
+)
+
+// Process is an alternative to Plugin which runs your hot reloadable logic in a child process. The host talks to the child over the child's stdin and stdout with serde-encoded messages. This works on every platform and doesn't require the plugin ABI to match, at the cost of a serialization round trip per call.
+// Note: Because stdout is used for messages, the child process must write its logs to stderr. The child's stderr is forwarded to the host's stderr.
+//
+// Host side:
+//
+//	p := hot.NewProcess("./build/logic")
+//	p.Check()
+//	resp, err := hot.Call[Req, Resp](p, "Update", req)
+//
+// Child side:
+//
+//	server := hot.NewServer()
+//	hot.Handle(server, "Update", update)
+//	server.ServeStdio()
+type Process struct {
+	path    string
+	args    []string
+	modTime time.Time
+	size    int64
+	current *child
+	gen     uint64
+}
+
+// Creates a process which will run the binary at path with the provided args
+func NewProcess(path string, args ...string) *Process {
+	return &Process{
+		path: path,
+		args: args,
+	}
+}
+
+// Returns the number of times a new child process has been swapped in
+func (p *Process) Generation() uint64 {
+	return p.gen
+}
+
+// Check to see if the binary has changed, or if the child has exited. If so, a new child is started. A child which exited can't save its state, so its replacement starts fresh.
+// Returns true if a new child process was swapped in
+func (p *Process) Check() bool {
+	if p.current != nil && p.current.dead() {
+		fmt.Println("Process Exited:", p.path)
+		p.Close()
+	}
+
+	info, err := os.Stat(p.path)
+	if err != nil {
+		fmt.Println("Error Checking Process:", err)
+		return false
+	}
+
+	changed := !info.ModTime().Equal(p.modTime) || info.Size() != p.size
+	if !changed && p.current != nil {
+		return false // Nothing new
+	}
+	p.modTime = info.ModTime()
+	p.size = info.Size()
+	fmt.Println("Found New Process:", p.path)
+
+	next, err := startChild(p.path, p.args...)
+	if err != nil {
+		fmt.Println("Error Starting Process:", err)
+		return false
+	}
+
+	err = p.swap(next)
+	if err != nil {
+		fmt.Println("Error Swapping Process:", err)
+		next.close()
+		return false
+	}
+
+	fmt.Println("Successfully Started Process:", p.path)
+	return true
+}
+
+// Swaps the active child over to next. The old child's state is saved and restored into the new child. If any step fails, then the old child stays active.
+func (p *Process) swap(next *child) error {
+	if p.current != nil {
+		state, err := p.current.call(methodSave, nil)
+		if err != nil {
+			return fmt.Errorf("failed to save process state: %w", err)
+		}
+
+		if state != nil {
+			_, err = next.call(methodRestore, state)
+			if err != nil {
+				return fmt.Errorf("failed to restore process state, rolling back: %w", err)
+			}
+		}
+
+		p.current.close()
+	}
+
+	p.current = next
+	p.gen++
+	return nil
+}
+
+// Shuts down the current child process
+func (p *Process) Close() {
+	if p.current == nil {
+		return
+	}
+	p.current.close()
+	p.current = nil
+}
+
+// Initialize lets a hot process be added to a flow.App. It adds a system to the start of the frame which checks for new builds of the child binary.
+func (p *Process) Initialize(world *ecs.World) {
+	app := flow.MustApp(world)
+
+	app.AddSystems(ecs.StagePreFixedUpdate, ecs.System{
+		Name: "hot.CheckProcessSystem",
+		Func: func(dt time.Duration) {
+			p.Check()
+		},
+	})
+}
+
+// Lets several hot processes be added to the same flow.App
+func (p *Process) PluginName() string {
+	return "hot.Process:" + p.path
+}
+
+// Calls method on the child process with req, and returns the child's response
+func Call[Req, Resp any](p *Process, method string, req Req) (Resp, error) {
+	var resp Resp
+	if p.current == nil {
+		return resp, errors.New("process not yet started")
+	}
+
+	reqDat, err := serde.Marshal(req)
+	if err != nil {
+		return resp, err
+	}
+
+	respDat, err := p.current.call(method, reqDat)
+	if err != nil {
+		var remoteErr RemoteError
+		if !errors.As(err, &remoteErr) {
+			// The connection is broken, so drop the child. The next Check will start a new one.
+			p.Close()
+		}
+		return resp, err
+	}
+
+	return serde.Unmarshal[Resp](respDat)
+}
+
+//--------------------------------------------------------------------------------
+// - Child
+//--------------------------------------------------------------------------------
+
+type child struct {
+	cmd    *exec.Cmd
+	conn   *conn
+	in     io.Closer
+	exited chan struct{} // Closed once the child process has exited
+}
+
+func startChild(path string, args ...string) (*child, error) {
+	cmd := exec.Command(path, args...)
+	cmd.Stderr = os.Stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		return nil, err
+	}
+
+	exited := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(exited)
+	}()
+
+	return &child{
+		cmd:    cmd,
+		conn:   newConn(stdout, stdin),
+		in:     stdin,
+		exited: exited,
+	}, nil
+}
+
+func (c *child) call(method string, data []byte) ([]byte, error) {
+	return c.conn.call(method, data)
+}
+
+// The time that a child has to shutdown before it is killed
+const shutdownTimeout = 1 * time.Second
+
+// Asks the child to shutdown, and kills it if it doesn't exit in time
+func (c *child) close() {
+	// Note: The call can block forever if the child is stuck, so the child is killed if it doesn't answer in time. Killing the child unblocks the call.
+	shutdown := make(chan struct{})
+	go func() {
+		c.conn.call(methodShutdown, nil)
+		close(shutdown)
+	}()
+	select {
+	case <-shutdown:
+	case <-c.exited:
+	case <-time.After(shutdownTimeout):
+		c.kill()
+	}
+
+	if c.in != nil {
+		c.in.Close()
+	}
+	if c.cmd == nil {
+		return
+	}
+
+	select {
+	case <-c.exited:
+	case <-time.After(shutdownTimeout):
+		c.kill()
+		<-c.exited
+	}
+}
+
+func (c *child) kill() {
+	if c.cmd == nil {
+		return
+	}
+	c.cmd.Process.Kill()
+}
+
+// Returns true if the child process has exited
+func (c *child) dead() bool {
+	select {
+	case <-c.exited:
+		return true
+	default:
+		return false
+	}
+}
+
+//--------------------------------------------------------------------------------
+// - Server
+//--------------------------------------------------------------------------------
+
+// Built in methods used to implement the Lifecycle contract across processes
+const (
+	methodSave     = "hot.Save"
+	methodRestore  = "hot.Restore"
+	methodShutdown = "hot.Shutdown"
+)
+
+type handlerFunc func([]byte) ([]byte, error)
+
+// Server runs inside of the child process and dispatches the host's calls to registered handlers
+type Server struct {
+	handlers map[string]handlerFunc
+
+	// Optional: If set, the server's state will be carried over when the host swaps in a new child
+	Lifecycle Lifecycle
+}
+
+func NewServer() *Server {
+	return &Server{
+		handlers: make(map[string]handlerFunc),
+	}
+}
+
+// Registers handler to be called whenever the host calls method
+func Handle[Req, Resp any](s *Server, method string, handler func(Req) (Resp, error)) {
+	s.handlers[method] = func(dat []byte) ([]byte, error) {
+		req, err := serde.Unmarshal[Req](dat)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := handler(req)
+		if err != nil {
+			return nil, err
+		}
+		return serde.Marshal(resp)
+	}
+}
+
+// Serves requests over stdin and stdout. Blocks until the host shuts down the child.
+func (s *Server) ServeStdio() error {
+	return s.Serve(os.Stdin, os.Stdout)
+}
+
+// Serves requests read from r and writes responses to w. Blocks until the host shuts down the server, or r is closed.
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	reader := bufio.NewReader(r)
+	for {
+		req, err := readMessage(reader)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		resp := s.handle(req)
+		err = writeMessage(w, resp)
+		if err != nil {
+			return err
+		}
+
+		if req.Method == methodShutdown {
+			return nil
+		}
+	}
+}
+
+func (s *Server) handle(req message) message {
+	resp := message{
+		Method: req.Method,
+	}
+
+	var data []byte
+	var err error
+	switch req.Method {
+	case methodSave:
+		if s.Lifecycle != nil {
+			data, err = s.Lifecycle.Save()
+		}
+	case methodRestore:
+		if s.Lifecycle != nil {
+			err = s.Lifecycle.Restore(req.Data)
+		}
+	case methodShutdown:
+		if s.Lifecycle != nil {
+			s.Lifecycle.Shutdown()
+		}
+	default:
+		handler, ok := s.handlers[req.Method]
+		if !ok {
+			err = fmt.Errorf("unknown method: %s", req.Method)
+		} else {
+			data, err = handler(req.Data)
+		}
+	}
+
+	resp.Data = data
+	if err != nil {
+		resp.Err = err.Error()
+	}
+	return resp
+}
+
+//--------------------------------------------------------------------------------
+// - Wire Format
+//--------------------------------------------------------------------------------
+
+// RemoteError is an error that was returned by a handler in the child process
+type RemoteError struct {
+	Method string
+	Msg    string
+}
+
+func (e RemoteError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Method, e.Msg)
+}
+
+type message struct {
+	Method string
+	Data   []byte
+	Err    string
+}
+
+type conn struct {
+	mu     sync.Mutex
+	reader *bufio.Reader
+	writer io.Writer
+}
+
+func newConn(r io.Reader, w io.Writer) *conn {
+	return &conn{
+		reader: bufio.NewReader(r),
+		writer: w,
+	}
+}
+
+func (c *conn) call(method string, data []byte) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	err := writeMessage(c.writer, message{
+		Method: method,
+		Data:   data,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := readMessage(c.reader)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Err != "" {
+		return nil, RemoteError{method, resp.Err}
+	}
+	return resp.Data, nil
+}
+
+// Messages are framed as a 4 byte big endian length followed by the serde-encoded message
+func writeMessage(w io.Writer, msg message) error {
+	dat, err := serde.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, 4+len(dat))
+	binary.BigEndian.PutUint32(buf, uint32(len(dat)))
+	copy(buf[4:], dat)
+	_, err = w.Write(buf)
+	return err
+}
+
+func readMessage(r io.Reader) (message, error) {
+	var header [4]byte
+	_, err := io.ReadFull(r, header[:])
+	if err != nil {
+		return message{}, err
+	}
+
+	dat := make([]byte, binary.BigEndian.Uint32(header[:]))
+	_, err = io.ReadFull(r, dat)
+	if err != nil {
+		return message{}, err
+	}
+
+	return serde.Unmarshal[message](dat)
+}
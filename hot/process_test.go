@@ -0,0 +1,271 @@
+package hot
+
+import (
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Starts a server in a goroutine and returns a child connected to it
+func newPipeChild(t *testing.T, server *Server) *child {
+	reqReader, reqWriter := io.Pipe()
+	respReader, respWriter := io.Pipe()
+	go func() {
+		err := server.Serve(reqReader, respWriter)
+		if err != nil {
+			t.Error(err)
+		}
+		respWriter.Close()
+	}()
+
+	return &child{
+		conn: newConn(respReader, reqWriter),
+		in:   reqWriter,
+	}
+}
+
+func newCounterServer(state *State[counter]) *Server {
+	server := NewServer()
+	server.Lifecycle = state
+	Handle(server, "Add", func(n int) (int, error) {
+		if n < 0 {
+			return 0, errors.New("negative")
+		}
+		state.Value.Count += n
+		return state.Value.Count, nil
+	})
+	return server
+}
+
+func TestProcessCall(t *testing.T) {
+	p := NewProcess("unused")
+	_, err := Call[int, int](p, "Add", 1)
+	if err == nil {
+		t.Fatal("expected not started error")
+	}
+
+	err = p.swap(newPipeChild(t, newCounterServer(NewState(counter{}))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	val, err := Call[int, int](p, "Add", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	val, err = Call[int, int](p, "Add", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val != 5 {
+		t.Errorf("expected 5, got %d", val)
+	}
+
+	_, err = Call[int, int](p, "Add", -1)
+	var remoteErr RemoteError
+	if !errors.As(err, &remoteErr) {
+		t.Fatalf("expected remote error, got %v", err)
+	}
+	if p.current == nil {
+		t.Errorf("remote errors should not drop the child")
+	}
+
+	_, err = Call[int, int](p, "Missing", 1)
+	if !errors.As(err, &remoteErr) {
+		t.Fatalf("expected remote error, got %v", err)
+	}
+}
+
+func TestProcessSwapTransfersState(t *testing.T) {
+	p := NewProcess("unused")
+
+	err := p.swap(newPipeChild(t, newCounterServer(NewState(counter{}))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Call[int, int](p, "Add", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second := NewState(counter{})
+	err = p.swap(newPipeChild(t, newCounterServer(second)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	val, err := Call[int, int](p, "Add", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val != 5 {
+		t.Errorf("expected state to carry over to 5, got %d", val)
+	}
+	if p.Generation() != 2 {
+		t.Errorf("expected generation 2, got %d", p.Generation())
+	}
+}
+
+func TestProcessSwapRollsBack(t *testing.T) {
+	p := NewProcess("unused")
+
+	err := p.swap(newPipeChild(t, newCounterServer(NewState(counter{Count: 2}))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	bad := NewServer()
+	bad.Lifecycle = &failingRestore{}
+	next := newPipeChild(t, bad)
+	err = p.swap(next)
+	if err == nil {
+		t.Fatal("expected restore error")
+	}
+	next.close()
+
+	val, err := Call[int, int](p, "Add", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val != 3 {
+		t.Errorf("expected old process to still be active with 3, got %d", val)
+	}
+}
+
+// Builds testdata/counter into path, with version baked in
+func buildCounter(t *testing.T, path string, version string) {
+	t.Helper()
+	buildChild(t, path, "./testdata/counter", "-ldflags", "-X main.version="+version)
+}
+
+// Builds the child package into path, or skips the test if it can't be built here
+func buildChild(t *testing.T, path string, pkg string, flags ...string) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("builds a child binary")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go toolchain not found")
+	}
+	args := append([]string{"build", "-o", path}, flags...)
+	cmd := exec.Command("go", append(args, pkg)...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("failed to build child: %v\n%s", err, out)
+	}
+}
+
+func TestProcessRebuild(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "counter")
+	buildCounter(t, path, "1")
+
+	p := NewProcess(path)
+	defer p.Close()
+	if !p.Check() {
+		t.Fatal("expected child to start")
+	}
+	_, err := Call[int, int](p, "Add", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Check() {
+		t.Errorf("expected unchanged binary to keep the same child")
+	}
+
+	buildCounter(t, path, "2")
+	// Note: Make sure the new build looks changed, even if the filesystem's timestamps are coarse
+	later := time.Now().Add(time.Second)
+	err = os.Chtimes(path, later, later)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.Check() {
+		t.Fatal("expected rebuilt child to be swapped in")
+	}
+
+	version, err := Call[struct{}, string](p, "Version", struct{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != "2" {
+		t.Errorf("expected rebuilt child, got version %s", version)
+	}
+	val, err := Call[int, int](p, "Add", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val != 5 {
+		t.Errorf("expected state to carry over to 5, got %d", val)
+	}
+	if p.Generation() != 2 {
+		t.Errorf("expected generation 2, got %d", p.Generation())
+	}
+}
+
+// A child which never answers the shutdown call must still be closed
+func TestChildCloseTimeout(t *testing.T) {
+	reqReader, reqWriter := io.Pipe()
+	respReader, _ := io.Pipe()
+	go io.Copy(io.Discard, reqReader)
+
+	c := &child{
+		conn: newConn(respReader, reqWriter),
+		in:   reqWriter,
+	}
+	start := time.Now()
+	c.close()
+	if time.Since(start) > 2*shutdownTimeout {
+		t.Errorf("expected close to give up after the timeout")
+	}
+}
+
+// A real child which ignores the shutdown call and its closed stdin must be killed
+func TestChildCloseKillsStuckChild(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stuck")
+	buildChild(t, path, "./testdata/stuck")
+
+	c, err := startChild(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	c.close()
+	if time.Since(start) > 3*shutdownTimeout {
+		t.Errorf("expected close to kill the child after the timeout, took %v", time.Since(start))
+	}
+	if !c.dead() {
+		t.Errorf("expected child to have exited")
+	}
+}
+
+func TestProcessRestartsDeadChild(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "counter")
+	buildCounter(t, path, "1")
+
+	p := NewProcess(path)
+	defer p.Close()
+	if !p.Check() {
+		t.Fatal("expected child to start")
+	}
+	old := p.current
+	old.kill()
+	<-old.exited
+
+	if !p.Check() {
+		t.Fatal("expected a new child to replace the dead one")
+	}
+	val, err := Call[int, int](p, "Add", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val != 1 || p.Generation() != 2 {
+		t.Errorf("expected a fresh child, got count %d generation %d", val, p.Generation())
+	}
+}
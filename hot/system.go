@@ -0,0 +1,76 @@
+package hot
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/unitoftime/ecs"
+	"github.com/unitoftime/flow"
+)
+
+// The type of symbol that a plugin must export to back a hot system. This matches the `func(world *ecs.World) ecs.System` system constructors used throughout flow.
+type SystemFunc = func(world *ecs.World) ecs.System
+
+// Initialize lets a hot plugin be added to a flow.App. It adds a system to the start of the frame which checks for new plugin builds, so that every hot system swaps to the new build before any of them run.
+func (p *Plugin) Initialize(world *ecs.World) {
+	app := flow.MustApp(world)
+
+	app.AddSystems(ecs.StagePreFixedUpdate, ecs.System{
+		Name: "hot.CheckPluginSystem",
+		Func: func(dt time.Duration) {
+			p.Check()
+		},
+	})
+}
+
+// Lets several hot plugins be added to the same flow.App
+func (p *Plugin) PluginName() string {
+	return "hot.Plugin:" + p.path
+}
+
+type system struct {
+	plugin   *Plugin
+	symName  string
+	fallback SystemFunc
+}
+
+// Returns a system builder which is backed by the plugin symbol symName. Whenever the plugin loads a new build, the symbol is looked up again and the system is rebuilt before its next run. If the plugin hasn't loaded yet, or the symbol can't be found, then the fallback is used (fallback may be nil to skip the system until the plugin loads).
+//
+//	app.AddPlugin(p)
+//	app.AddSystems(ecs.StageFixedUpdate, hot.System(p, "MovementSystem", game.MovementSystem))
+func System(p *Plugin, symName string, fallback SystemFunc) ecs.SystemBuilder {
+	return system{
+		plugin:   p,
+		symName:  symName,
+		fallback: fallback,
+	}
+}
+
+func (s system) Build(world *ecs.World) ecs.System {
+	var current ecs.System
+	if s.fallback != nil {
+		current = s.fallback(world)
+	}
+
+	gen := uint64(0)
+	return ecs.System{
+		Name: "hot." + s.symName,
+		Func: func(dt time.Duration) {
+			if gen != s.plugin.Generation() {
+				gen = s.plugin.Generation()
+
+				sysFunc, err := Lookup[SystemFunc](s.plugin, s.symName)
+				if err != nil {
+					fmt.Println("Error Swapping System:", err)
+				} else {
+					current = sysFunc(world)
+				}
+			}
+
+			if current.Func == nil {
+				return
+			}
+			current.Run(dt)
+		},
+	}
+}
@@ -0,0 +1,36 @@
+// A tiny child process used by the hot process tests. It keeps a count which is carried over when the host swaps in a new build.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/unitoftime/flow/hot"
+)
+
+// Set with -ldflags so that each build is a different binary
+var version = "1"
+
+type counter struct {
+	Count int
+}
+
+func main() {
+	state := hot.NewState(counter{})
+
+	server := hot.NewServer()
+	server.Lifecycle = state
+	hot.Handle(server, "Add", func(n int) (int, error) {
+		state.Value.Count += n
+		return state.Value.Count, nil
+	})
+	hot.Handle(server, "Version", func(struct{}) (string, error) {
+		return version, nil
+	})
+
+	err := server.ServeStdio()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
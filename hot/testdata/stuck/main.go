@@ -0,0 +1,15 @@
+// A child process used by the hot process tests. It reads the host's messages but never answers them, not even the shutdown call, and it keeps running after its stdin is closed.
+package main
+
+import (
+	"io"
+	"os"
+	"time"
+)
+
+func main() {
+	io.Copy(io.Discard, os.Stdin)
+	for {
+		time.Sleep(time.Hour)
+	}
+}
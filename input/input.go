@@ -0,0 +1,146 @@
+package input
+
+import (
+	"fmt"
+	"math"
+)
+
+// The device type of a control
+type Device uint8
+
+const (
+	DeviceKey           Device = iota // A keyboard key or mouse button
+	DeviceGamepadButton               // A gamepad button
+	DeviceGamepadAxis                 // A gamepad axis, such as a stick or trigger
+)
+
+// Control identifies a physical input. Codes are the window library's codes for the device type (for example glitch.Key values for keys and mouse buttons).
+type Control struct {
+	Device Device
+	Code   int
+}
+
+func Key(code int) Control {
+	return Control{DeviceKey, code}
+}
+
+func GamepadButton(code int) Control {
+	return Control{DeviceGamepadButton, code}
+}
+
+func GamepadAxis(code int) Control {
+	return Control{DeviceGamepadAxis, code}
+}
+
+func (c Control) String() string {
+	switch c.Device {
+	case DeviceKey:
+		return fmt.Sprintf("key:%d", c.Code)
+	case DeviceGamepadButton:
+		return fmt.Sprintf("button:%d", c.Code)
+	case DeviceGamepadAxis:
+		return fmt.Sprintf("axis:%d", c.Code)
+	}
+	return fmt.Sprintf("unknown:%d:%d", c.Device, c.Code)
+}
+
+// Source reads the current state of the physical controls. Sources are sampled once per Map.Update. Tests can use a Synthetic source to drive a Map.
+type Source interface {
+	// Returns the value of the control. Buttons and keys are 0 or 1. Axes are between -1 and 1 (or 0 and 1 for triggers).
+	Value(c Control) float64
+}
+
+// Synthetic is a Source whose values are set directly. It is useful for tests, replays and bots.
+type Synthetic struct {
+	values map[Control]float64
+}
+
+func NewSynthetic() *Synthetic {
+	return &Synthetic{
+		values: make(map[Control]float64),
+	}
+}
+
+func (s *Synthetic) Value(c Control) float64 {
+	return s.values[c]
+}
+
+// Sets the value of a control
+func (s *Synthetic) Set(c Control, value float64) {
+	if value == 0 {
+		delete(s.values, c)
+		return
+	}
+	s.values[c] = value
+}
+
+// Presses a key or button
+func (s *Synthetic) Press(c Control) {
+	s.Set(c, 1)
+}
+
+// Releases a key or button
+func (s *Synthetic) Release(c Control) {
+	s.Set(c, 0)
+}
+
+// Releases every control
+func (s *Synthetic) Clear() {
+	clear(s.values)
+}
+
+// Binding binds an action to a control. The binding can be a chord: it only activates while every one of its Modifiers is also held.
+type Binding struct {
+	Control   Control
+	Modifiers []Control `json:",omitempty"`
+	Scale     float64   `json:",omitempty"` // Multiplies the control's value, for example -1 to bind a key to the negative direction of an axis. Zero is treated as 1.
+}
+
+// Binds an action to a control, with optional chord modifiers
+func Bind(c Control, modifiers ...Control) Binding {
+	return Binding{
+		Control:   c,
+		Modifiers: modifiers,
+	}
+}
+
+// Returns a copy of the binding with the scale set
+func (b Binding) Scaled(scale float64) Binding {
+	b.Scale = scale
+	return b
+}
+
+// Returns the scaled value of the binding, or 0 if any of the modifiers aren't held. The deadzone is applied to the control's value before it is scaled.
+func (b Binding) value(source Source, deadzone float64) float64 {
+	for _, m := range b.Modifiers {
+		if math.Abs(source.Value(m)) < ButtonThreshold {
+			return 0
+		}
+	}
+
+	scale := b.Scale
+	if scale == 0 {
+		scale = 1
+	}
+	return applyDeadzone(source.Value(b.Control), deadzone) * scale
+}
+
+// The number of modifiers that must be held, used to give more specific chords priority
+func (b Binding) specificity() int {
+	return len(b.Modifiers)
+}
+
+// Values below the deadzone are dropped, and the rest are rescaled so that the output still starts at 0 and ends at 1
+func applyDeadzone(v, deadzone float64) float64 {
+	if deadzone <= 0 {
+		return v
+	}
+	if deadzone >= 1 {
+		return 0
+	}
+	abs := math.Abs(v)
+	if abs < deadzone {
+		return 0
+	}
+	return math.Copysign((abs-deadzone)/(1-deadzone), v)
+}
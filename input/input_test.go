@@ -0,0 +1,150 @@
+package input
+
+import (
+	"math"
+	"testing"
+)
+
+const (
+	keyA = iota + 1
+	keyD
+	keyS
+	keyCtrl
+	keySpace
+)
+
+const (
+	axisLeftX = iota
+	axisTrigger
+)
+
+func newTestMap() (*Map, *Synthetic) {
+	source := NewSynthetic()
+	m := NewMap(source)
+	m.AddButton("jump", Bind(Key(keySpace)), Bind(GamepadButton(0)))
+	m.AddButton("fire", Bind(GamepadAxis(axisTrigger)))
+	m.AddButton("save", Bind(Key(keyS), Key(keyCtrl)))
+	m.AddButton("down", Bind(Key(keyS)))
+	m.AddAxis("move.x", 0.2,
+		Bind(Key(keyA)).Scaled(-1),
+		Bind(Key(keyD)),
+		Bind(GamepadAxis(axisLeftX)),
+	)
+	return m, source
+}
+
+func TestButtonStates(t *testing.T) {
+	m, source := newTestMap()
+
+	source.Press(Key(keySpace))
+	m.Update()
+	if !m.Pressed("jump") || !m.Held("jump") || m.Released("jump") {
+		t.Errorf("expected jump to be pressed")
+	}
+	m.Update()
+	if m.Pressed("jump") || !m.Held("jump") || m.HeldFrames("jump") != 2 {
+		t.Errorf("expected jump to be held for two frames")
+	}
+
+	// Releasing one of two held bindings keeps the action held
+	source.Press(GamepadButton(0))
+	source.Release(Key(keySpace))
+	m.Update()
+	if !m.Held("jump") || m.Pressed("jump") {
+		t.Errorf("expected jump to stay held")
+	}
+
+	source.Clear()
+	m.Update()
+	if !m.Released("jump") || m.Held("jump") || m.HeldFrames("jump") != 0 {
+		t.Errorf("expected jump to be released")
+	}
+
+	// Analog controls bound to buttons use the threshold
+	source.Set(GamepadAxis(axisTrigger), 0.3)
+	m.Update()
+	if m.Held("fire") {
+		t.Errorf("expected a light trigger pull to not fire")
+	}
+	source.Set(GamepadAxis(axisTrigger), 0.8)
+	m.Update()
+	if !m.Pressed("fire") {
+		t.Errorf("expected a full trigger pull to fire")
+	}
+}
+
+func TestChords(t *testing.T) {
+	m, source := newTestMap()
+
+	source.Press(Key(keyS))
+	m.Update()
+	if !m.Held("down") || m.Held("save") {
+		t.Errorf("expected only down to be held")
+	}
+
+	source.Press(Key(keyCtrl))
+	m.Update()
+	if !m.Pressed("save") || m.Held("down") {
+		t.Errorf("expected the chord to suppress the plain binding")
+	}
+}
+
+func TestAxis(t *testing.T) {
+	m, source := newTestMap()
+
+	tests := []struct {
+		name     string
+		set      func()
+		expected float64
+	}{
+		{"left key", func() { source.Press(Key(keyA)) }, -1},
+		{"both keys cancel", func() { source.Press(Key(keyA)); source.Press(Key(keyD)) }, 0},
+		{"stick in deadzone", func() { source.Set(GamepadAxis(axisLeftX), 0.1) }, 0},
+		{"stick rescaled", func() { source.Set(GamepadAxis(axisLeftX), 0.6) }, 0.5},
+		{"clamped", func() { source.Press(Key(keyD)); source.Set(GamepadAxis(axisLeftX), 1) }, 1},
+	}
+	for _, test := range tests {
+		source.Clear()
+		test.set()
+		m.Update()
+		if math.Abs(m.Value("move.x")-test.expected) > 1e-9 {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, m.Value("move.x"))
+		}
+	}
+}
+
+func TestRebind(t *testing.T) {
+	m, source := newTestMap()
+
+	err := m.Bind("jump", Bind(Key(keyA)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Bind("nope") == nil {
+		t.Errorf("expected error for unknown action")
+	}
+	if bound := m.BoundTo(Key(keyA)); len(bound) != 2 || bound[0] != "jump" || bound[1] != "move.x" {
+		t.Errorf("expected conflicting actions, got %v", bound)
+	}
+
+	source.Press(Key(keySpace))
+	m.Update()
+	if m.Held("jump") {
+		t.Errorf("expected old binding to be removed")
+	}
+
+	overrides := m.Overrides()
+	if len(overrides) != 1 || len(overrides["jump"]) != 1 {
+		t.Errorf("expected only the rebound action, got %v", overrides)
+	}
+
+	m2, _ := newTestMap()
+	m2.ApplyOverrides(overrides)
+	if b := m2.Bindings("jump"); len(b) != 1 || b[0].Control != Key(keyA) {
+		t.Errorf("expected overrides to apply, got %v", b)
+	}
+	m2.ResetBindings()
+	if len(m2.Overrides()) != 0 {
+		t.Errorf("expected reset to restore the defaults")
+	}
+}
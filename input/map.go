@@ -0,0 +1,253 @@
+package input
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/unitoftime/flow/storage"
+)
+
+// The absolute value at which an analog control counts as held when it is bound to a button action
+const ButtonThreshold = 0.5
+
+// The type of an action
+type Kind uint8
+
+const (
+	Button Kind = iota // Held or not held
+	Axis               // A value between -1 and 1
+)
+
+// Action is a named action, such as "jump" or "move.x", and the controls that are bound to it
+type Action struct {
+	Name     string
+	Kind     Kind
+	Deadzone float64 // Analog values below this are treated as 0
+
+	bindings []Binding
+	defaults []Binding
+
+	value      float64
+	held, was  bool
+	heldFrames int
+}
+
+// Map maps named actions to controls, and tracks the per frame state of every action. Call Update once per frame to sample the source.
+//
+// A chord suppresses the bindings of the same control with fewer modifiers while it is held, so binding "save" to Ctrl+S and "down" to S won't trigger both.
+type Map struct {
+	Source Source
+
+	actions map[string]*Action
+}
+
+func NewMap(source Source) *Map {
+	return &Map{
+		Source:  source,
+		actions: make(map[string]*Action),
+	}
+}
+
+// Adds a button action. The bindings are also used as the action's defaults.
+func (m *Map) AddButton(name string, bindings ...Binding) *Action {
+	return m.add(name, Button, 0, bindings)
+}
+
+// Adds an axis action. The bindings are summed and clamped to [-1, 1], so a pair of keys can be bound to the same axis with opposite scales. The bindings are also used as the action's defaults.
+func (m *Map) AddAxis(name string, deadzone float64, bindings ...Binding) *Action {
+	return m.add(name, Axis, deadzone, bindings)
+}
+
+func (m *Map) add(name string, kind Kind, deadzone float64, bindings []Binding) *Action {
+	_, exists := m.actions[name]
+	if exists {
+		panic(fmt.Sprintf("input: action already added: %s", name))
+	}
+	a := &Action{
+		Name:     name,
+		Kind:     kind,
+		Deadzone: deadzone,
+		bindings: slices.Clone(bindings),
+		defaults: slices.Clone(bindings),
+	}
+	m.actions[name] = a
+	return a
+}
+
+// Returns the action, or nil if it hasn't been added
+func (m *Map) Action(name string) *Action {
+	return m.actions[name]
+}
+
+// Returns the names of every action, sorted
+func (m *Map) Names() []string {
+	return slices.Sorted(maps.Keys(m.actions))
+}
+
+// Samples the source and updates the state of every action
+func (m *Map) Update() {
+	// Every chord that is currently held, so that less specific bindings of the same control can be suppressed
+	chords := make(map[Control]int)
+	for _, a := range m.actions {
+		for _, b := range a.bindings {
+			if b.specificity() > 0 && b.value(m.Source, a.Deadzone) != 0 {
+				chords[b.Control] = max(chords[b.Control], b.specificity())
+			}
+		}
+	}
+
+	for _, a := range m.actions {
+		total := 0.0
+		for _, b := range a.bindings {
+			if b.specificity() < chords[b.Control] {
+				continue // Suppressed by a held chord
+			}
+			v := b.value(m.Source, a.Deadzone)
+			if a.Kind == Button {
+				if v >= ButtonThreshold || v <= -ButtonThreshold {
+					total = 1
+				}
+				continue
+			}
+			total += v
+		}
+		a.update(max(-1, min(1, total)))
+	}
+}
+
+func (a *Action) update(value float64) {
+	a.value = value
+	a.was = a.held
+	a.held = value != 0
+	if a.held {
+		a.heldFrames++
+	} else {
+		a.heldFrames = 0
+	}
+}
+
+// Returns true if the action started being held on this frame
+func (m *Map) Pressed(name string) bool {
+	a := m.actions[name]
+	return a != nil && a.held && !a.was
+}
+
+// Returns true if the action stopped being held on this frame
+func (m *Map) Released(name string) bool {
+	a := m.actions[name]
+	return a != nil && !a.held && a.was
+}
+
+// Returns true if the action is held
+func (m *Map) Held(name string) bool {
+	a := m.actions[name]
+	return a != nil && a.held
+}
+
+// Returns the number of frames that the action has been held for, including this frame. Returns 0 if it isn't held.
+func (m *Map) HeldFrames(name string) int {
+	a := m.actions[name]
+	if a == nil || !a.held {
+		return 0
+	}
+	return a.heldFrames
+}
+
+// Returns the value of the action. Axes are between -1 and 1, buttons are 0 or 1.
+func (m *Map) Value(name string) float64 {
+	a := m.actions[name]
+	if a == nil {
+		return 0
+	}
+	return a.value
+}
+
+//--------------------------------------------------------------------------------
+// - Rebinding
+//--------------------------------------------------------------------------------
+
+// Returns a copy of the action's bindings
+func (m *Map) Bindings(name string) []Binding {
+	a := m.actions[name]
+	if a == nil {
+		return nil
+	}
+	return slices.Clone(a.bindings)
+}
+
+// Replaces the action's bindings
+func (m *Map) Bind(name string, bindings ...Binding) error {
+	a := m.actions[name]
+	if a == nil {
+		return fmt.Errorf("input: unknown action: %s", name)
+	}
+	a.bindings = slices.Clone(bindings)
+	return nil
+}
+
+// Sets every action's bindings back to the bindings that it was added with
+func (m *Map) ResetBindings() {
+	for _, a := range m.actions {
+		a.bindings = slices.Clone(a.defaults)
+	}
+}
+
+// Returns the actions which have a binding for the control, sorted. This is useful for warning about conflicts while rebinding.
+func (m *Map) BoundTo(c Control) []string {
+	var names []string
+	for name, a := range m.actions {
+		for _, b := range a.bindings {
+			if b.Control == c {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Returns the bindings of every action that differs from its defaults
+func (m *Map) Overrides() map[string][]Binding {
+	ret := make(map[string][]Binding)
+	for name, a := range m.actions {
+		if !slices.EqualFunc(a.bindings, a.defaults, bindingEqual) {
+			ret[name] = slices.Clone(a.bindings)
+		}
+	}
+	return ret
+}
+
+// Applies bindings that were returned by Overrides. Bindings for unknown actions are ignored, so that removing an action doesn't break old saves.
+func (m *Map) ApplyOverrides(overrides map[string][]Binding) {
+	for name, bindings := range overrides {
+		a := m.actions[name]
+		if a == nil {
+			continue
+		}
+		a.bindings = slices.Clone(bindings)
+	}
+}
+
+func bindingEqual(a, b Binding) bool {
+	return a.Control == b.Control && a.Scale == b.Scale && slices.Equal(a.Modifiers, b.Modifiers)
+}
+
+// Saves the rebound actions to storage under the key
+func (m *Map) Save(key string) error {
+	return storage.SetItem(key, m.Overrides())
+}
+
+// Loads the rebound actions from storage under the key
+func (m *Map) Load(key string) error {
+	overrides, err := storage.GetItem[map[string][]Binding](key)
+	if err != nil {
+		return err
+	}
+	if overrides == nil {
+		return nil // Nothing has been saved yet
+	}
+	m.ApplyOverrides(*overrides)
+	return nil
+}
@@ -0,0 +1,57 @@
+package input
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/unitoftime/ecs"
+	"github.com/unitoftime/flow"
+)
+
+// Plugin adds a Map resource, and a system which updates it at the start of every frame in StagePreFixedUpdate.
+// Note: Pressed and Released are true for a whole frame. The fixed update stage can run several times in a frame, or not at all, so fixed update systems should read held state or values, or buffer the presses.
+//
+// Systems can read the actions by injecting the resource: `func(dt time.Duration, actions *input.Map)`
+type Plugin struct {
+	Source     Source
+	StorageKey string // If set, rebound actions are loaded from storage on startup, and saved on exit
+
+	Setup func(m *Map) // Optional: Adds the actions. Actions must be added before the saved bindings are loaded.
+}
+
+func (p Plugin) Initialize(world *ecs.World) {
+	app := flow.MustApp(world)
+	if p.Source == nil {
+		panic("input.Plugin requires a Source")
+	}
+
+	m := NewMap(p.Source)
+	if p.Setup != nil {
+		p.Setup(m)
+	}
+	if p.StorageKey != "" {
+		err := m.Load(p.StorageKey)
+		if err != nil {
+			fmt.Println("Error Loading Input Bindings:", err)
+		}
+	}
+	ecs.PutResource(world, m)
+
+	app.AddSystems(ecs.StagePreFixedUpdate, ecs.System{
+		Name: "input.UpdateSystem",
+		Func: func(dt time.Duration) {
+			m.Update()
+		},
+	})
+}
+
+func (p Plugin) Exit(world *ecs.World) {
+	m := ecs.GetResource[Map](world)
+	if m == nil || p.StorageKey == "" {
+		return
+	}
+	err := m.Save(p.StorageKey)
+	if err != nil {
+		fmt.Println("Error Saving Input Bindings:", err)
+	}
+}
@@ -0,0 +1,474 @@
+package layout
+
+import (
+	"math"
+
+	"github.com/unitoftime/flow/glm"
+)
+
+// Size describes how a node is sized along one axis
+type Size struct {
+	Pref float64 // The preferred size. If this is 0, the node is sized to fit its children.
+	Min  float64
+	Max  float64 // If this is 0, the size is unbounded
+	Grow float64 // The node's share of any leftover space in its parent. Nodes with 0 don't grow.
+}
+
+// Returns a size which is always exactly v
+func Fixed(v float64) Size {
+	return Size{Pref: v, Min: v, Max: v}
+}
+
+// Returns a size which fills the leftover space, shared with its siblings by weight
+func Grow(weight float64) Size {
+	return Size{Grow: weight}
+}
+
+func (s Size) clamp(v float64) float64 {
+	if s.Max > 0 {
+		v = math.Min(v, s.Max)
+	}
+	return math.Max(v, s.Min)
+}
+
+// Mode is how a node places its children
+type Mode uint8
+
+const (
+	Stack  Mode = iota // In a row or column along Direction
+	Grid               // In a grid of Columns, filled row by row
+	Anchor             // Each child is placed independently at its Anchor point
+)
+
+type Direction uint8
+
+const (
+	Vertical   Direction = iota // Top to bottom
+	Horizontal                  // Left to right
+)
+
+// Align is how children are placed on the cross axis of a stack
+type Align uint8
+
+const (
+	AlignStretch Align = iota // Fill the cross axis, unless the child has a preferred size
+	AlignStart
+	AlignCenter
+	AlignEnd
+)
+
+// Justify is how the leftover space on the main axis of a stack is distributed, when none of the children grow
+type Justify uint8
+
+const (
+	JustifyStart Justify = iota
+	JustifyCenter
+	JustifyEnd
+	JustifySpaceBetween
+)
+
+// Scroll holds the scroll position of a node. Nodes with a Scroll are allowed to have more content than fits along their Direction. The content is moved by the offset, and children are clipped to the node.
+type Scroll struct {
+	Offset float64 // How far the content is scrolled, from 0 to Max()
+
+	content, viewport float64
+}
+
+// Scrolls by an amount. The offset is clamped to the scrollable range.
+func (s *Scroll) ScrollBy(amount float64) {
+	s.Offset = math.Max(0, math.Min(s.Offset+amount, s.Max()))
+}
+
+// Returns the largest offset, as of the last layout
+func (s *Scroll) Max() float64 {
+	return math.Max(0, s.content-s.viewport)
+}
+
+// Node is a container or leaf in a layout tree. Call Layout on the root to compute the Rect of every node. Coordinates are y-up, so vertical stacks are cut from the top of their rect.
+type Node struct {
+	Name string // Optional: Used by Find
+
+	Width, Height Size
+	Padding       glm.Rect // Space inside the node's edges: Min.X left, Min.Y bottom, Max.X right, Max.Y top
+
+	Mode      Mode
+	Direction Direction
+	Align     Align
+	Justify   Justify
+	Gap       float64 // Space between children (both directions in a grid)
+	Columns   int     // For Grid, the number of columns
+	RowHeight float64 // For Grid, the height of each row. If 0, the rows share the height evenly
+
+	// For children of Anchor nodes: The point in the parent (from 0,0 bottom left to 1,1 top right) that the child is aligned to, and an offset from that point
+	Anchor glm.Vec2
+	Offset glm.Vec2
+
+	Scroll *Scroll
+
+	Children []*Node
+
+	// Computed by Layout
+	Rect glm.Rect // The node's rect
+	Clip glm.Rect // The visible part of the node's rect, after clipping by scrolling ancestors
+}
+
+// Returns a vertical stack
+func VStack(children ...*Node) *Node {
+	return &Node{Mode: Stack, Direction: Vertical, Children: children}
+}
+
+// Returns a horizontal stack
+func HStack(children ...*Node) *Node {
+	return &Node{Mode: Stack, Direction: Horizontal, Children: children}
+}
+
+// Returns a grid with the number of columns
+func GridOf(columns int, children ...*Node) *Node {
+	return &Node{Mode: Grid, Columns: columns, Children: children}
+}
+
+// Returns a leaf node
+func Leaf(name string, width, height Size) *Node {
+	return &Node{Name: name, Width: width, Height: height}
+}
+
+// Returns the first node in the tree with the name, searching depth first
+func (n *Node) Find(name string) *Node {
+	if n.Name == name {
+		return n
+	}
+	for _, c := range n.Children {
+		found := c.Find(name)
+		if found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// Calls fn for the node and every descendant, depth first
+func (n *Node) Walk(fn func(n *Node)) {
+	fn(n)
+	for _, c := range n.Children {
+		c.Walk(fn)
+	}
+}
+
+// Computes the rect of every node in the tree, with the root filling the rect (for example the window or camera bounds)
+func (n *Node) Layout(rect glm.Rect) {
+	n.layout(rect, rect)
+}
+
+func (n *Node) layout(rect, clip glm.Rect) {
+	n.Rect = rect
+	n.Clip = rect.Intersect(clip)
+	inner := rect.Unpad(n.Padding)
+
+	content := inner
+	childClip := n.Clip
+	if n.Scroll != nil {
+		childClip = inner.Intersect(n.Clip)
+		content = n.scrollContent(inner)
+	}
+
+	switch n.Mode {
+	case Stack:
+		n.layoutStack(content, childClip)
+	case Grid:
+		n.layoutGrid(content, childClip)
+	case Anchor:
+		n.layoutAnchor(content, childClip)
+	}
+}
+
+// Returns the rect that the children are laid out in, which is extended along the scroll direction to fit the content and then moved by the offset
+func (n *Node) scrollContent(inner glm.Rect) glm.Rect {
+	measured := n.measureChildren()
+	s := n.Scroll
+	if n.Direction == Vertical {
+		s.viewport = inner.H()
+		s.content = math.Max(measured.Y, s.viewport)
+		s.Offset = math.Max(0, math.Min(s.Offset, s.Max()))
+		top := inner.Max.Y + s.Offset
+		return glm.R(inner.Min.X, top-s.content, inner.Max.X, top)
+	}
+
+	s.viewport = inner.W()
+	s.content = math.Max(measured.X, s.viewport)
+	s.Offset = math.Max(0, math.Min(s.Offset, s.Max()))
+	left := inner.Min.X - s.Offset
+	return glm.R(left, inner.Min.Y, left+s.content, inner.Max.Y)
+}
+
+//--------------------------------------------------------------------------------
+// - Measuring
+//--------------------------------------------------------------------------------
+
+// Returns the preferred size of the node, including padding
+func (n *Node) measure() glm.Vec2 {
+	size := glm.Vec2{n.Width.Pref, n.Height.Pref}
+	if size.X <= 0 || size.Y <= 0 {
+		content := n.measureChildren()
+		if size.X <= 0 {
+			size.X = content.X + n.Padding.Min.X + n.Padding.Max.X
+		}
+		if size.Y <= 0 {
+			size.Y = content.Y + n.Padding.Min.Y + n.Padding.Max.Y
+		}
+	}
+	return glm.Vec2{n.Width.clamp(size.X), n.Height.clamp(size.Y)}
+}
+
+// Returns the size that the children need, not including padding
+func (n *Node) measureChildren() glm.Vec2 {
+	if len(n.Children) == 0 {
+		return glm.Vec2{}
+	}
+
+	switch n.Mode {
+	case Stack:
+		var main, cross float64
+		for _, c := range n.Children {
+			m := c.measure()
+			if n.Direction == Vertical {
+				main += m.Y
+				cross = math.Max(cross, m.X)
+			} else {
+				main += m.X
+				cross = math.Max(cross, m.Y)
+			}
+		}
+		main += n.Gap * float64(len(n.Children)-1)
+		if n.Direction == Vertical {
+			return glm.Vec2{cross, main}
+		}
+		return glm.Vec2{main, cross}
+
+	case Grid:
+		cols, rows := n.gridSize()
+		var cell glm.Vec2
+		for _, c := range n.Children {
+			m := c.measure()
+			cell.X = math.Max(cell.X, m.X)
+			cell.Y = math.Max(cell.Y, m.Y)
+		}
+		if n.RowHeight > 0 {
+			cell.Y = n.RowHeight
+		}
+		return glm.Vec2{
+			float64(cols)*cell.X + float64(cols-1)*n.Gap,
+			float64(rows)*cell.Y + float64(rows-1)*n.Gap,
+		}
+
+	case Anchor:
+		var size glm.Vec2
+		for _, c := range n.Children {
+			m := c.measure()
+			size.X = math.Max(size.X, m.X+math.Abs(c.Offset.X))
+			size.Y = math.Max(size.Y, m.Y+math.Abs(c.Offset.Y))
+		}
+		return size
+	}
+	return glm.Vec2{}
+}
+
+//--------------------------------------------------------------------------------
+// - Stack
+//--------------------------------------------------------------------------------
+
+func (n *Node) layoutStack(rect, clip glm.Rect) {
+	count := len(n.Children)
+	if count == 0 {
+		return
+	}
+
+	vertical := n.Direction == Vertical
+	mainLen, crossLen := rect.W(), rect.H()
+	if vertical {
+		mainLen, crossLen = rect.H(), rect.W()
+	}
+
+	sizes := make([]float64, count)
+	mains := make([]Size, count)
+	for i, c := range n.Children {
+		m := c.measure()
+		mains[i] = c.Width
+		sizes[i] = m.X
+		if vertical {
+			mains[i] = c.Height
+			sizes[i] = m.Y
+		}
+	}
+
+	free := distribute(sizes, mains, mainLen-n.Gap*float64(count-1))
+
+	// Leftover space is justified
+	lead, gap := 0.0, n.Gap
+	if free > 0 {
+		switch n.Justify {
+		case JustifyCenter:
+			lead = free / 2
+		case JustifyEnd:
+			lead = free
+		case JustifySpaceBetween:
+			if count > 1 {
+				gap += free / float64(count-1)
+			} else {
+				lead = free / 2
+			}
+		}
+	}
+
+	remaining := rect
+	cut(&remaining, vertical, lead)
+	for i, c := range n.Children {
+		slot := cut(&remaining, vertical, sizes[i])
+		cut(&remaining, vertical, gap)
+		c.layout(n.alignCross(c, slot, vertical, crossLen), clip)
+	}
+}
+
+// Cuts an amount from the start of the main axis
+func cut(r *glm.Rect, vertical bool, amount float64) glm.Rect {
+	if vertical {
+		return r.CutTop(amount)
+	}
+	return r.CutLeft(amount)
+}
+
+// Places the child within its slot on the cross axis
+func (n *Node) alignCross(c *Node, slot glm.Rect, vertical bool, crossLen float64) glm.Rect {
+	cross := c.Height
+	if vertical {
+		cross = c.Width
+	}
+
+	size := crossLen
+	align := n.Align
+	if align == AlignStretch && cross.Pref > 0 {
+		align = AlignStart
+	}
+	if align != AlignStretch {
+		m := c.measure()
+		size = m.Y
+		if vertical {
+			size = m.X
+		}
+	}
+	size = math.Min(cross.clamp(size), crossLen)
+
+	var start float64
+	switch align {
+	case AlignCenter:
+		start = (crossLen - size) / 2
+	case AlignEnd:
+		start = crossLen - size
+	}
+
+	if vertical {
+		return glm.R(slot.Min.X+start, slot.Min.Y, slot.Min.X+start+size, slot.Max.Y)
+	}
+	// Note: The cross axis of a horizontal stack starts at the top
+	return glm.R(slot.Min.X, slot.Max.Y-start-size, slot.Max.X, slot.Max.Y-start)
+}
+
+// Grows or shrinks the sizes so that they fill the available space, respecting each size's min and max. Returns the leftover space.
+func distribute(sizes []float64, specs []Size, available float64) float64 {
+	frozen := make([]bool, len(sizes))
+	for {
+		total := 0.0
+		for _, s := range sizes {
+			total += s
+		}
+		free := available - total
+		if math.Abs(free) < 1e-9 {
+			return 0
+		}
+
+		// Growing is shared by weight, shrinking is shared by how far each size is above its min
+		weights := make([]float64, len(sizes))
+		totalWeight := 0.0
+		for i, spec := range specs {
+			if frozen[i] {
+				continue
+			}
+			if free > 0 {
+				weights[i] = spec.Grow
+			} else {
+				weights[i] = sizes[i] - spec.Min
+			}
+			totalWeight += weights[i]
+		}
+		if totalWeight <= 0 {
+			return max(free, 0)
+		}
+
+		clamped := false
+		for i, spec := range specs {
+			if weights[i] <= 0 {
+				continue
+			}
+			target := sizes[i] + free*weights[i]/totalWeight
+			limited := spec.clamp(target)
+			if limited != target {
+				frozen[i] = true
+				clamped = true
+			}
+			sizes[i] = limited
+		}
+		if !clamped {
+			return 0
+		}
+	}
+}
+
+//--------------------------------------------------------------------------------
+// - Grid and Anchor
+//--------------------------------------------------------------------------------
+
+func (n *Node) gridSize() (cols, rows int) {
+	cols = max(1, n.Columns)
+	rows = (len(n.Children) + cols - 1) / cols
+	return cols, max(1, rows)
+}
+
+func (n *Node) layoutGrid(rect, clip glm.Rect) {
+	if len(n.Children) == 0 {
+		return
+	}
+	cols, rows := n.gridSize()
+	cellW := (rect.W() - n.Gap*float64(cols-1)) / float64(cols)
+	cellH := n.RowHeight
+	if cellH <= 0 {
+		cellH = (rect.H() - n.Gap*float64(rows-1)) / float64(rows)
+	}
+
+	remaining := rect
+	for row := 0; row < rows; row++ {
+		rowRect := remaining.CutTop(cellH)
+		remaining.CutTop(n.Gap)
+		for col := 0; col < cols; col++ {
+			i := row*cols + col
+			if i >= len(n.Children) {
+				return
+			}
+			cell := rowRect.CutLeft(cellW)
+			rowRect.CutLeft(n.Gap)
+			n.Children[i].layout(cell, clip)
+		}
+	}
+}
+
+func (n *Node) layoutAnchor(rect, clip glm.Rect) {
+	for _, c := range n.Children {
+		size := c.measure()
+		if c.Width.Grow > 0 {
+			size.X = c.Width.clamp(rect.W())
+		}
+		if c.Height.Grow > 0 {
+			size.Y = c.Height.clamp(rect.H())
+		}
+		placed := rect.Anchor(glm.R(0, 0, size.X, size.Y), c.Anchor).Moved(c.Offset)
+		c.layout(placed, clip)
+	}
+}
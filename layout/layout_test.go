@@ -0,0 +1,148 @@
+package layout
+
+import (
+	"testing"
+
+	"github.com/unitoftime/flow/glm"
+)
+
+func expectRect(t *testing.T, name string, got, expected glm.Rect) {
+	t.Helper()
+	if got != expected {
+		t.Errorf("%s: expected %v, got %v", name, expected, got)
+	}
+}
+
+func TestStack(t *testing.T) {
+	root := VStack(
+		Leaf("header", Size{}, Fixed(20)),
+		Leaf("body", Size{}, Grow(1)),
+		Leaf("footer", Size{}, Fixed(10)),
+	)
+	root.Padding = glm.R(5, 5, 5, 5)
+	root.Gap = 5
+	root.Layout(glm.R(0, 0, 100, 100))
+
+	// Note: y-up, so the header is cut from the top
+	expectRect(t, "header", root.Find("header").Rect, glm.R(5, 75, 95, 95))
+	expectRect(t, "body", root.Find("body").Rect, glm.R(5, 20, 95, 70))
+	expectRect(t, "footer", root.Find("footer").Rect, glm.R(5, 5, 95, 15))
+}
+
+func TestFlex(t *testing.T) {
+	root := HStack(
+		Leaf("a", Grow(1), Size{}),
+		Leaf("b", Size{Grow: 2, Max: 30}, Size{}),
+		Leaf("c", Grow(1), Size{}),
+	)
+	root.Layout(glm.R(0, 0, 100, 10))
+
+	// b wants 50 but is capped at 30, so its share goes to a and c
+	expectRect(t, "a", root.Find("a").Rect, glm.R(0, 0, 35, 10))
+	expectRect(t, "b", root.Find("b").Rect, glm.R(35, 0, 65, 10))
+	expectRect(t, "c", root.Find("c").Rect, glm.R(65, 0, 100, 10))
+
+	// Shrinking is shared by how far each size is above its min
+	shrink := HStack(
+		Leaf("a", Size{Pref: 60, Min: 60}, Size{}),
+		Leaf("b", Size{Pref: 60, Min: 20}, Size{}),
+		Leaf("c", Size{Pref: 40, Min: 0}, Size{}),
+	)
+	shrink.Layout(glm.R(0, 0, 100, 10))
+	expectRect(t, "shrink a", shrink.Find("a").Rect, glm.R(0, 0, 60, 10))
+	expectRect(t, "shrink b", shrink.Find("b").Rect, glm.R(60, 0, 90, 10))
+	expectRect(t, "shrink c", shrink.Find("c").Rect, glm.R(90, 0, 100, 10))
+}
+
+func TestAlignAndJustify(t *testing.T) {
+	root := VStack(
+		Leaf("a", Fixed(20), Fixed(10)),
+		Leaf("b", Fixed(40), Fixed(10)),
+	)
+	root.Align = AlignCenter
+	root.Justify = JustifySpaceBetween
+	root.Layout(glm.R(0, 0, 100, 100))
+
+	expectRect(t, "a", root.Find("a").Rect, glm.R(40, 90, 60, 100))
+	expectRect(t, "b", root.Find("b").Rect, glm.R(30, 0, 70, 10))
+
+	root.Align = AlignEnd
+	root.Justify = JustifyCenter
+	root.Layout(glm.R(0, 0, 100, 100))
+	expectRect(t, "a end", root.Find("a").Rect, glm.R(80, 50, 100, 60))
+	expectRect(t, "b end", root.Find("b").Rect, glm.R(60, 40, 100, 50))
+}
+
+func TestGrid(t *testing.T) {
+	root := GridOf(2,
+		Leaf("0", Size{}, Size{}),
+		Leaf("1", Size{}, Size{}),
+		Leaf("2", Size{}, Size{}),
+	)
+	root.Gap = 10
+	root.Layout(glm.R(0, 0, 110, 50))
+
+	expectRect(t, "0", root.Find("0").Rect, glm.R(0, 30, 50, 50))
+	expectRect(t, "1", root.Find("1").Rect, glm.R(60, 30, 110, 50))
+	expectRect(t, "2", root.Find("2").Rect, glm.R(0, 0, 50, 20))
+}
+
+func TestAnchor(t *testing.T) {
+	minimap := Leaf("minimap", Fixed(30), Fixed(20))
+	minimap.Anchor = glm.Vec2{1, 1}
+	minimap.Offset = glm.Vec2{-5, -5}
+
+	bar := Leaf("bar", Grow(1), Fixed(10))
+	bar.Anchor = glm.Vec2{0.5, 0}
+
+	root := &Node{Mode: Anchor, Children: []*Node{minimap, bar}}
+	root.Layout(glm.R(0, 0, 200, 100))
+
+	expectRect(t, "minimap", minimap.Rect, glm.R(165, 75, 195, 95))
+	expectRect(t, "bar", bar.Rect, glm.R(0, 0, 200, 10))
+}
+
+func TestScroll(t *testing.T) {
+	var items []*Node
+	for i := 0; i < 10; i++ {
+		items = append(items, Leaf("", Size{}, Fixed(20)))
+	}
+	list := VStack(items...)
+	list.Scroll = &Scroll{}
+	list.Layout(glm.R(0, 0, 50, 50))
+
+	if list.Scroll.Max() != 150 {
+		t.Errorf("expected 150 of overflow, got %v", list.Scroll.Max())
+	}
+	expectRect(t, "first", items[0].Rect, glm.R(0, 30, 50, 50))
+	expectRect(t, "first clip", items[0].Clip, glm.R(0, 30, 50, 50))
+	if items[5].Clip.H() != 0 {
+		t.Errorf("expected item below the viewport to be clipped, got %v", items[5].Clip)
+	}
+
+	list.Scroll.ScrollBy(1000)
+	list.Layout(glm.R(0, 0, 50, 50))
+	if list.Scroll.Offset != 150 {
+		t.Errorf("expected offset to be clamped, got %v", list.Scroll.Offset)
+	}
+	expectRect(t, "last", items[9].Rect, glm.R(0, 0, 50, 20))
+	if items[0].Clip.H() != 0 {
+		t.Errorf("expected item above the viewport to be clipped, got %v", items[0].Clip)
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	// Containers without a preferred size fit their children
+	inner := HStack(
+		Leaf("a", Fixed(10), Fixed(5)),
+		Leaf("b", Fixed(20), Fixed(8)),
+	)
+	inner.Gap = 2
+	inner.Padding = glm.R(1, 1, 1, 1)
+
+	root := VStack(inner)
+	root.Align = AlignStart
+	root.Layout(glm.R(0, 0, 100, 100))
+
+	expectRect(t, "inner", inner.Rect, glm.R(0, 90, 34, 100))
+}
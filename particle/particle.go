@@ -9,6 +9,7 @@ import (
 	// "github.com/ungerik/go3d/float64/vec2"
 
 	"github.com/unitoftime/ecs"
+	"github.com/unitoftime/flow"
 
 	// "github.com/unitoftime/flow/timer"
 	"github.com/unitoftime/flow/glm"
@@ -68,6 +69,19 @@ func (l *Lifetime) Ratio() float64 {
 	return 1 - Clamp(0, 1.0, l.Remaining.Seconds()/l.Total.Seconds())
 }
 
+// Counts the remaining lifetime down by dt. Returns true if the lifetime has run out.
+func (l *Lifetime) Update(dt time.Duration) bool {
+	l.Remaining -= dt
+	return l.Remaining <= 0
+}
+
+// Counts down every lifetime using the scaled time, so that particles respect pause and slow motion
+func UpdateLifetimeSystem(dt time.Duration, clock *flow.Time, query *ecs.View1[Lifetime]) {
+	query.MapId(func(id ecs.Id, l *Lifetime) {
+		l.Update(clock.Delta)
+	})
+}
+
 type Color struct {
 	Interp     interp.Interp
 	Start, End color.NRGBA
@@ -5,6 +5,7 @@ import (
 
 	"github.com/unitoftime/ecs"
 	"github.com/unitoftime/flow/glm"
+	"github.com/unitoftime/flow/snapshot"
 )
 
 // //cod:struct
@@ -46,6 +47,10 @@ import (
 // 	return math.Atan2(v.Y, v.X)
 // }
 
+func init() {
+	snapshot.Register[Vel]()
+}
+
 //cod:struct
 type Vel glm.Vec2
 
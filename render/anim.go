@@ -3,6 +3,7 @@ package render
 import (
 	"time"
 
+	"github.com/unitoftime/flow"
 	"github.com/unitoftime/flow/glm"
 	"github.com/unitoftime/glitch"
 )
@@ -13,6 +14,11 @@ func UpdateGlobalAnimationTimer(dt time.Duration) {
 	globalTimer += dt
 }
 
+// Advances the global animation timer by the scaled time, so that animations respect pause and slow motion
+func UpdateGlobalAnimationTimerSystem(dt time.Duration, clock *flow.Time) {
+	UpdateGlobalAnimationTimer(clock.Delta)
+}
+
 // TODO - it might make more sense to make this like an aseprite wrapper object that has layers, frames, tags, etc
 
 // This is an animation frame
@@ -54,6 +54,16 @@ func (c Target) CompWrite(w ecs.W) {
 	TargetComp.WriteVal(w, c)
 }
 
+var TintComp = ecs.NewComp[Tint]()
+
+func (c Tint) CompId() ecs.CompId {
+	return TintComp.CompId()
+}
+
+func (c Tint) CompWrite(w ecs.W) {
+	TintComp.WriteVal(w, c)
+}
+
 var TransformComp = ecs.NewComp[Transform]()
 
 func (c Transform) CompId() ecs.CompId {
@@ -4,25 +4,43 @@ import (
 	"time"
 
 	"github.com/unitoftime/ecs"
+	"github.com/unitoftime/flow"
 	"github.com/unitoftime/flow/glm"
+	"github.com/unitoftime/flow/snapshot"
+	"github.com/unitoftime/flow/transform"
 	"github.com/unitoftime/glitch"
 )
 
 //go:generate go run ../../cod/cmd/cod
 
+// Note: Window, Sprite, Target, Camera, and Animation aren't registered for snapshots because they hold live window and GPU resources. VisionList is recalculated every frame.
+func init() {
+	snapshot.Register[Visibility]()
+	snapshot.Register[CalculatedVisibility]()
+	snapshot.Register[Transform]()
+	snapshot.Register[Tint]()
+}
+
 type DefaultPlugin struct {
 }
 
+func (p DefaultPlugin) Dependencies() []flow.Plugin {
+	return []flow.Plugin{
+		transform.DefaultPlugin{},
+	}
+}
+
 func (p DefaultPlugin) Initialize(world *ecs.World) {
-	scheduler := ecs.GetResource[ecs.Scheduler](world)
+	app := flow.MustApp(world)
 
 	rl := NewRenderPassList()
 	ecs.PutResource(world, &rl)
 
-	scheduler.AddSystems(ecs.StageStartup,
+	app.AddSystems(ecs.StageStartup,
 		ecs.NewSystem1(SetupRenderingSystem),
 	)
-	scheduler.AddSystems(ecs.StageUpdate,
+	app.AddSystems(ecs.StageUpdate,
+		ecs.NewSystem1(UpdateGlobalAnimationTimerSystem),
 		ecs.NewSystem1(UpdateCameraSystem),
 		ecs.NewSystem2(CalculateVisibilitySystem),
 
@@ -63,6 +81,13 @@ func (s Sprite) Bounds() glm.Rect {
 	return s.Sprite.Bounds()
 }
 
+// Tint multiplies the color of the entity's sprite. Sprites without a tint are drawn white.
+//
+//cod:component
+type Tint struct {
+	glm.RGBA
+}
+
 //cod:component
 type Target struct {
 	draw  RenderTarget
@@ -1,6 +1,7 @@
 package render
 
 import (
+	"github.com/unitoftime/flow/input"
 	"github.com/unitoftime/glitch"
 )
 
@@ -33,3 +34,36 @@ func MouseDrag(win *glitch.Window, camera *Camera, dragButton glitch.Key) {
 		cursor.Dragging = false
 	}
 }
+
+// WindowSource is an input.Source which reads the window's keyboard, mouse and primary gamepad
+type WindowSource struct {
+	Window *glitch.Window
+}
+
+func (s WindowSource) Value(c input.Control) float64 {
+	switch c.Device {
+	case input.DeviceKey:
+		if s.Window.Pressed(glitch.Key(c.Code)) {
+			return 1
+		}
+	case input.DeviceGamepadButton:
+		if s.Window.GetGamepadPressed(s.Window.GetPrimaryGamepad(), glitch.GamepadButton(c.Code)) {
+			return 1
+		}
+	case input.DeviceGamepadAxis:
+		return s.Window.GetGamepadAxis(s.Window.GetPrimaryGamepad(), glitch.GamepadAxis(c.Code))
+	}
+	return 0
+}
+
+func KeyControl(key glitch.Key) input.Control {
+	return input.Key(int(key))
+}
+
+func GamepadButtonControl(button glitch.GamepadButton) input.Control {
+	return input.GamepadButton(int(button))
+}
+
+func GamepadAxisControl(axis glitch.GamepadAxis) input.Control {
+	return input.GamepadAxis(int(axis))
+}
@@ -121,7 +121,7 @@ func CalculateRenderPasses(dt time.Duration, passes *RenderPassList, query *ecs.
 	// TODO: Sort somehow? Priority? Order added?
 }
 
-func ExecuteRenderPass(dt time.Duration, passes *RenderPassList, query *ecs.View2[transform.Global, Sprite]) {
+func ExecuteRenderPass(dt time.Duration, passes *RenderPassList, query *ecs.View3[transform.Global, Sprite, Tint]) {
 	for _, pass := range passes.List {
 		glitch.Clear(pass.drawTarget, pass.clearColor)
 
@@ -129,7 +129,7 @@ func ExecuteRenderPass(dt time.Duration, passes *RenderPassList, query *ecs.View
 		glitch.SetCamera(camera)
 
 		for _, id := range pass.visionList.List {
-			gt, sprite := query.Read(id)
+			gt, sprite, tint := query.Read(id)
 			if gt == nil {
 				continue
 			}
@@ -137,8 +137,13 @@ func ExecuteRenderPass(dt time.Duration, passes *RenderPassList, query *ecs.View
 				continue
 			}
 
+			color := glm.White
+			if tint != nil {
+				color = tint.RGBA
+			}
+
 			mat := gt.Mat4()
-			sprite.Sprite.DrawColorMask(pass.batchTarget, mat, glm.White)
+			sprite.Sprite.DrawColorMask(pass.batchTarget, mat, color)
 		}
 	}
 }
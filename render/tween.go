@@ -0,0 +1,9 @@
+package render
+
+import (
+	"github.com/unitoftime/flow/glm"
+	"github.com/unitoftime/flow/tween"
+)
+
+// SpriteColor tweens the entity's Tint
+var SpriteColor = tween.Register(tween.Field("render.SpriteColor", func(t *Tint) *glm.RGBA { return &t.RGBA }))
@@ -0,0 +1,80 @@
+package render
+
+import (
+	"github.com/unitoftime/flow/glm"
+	"github.com/unitoftime/flow/ui"
+	"github.com/unitoftime/glitch"
+)
+
+// UIPainter is a ui.Painter which draws with glitch. Rects are drawn by stretching the Blank sprite (usually a single white pixel). Glitch doesn't have scissor rects, so rects are cut to the clip and text which isn't fully inside the clip is skipped.
+type UIPainter struct {
+	Target    glitch.BatchTarget
+	Blank     *glitch.Sprite
+	Atlas     *glitch.Atlas
+	TextScale float64
+
+	clips []glm.Rect
+}
+
+// Returns a ui.Style with text measured by the painter's atlas
+func (p *UIPainter) Style() ui.Style {
+	style := ui.DefaultStyle()
+	style.MeasureText = func(s string) float64 {
+		return p.Atlas.Text(s, p.scale()).Bounds().W()
+	}
+	return style
+}
+
+func (p *UIPainter) scale() float64 {
+	if p.TextScale == 0 {
+		return 1
+	}
+	return p.TextScale
+}
+
+func (p *UIPainter) Rect(r glm.Rect, color glm.RGBA) {
+	if len(p.clips) > 0 {
+		r = r.Intersect(p.clips[len(p.clips)-1])
+	}
+	if r.W() <= 0 || r.H() <= 0 {
+		return
+	}
+	p.Blank.RectDrawColorMask(p.Target, r, color)
+}
+
+func (p *UIPainter) Text(r glm.Rect, text string, color glm.RGBA) {
+	t := p.Atlas.Text(text, p.scale())
+	r = r.FullAnchor(t.Bounds(), glm.Vec2{0, 0.5}, glm.Vec2{0, 0.5})
+	if len(p.clips) > 0 && r.Intersect(p.clips[len(p.clips)-1]) != r {
+		return
+	}
+	t.DrawRect(p.Target, r, color)
+}
+
+func (p *UIPainter) PushClip(r glm.Rect) {
+	p.clips = append(p.clips, r)
+}
+
+func (p *UIPainter) PopClip() {
+	p.clips = p.clips[:len(p.clips)-1]
+}
+
+// Returns the ui.Input for the window's mouse and keyboard. Gamepad navigation can be added by the caller.
+func UIInput(win *glitch.Window) ui.Input {
+	x, y := win.MousePosition()
+	_, scroll := win.MouseScroll()
+	return ui.Input{
+		Mouse:     glm.Vec2{x, y},
+		MouseDown: win.Pressed(glitch.MouseButtonLeft),
+		Scroll:    scroll,
+		Text:      win.Typed(),
+
+		NavUp:     win.JustPressed(glitch.KeyUp) || (win.JustPressed(glitch.KeyTab) && win.Pressed(glitch.KeyLeftShift)),
+		NavDown:   win.JustPressed(glitch.KeyDown) || (win.JustPressed(glitch.KeyTab) && !win.Pressed(glitch.KeyLeftShift)),
+		NavLeft:   win.Repeated(glitch.KeyLeft) || win.JustPressed(glitch.KeyLeft),
+		NavRight:  win.Repeated(glitch.KeyRight) || win.JustPressed(glitch.KeyRight),
+		Activate:  win.JustPressed(glitch.KeyEnter),
+		Cancel:    win.JustPressed(glitch.KeyEscape),
+		Backspace: win.Repeated(glitch.KeyBackspace) || win.JustPressed(glitch.KeyBackspace),
+	}
+}
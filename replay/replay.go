@@ -0,0 +1,315 @@
+package replay
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/unitoftime/ecs"
+	"github.com/unitoftime/flow"
+	"github.com/unitoftime/flow/asset/serde"
+	"github.com/unitoftime/flow/snapshot"
+)
+
+// The recording format version. This is bumped whenever the layout of Recording changes.
+const Version = 1
+
+// The default number of frames between world hashes
+const DefaultHashInterval = 60
+
+// Recording holds everything needed to play back a session: The seed of the app's flow.Rng, and the dt and input of every frame. Periodic hashes of the world are stored so that a playback can check that it produced the same world.
+// I is your game's per-frame input type (ie the keys and mouse buttons that were held). It must be serializable with serde.
+type Recording[I any] struct {
+	Version int
+	Seed    int64
+	Frames  []Frame[I]
+	Hashes  []Hash
+}
+
+type Frame[I any] struct {
+	Dt    time.Duration // The frame's dt, after the app clamped it to its max frame time
+	Input I
+}
+
+// The hash of the world at the start of a frame, before the frame's input is applied
+type Hash struct {
+	Frame int
+	Sum   uint64
+}
+
+// Input is a resource which holds the input for the current frame. While recording it is filled by your poll function, and while playing back it is filled from the recording. Systems should read input from here, rather than from the window, so that playback is exact.
+//
+// Systems can access it by injecting the resource: `func(dt time.Duration, input *replay.Input[MyInput])`
+type Input[I any] struct {
+	Current I
+}
+
+func Marshal[I any](rec *Recording[I]) ([]byte, error) {
+	return serde.Marshal(*rec)
+}
+
+func Unmarshal[I any](dat []byte) (*Recording[I], error) {
+	rec, err := serde.Unmarshal[Recording[I]](dat)
+	if err != nil {
+		return nil, err
+	}
+	if rec.Version != Version {
+		return nil, fmt.Errorf("replay: unsupported version %d, expected %d", rec.Version, Version)
+	}
+	return &rec, nil
+}
+
+// Reads a recording from a file
+func Load[I any](path string) (*Recording[I], error) {
+	dat, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Unmarshal[I](dat)
+}
+
+func hashWorld(registry *snapshot.Registry, world *ecs.World) (uint64, error) {
+	if registry == nil {
+		registry = snapshot.DefaultRegistry
+	}
+	snap, err := registry.Capture(world)
+	if err != nil {
+		return 0, err
+	}
+	return snap.Hash(), nil
+}
+
+func mustGetRng(world *ecs.World) *flow.Rng {
+	rng := ecs.GetResource[flow.Rng](world)
+	if rng == nil {
+		panic("replay: requires a flow.Rng resource, use flow.NewApp to create your world")
+	}
+	return rng
+}
+
+//--------------------------------------------------------------------------------
+// - Recorder
+//--------------------------------------------------------------------------------
+
+// Recorder is a plugin which records the dt and input of every frame of an app. It should be added before any plugins that use randomness when they are initialized.
+//
+//	recorder := replay.NewRecorder(pollInput)
+//	app.AddPlugin(recorder)
+//	app.Run()
+//	recorder.Save("bug.replay")
+type Recorder[I any] struct {
+	Poll         func(world *ecs.World) I // Reads the live input at the start of each frame
+	HashInterval int                      // The number of frames between world hashes. Defaults to DefaultHashInterval
+	Registry     *snapshot.Registry       // The components which are hashed. Defaults to snapshot.DefaultRegistry
+
+	world *ecs.World
+	input *Input[I]
+	rec   Recording[I]
+	err   error
+}
+
+func NewRecorder[I any](poll func(world *ecs.World) I) *Recorder[I] {
+	return &Recorder[I]{
+		Poll:         poll,
+		HashInterval: DefaultHashInterval,
+	}
+}
+
+func (r *Recorder[I]) Initialize(world *ecs.World) {
+	app := flow.MustApp(world)
+	r.world = world
+	r.rec.Version = Version
+
+	// Note: Reseeding resets the generator, so the recording and the playback draw the same sequence from this point on
+	rng := mustGetRng(world)
+	r.rec.Seed = rng.Seed()
+	rng.SetSeed(r.rec.Seed)
+
+	r.input = &Input[I]{}
+	ecs.PutResource(world, r.input)
+	app.OnFrameStart(r.frameStart)
+}
+
+func (r *Recorder[I]) frameStart(world *ecs.World, dt time.Duration) {
+	frame := len(r.rec.Frames)
+	if frame%hashInterval(r.HashInterval) == 0 {
+		r.hash(frame)
+	}
+
+	input := r.Poll(world)
+	r.input.Current = input
+	r.rec.Frames = append(r.rec.Frames, Frame[I]{dt, input})
+}
+
+func hashInterval(interval int) int {
+	if interval <= 0 {
+		return DefaultHashInterval
+	}
+	return interval
+}
+
+func (r *Recorder[I]) hash(frame int) {
+	sum, err := hashWorld(r.Registry, r.world)
+	if err != nil {
+		if r.err == nil {
+			r.err = fmt.Errorf("replay: failed to hash frame %d: %w", frame, err)
+		}
+		return
+	}
+	r.rec.Hashes = append(r.rec.Hashes, Hash{frame, sum})
+}
+
+// Returns the number of frames that have been recorded
+func (r *Recorder[I]) Len() int {
+	return len(r.rec.Frames)
+}
+
+// Returns a copy of everything recorded so far. The final state of the world is hashed, so that playback can check the final frame.
+func (r *Recorder[I]) Recording() (*Recording[I], error) {
+	if r.world == nil {
+		return nil, fmt.Errorf("replay: recorder was never initialized")
+	}
+
+	rec := r.rec
+	rec.Frames = append([]Frame[I](nil), r.rec.Frames...)
+	rec.Hashes = append([]Hash(nil), r.rec.Hashes...)
+
+	frame := len(rec.Frames)
+	if len(rec.Hashes) == 0 || rec.Hashes[len(rec.Hashes)-1].Frame != frame {
+		sum, err := hashWorld(r.Registry, r.world)
+		if err != nil {
+			return nil, err
+		}
+		rec.Hashes = append(rec.Hashes, Hash{frame, sum})
+	}
+	return &rec, r.err
+}
+
+// Writes the recording to a file
+func (r *Recorder[I]) Save(path string) error {
+	rec, err := r.Recording()
+	if err != nil {
+		return err
+	}
+	dat, err := Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, dat, 0644)
+}
+
+//--------------------------------------------------------------------------------
+// - Player
+//--------------------------------------------------------------------------------
+
+// Divergence is returned when a playback produces a different world than the recording did
+type Divergence struct {
+	Frame    int // The first hashed frame that didn't match. The divergence happened somewhere after the previous hashed frame.
+	Expected uint64
+	Actual   uint64
+}
+
+func (d *Divergence) Error() string {
+	return fmt.Sprintf("replay: world diverged at frame %d (expected hash %x, got %x)", d.Frame, d.Expected, d.Actual)
+}
+
+// Player is a plugin which feeds a recording back into an app. The app must be built with the same plugins and systems as the app that was recorded.
+//
+//	rec, err := replay.Load[MyInput]("bug.replay")
+//	player := replay.NewPlayer(rec)
+//	app.AddPlugin(player)
+//	err = player.Run(app)
+type Player[I any] struct {
+	Registry *snapshot.Registry // The components which are hashed. Must match the recorder. Defaults to snapshot.DefaultRegistry
+
+	rec        *Recording[I]
+	world      *ecs.World
+	input      *Input[I]
+	frame      int
+	nextHash   int
+	divergence *Divergence
+	err        error
+}
+
+func NewPlayer[I any](rec *Recording[I]) *Player[I] {
+	return &Player[I]{
+		rec: rec,
+	}
+}
+
+func (p *Player[I]) Initialize(world *ecs.World) {
+	app := flow.MustApp(world)
+	p.world = world
+
+	mustGetRng(world).SetSeed(p.rec.Seed)
+
+	p.input = &Input[I]{}
+	ecs.PutResource(world, p.input)
+	app.OnFrameStart(p.frameStart)
+}
+
+func (p *Player[I]) frameStart(world *ecs.World, dt time.Duration) {
+	p.check(p.frame)
+
+	if p.frame < len(p.rec.Frames) {
+		p.input.Current = p.rec.Frames[p.frame].Input
+	} else {
+		var zero I
+		p.input.Current = zero
+	}
+	p.frame++
+}
+
+// Compares the world against the recorded hash for the frame, if there is one
+func (p *Player[I]) check(frame int) {
+	if p.divergence != nil || p.err != nil {
+		return
+	}
+
+	for p.nextHash < len(p.rec.Hashes) && p.rec.Hashes[p.nextHash].Frame < frame {
+		p.nextHash++
+	}
+	if p.nextHash >= len(p.rec.Hashes) || p.rec.Hashes[p.nextHash].Frame != frame {
+		return
+	}
+	expected := p.rec.Hashes[p.nextHash].Sum
+	p.nextHash++
+
+	sum, err := hashWorld(p.Registry, p.world)
+	if err != nil {
+		p.err = fmt.Errorf("replay: failed to hash frame %d: %w", frame, err)
+		return
+	}
+	if sum != expected {
+		p.divergence = &Divergence{frame, expected, sum}
+	}
+}
+
+// Returns true once every recorded frame has been played
+func (p *Player[I]) Done() bool {
+	return p.frame >= len(p.rec.Frames)
+}
+
+// Returns the first divergence that was found, or nil if the playback has matched the recording so far
+func (p *Player[I]) Divergence() *Divergence {
+	return p.divergence
+}
+
+// Steps the app through every recorded frame with the recorded dt, then checks the final state of the world. Playback stops at the first divergence, which is returned as a *Divergence error.
+func (p *Player[I]) Run(app *flow.App) error {
+	for !p.Done() {
+		app.Step(p.rec.Frames[p.frame].Dt)
+		if p.divergence != nil || p.err != nil {
+			break
+		}
+	}
+	p.check(p.frame)
+
+	if p.err != nil {
+		return p.err
+	}
+	if p.divergence != nil {
+		return p.divergence
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package replay
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/unitoftime/ecs"
+	"github.com/unitoftime/flow"
+	"github.com/unitoftime/flow/snapshot"
+)
+
+type testInput struct {
+	Right bool
+	Jump  bool
+}
+
+type body struct {
+	X, Y int
+}
+
+func testRegistry() *snapshot.Registry {
+	r := snapshot.NewRegistry()
+	snapshot.RegisterTo[body](r)
+	return r
+}
+
+// Builds a small game which moves a body from input and randomness. If broken is set, the game misbehaves after frame 25.
+func newGame(plugin flow.Plugin, broken bool) *flow.App {
+	app := flow.NewApp()
+	app.SetFixedTimeStep(10 * time.Millisecond)
+	app.AddPlugin(plugin)
+
+	app.OnStartup(func(world *ecs.World) {
+		id := world.NewId()
+		world.Write(id, ecs.C(body{}))
+	})
+
+	frame := 0
+	app.AddSystems(ecs.StageFixedUpdate, ecs.NewSystem3(func(dt time.Duration, input *Input[testInput], rng *flow.Rng, query *ecs.View1[body]) {
+		query.MapId(func(id ecs.Id, b *body) {
+			if input.Current.Right {
+				b.X += 1 + rng.Intn(3)
+			}
+			if input.Current.Jump {
+				b.Y += 10
+			}
+			if broken && frame > 25 {
+				b.Y++
+			}
+		})
+	}))
+	app.AddSystems(ecs.StageUpdate, ecs.NewSystem(func(dt time.Duration) {
+		frame++
+	}))
+	return app
+}
+
+func record(t *testing.T, frames int) *Recording[testInput] {
+	t.Helper()
+	count := 0
+	recorder := NewRecorder(func(world *ecs.World) testInput {
+		count++
+		return testInput{
+			Right: count%3 != 0,
+			Jump:  count%7 == 0,
+		}
+	})
+	recorder.HashInterval = 10
+	recorder.Registry = testRegistry()
+
+	app := newGame(recorder, false)
+	for i := 0; i < frames; i++ {
+		// Note: Vary the frame time, so that some frames run several fixed updates and some run none
+		app.Step(time.Duration(5+i%4*5) * time.Millisecond)
+	}
+
+	rec, err := recorder.Recording()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rec
+}
+
+func TestReplayMatches(t *testing.T) {
+	rec := record(t, 50)
+	if len(rec.Frames) != 50 {
+		t.Fatalf("expected 50 frames, got %d", len(rec.Frames))
+	}
+	if len(rec.Hashes) != 6 {
+		t.Fatalf("expected a hash every 10 frames plus the final frame, got %v", rec.Hashes)
+	}
+
+	dat, err := Marshal(rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Unmarshal[testInput](dat)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	player := NewPlayer(loaded)
+	player.Registry = testRegistry()
+	app := newGame(player, false)
+	err = player.Run(app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !player.Done() {
+		t.Errorf("expected every frame to be played")
+	}
+}
+
+func TestReplayReportsDivergence(t *testing.T) {
+	rec := record(t, 50)
+
+	player := NewPlayer(rec)
+	player.Registry = testRegistry()
+	app := newGame(player, true)
+	err := player.Run(app)
+
+	var div *Divergence
+	if !errors.As(err, &div) {
+		t.Fatalf("expected divergence, got %v", err)
+	}
+	if div.Frame != 30 {
+		t.Errorf("expected divergence at frame 30, got %d", div.Frame)
+	}
+	if player.Done() {
+		t.Errorf("expected playback to stop at the divergence")
+	}
+}
+
+func TestReplaySeed(t *testing.T) {
+	rec := record(t, 50)
+	rec.Seed++
+
+	player := NewPlayer(rec)
+	player.Registry = testRegistry()
+	app := newGame(player, false)
+	err := player.Run(app)
+	if err == nil {
+		t.Errorf("expected a different seed to diverge")
+	}
+}
@@ -0,0 +1,198 @@
+package replicate
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/unitoftime/ecs"
+	"github.com/unitoftime/flow"
+	"github.com/unitoftime/flow/asset/serde"
+	"github.com/unitoftime/flow/snapshot"
+)
+
+// Client applies the server's updates to its world. Server entities are spawned as new local entities, and the entity ids stored inside of components are mapped to the local ids (see snapshot.IdRemapper). The client's registry must match the server's registry.
+type Client struct {
+	History int // The number of past ticks to keep. Defaults to DefaultHistory
+
+	registry  *snapshot.Registry
+	transport Transport
+	applied   uint64
+	history   map[uint64]state
+	local     map[ecs.Id]ecs.Id // Maps server ids to local ids
+}
+
+func NewClient(registry *snapshot.Registry, transport Transport) *Client {
+	return &Client{
+		History:   DefaultHistory,
+		registry:  registry,
+		transport: transport,
+		history:   make(map[uint64]state),
+		local:     make(map[ecs.Id]ecs.Id),
+	}
+}
+
+// Returns the last server tick that was applied
+func (c *Client) Tick() uint64 {
+	return c.applied
+}
+
+// Returns the local id of a server entity
+func (c *Client) LocalId(serverId ecs.Id) (ecs.Id, bool) {
+	id, ok := c.local[serverId]
+	return id, ok
+}
+
+// Applies every update that the server has sent
+func (c *Client) Update(world *ecs.World) error {
+	for {
+		dat, ok, err := c.transport.Recv()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+
+		update, err := serde.Unmarshal[Update](dat)
+		if err != nil {
+			return err
+		}
+		err = c.apply(world, update)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (c *Client) apply(world *ecs.World, update Update) error {
+	if update.Tick <= c.applied {
+		return nil // Stale: We've already applied a newer update
+	}
+
+	var base state
+	if update.Base != 0 {
+		var ok bool
+		base, ok = c.history[update.Base]
+		if !ok {
+			return nil // Note: We no longer have the base, so skip this update. The server will send a newer base once it sees our acks.
+		}
+	}
+
+	next, err := patch(base, update)
+	if err != nil {
+		return err
+	}
+	err = c.write(world, c.history[c.applied], next)
+	if err != nil {
+		return err
+	}
+
+	c.applied = update.Tick
+	c.history[update.Tick] = next
+	c.prune(update.Base)
+
+	dat, err := serde.Marshal(Ack{update.Tick})
+	if err != nil {
+		return err
+	}
+	return c.transport.Send(dat)
+}
+
+// Writes the changes between the previously applied state and the next state into the world
+func (c *Client) write(world *ecs.World, prev, next state) error {
+	for _, serverId := range slices.Sorted(maps.Keys(prev)) {
+		_, ok := next[serverId]
+		if ok {
+			continue
+		}
+		id, ok := c.local[serverId]
+		if ok {
+			ecs.Delete(world, id)
+			delete(c.local, serverId)
+		}
+	}
+
+	for _, serverId := range slices.Sorted(maps.Keys(next)) {
+		id := c.localId(world, serverId)
+		prevComps := prev[serverId]
+		nextComps := next[serverId]
+
+		comps := make([]ecs.Component, 0, len(nextComps))
+		for _, name := range slices.Sorted(maps.Keys(nextComps)) {
+			dat := nextComps[name]
+			prevDat, ok := prevComps[name]
+			if ok && bytes.Equal(dat, prevDat) {
+				continue
+			}
+
+			comp, err := c.registry.Decode(name, dat, func(serverId ecs.Id) ecs.Id {
+				return c.localId(world, serverId)
+			})
+			if err != nil {
+				return fmt.Errorf("replicate: failed to decode %s: %w", name, err)
+			}
+			comps = append(comps, comp)
+		}
+		if len(comps) > 0 {
+			world.Write(id, comps...)
+		}
+
+		for _, name := range slices.Sorted(maps.Keys(prevComps)) {
+			_, ok := nextComps[name]
+			if ok {
+				continue
+			}
+			err := c.registry.DeleteComponent(world, id, name)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Returns the local id for a server id, allocating a new one if this is the first time the server id has been seen
+func (c *Client) localId(world *ecs.World, serverId ecs.Id) ecs.Id {
+	if serverId == ecs.InvalidEntity {
+		return ecs.InvalidEntity
+	}
+	id, ok := c.local[serverId]
+	if !ok {
+		id = world.NewId()
+		c.local[serverId] = id
+	}
+	return id
+}
+
+// Drops the ticks that the server will no longer use as a base. The server's bases only move forward.
+func (c *Client) prune(base uint64) {
+	history := c.History
+	if history <= 0 {
+		history = DefaultHistory
+	}
+
+	for tick := range c.history {
+		tooOld := c.applied-tick >= uint64(history)
+		if (tick < base || tooOld) && tick != c.applied {
+			delete(c.history, tick)
+		}
+	}
+}
+
+// Initialize lets the client be added to a flow.App. It adds a system which applies the server's updates before the fixed updates run.
+func (c *Client) Initialize(world *ecs.World) {
+	app := flow.MustApp(world)
+
+	app.AddSystems(ecs.StagePreFixedUpdate, ecs.System{
+		Name: "replicate.ClientSystem",
+		Func: func(dt time.Duration) {
+			err := c.Update(world)
+			if err != nil {
+				fmt.Println("Error Replicating:", err)
+			}
+		},
+	})
+}
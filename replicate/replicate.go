@@ -0,0 +1,220 @@
+package replicate
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"maps"
+	"slices"
+
+	"github.com/unitoftime/ecs"
+	"github.com/unitoftime/flow/snapshot"
+)
+
+// The number of past ticks that servers and clients keep around to delta against. If a client falls further behind than this, it is sent a full update.
+const DefaultHistory = 64
+
+// Update is sent from the server to a client. It holds the changes to the replicated components since the base tick. A base of 0 means that the update is relative to an empty world.
+type Update struct {
+	Tick     uint64
+	Base     uint64
+	Entities []EntityDelta
+	Removed  []ecs.Id // Entities that no longer exist (or no longer have any replicated components)
+}
+
+type EntityDelta struct {
+	Id         ecs.Id // The server's id for the entity
+	Components []ComponentDelta
+	Removed    []string // The names of components that were removed from the entity
+}
+
+type ComponentDelta struct {
+	Name  string
+	Delta bool   // If true, Data holds a delta against the component's data at the base tick. Else it holds the full component data.
+	Data  []byte // The serde-encoded component, or the delta
+}
+
+// Ack is sent from a client to the server to acknowledge that it has applied the update for a tick
+type Ack struct {
+	Tick uint64
+}
+
+//--------------------------------------------------------------------------------
+// - State
+//--------------------------------------------------------------------------------
+
+// The encoded replicated components of every entity at a tick, keyed by server id and then by component name
+type state map[ecs.Id]map[string][]byte
+
+func captureState(registry *snapshot.Registry, world *ecs.World) (state, error) {
+	snap, err := registry.Capture(world)
+	if err != nil {
+		return nil, err
+	}
+	st := make(state, len(snap.Entities))
+	for _, ent := range snap.Entities {
+		comps := make(map[string][]byte, len(ent.Components))
+		for _, c := range ent.Components {
+			comps[c.Name] = c.Data
+		}
+		st[ent.Id] = comps
+	}
+	return st, nil
+}
+
+// Builds the update that turns the base state into the next state
+func diff(tick, baseTick uint64, base, next state) Update {
+	update := Update{
+		Tick: tick,
+		Base: baseTick,
+	}
+
+	for _, id := range slices.Sorted(maps.Keys(next)) {
+		nextComps := next[id]
+		baseComps := base[id]
+
+		ent := EntityDelta{Id: id}
+		for _, name := range slices.Sorted(maps.Keys(nextComps)) {
+			dat := nextComps[name]
+			baseDat, ok := baseComps[name]
+			if ok && bytes.Equal(dat, baseDat) {
+				continue // Unchanged
+			}
+			ent.Components = append(ent.Components, encodeComponent(name, baseDat, dat))
+		}
+		for _, name := range slices.Sorted(maps.Keys(baseComps)) {
+			_, ok := nextComps[name]
+			if !ok {
+				ent.Removed = append(ent.Removed, name)
+			}
+		}
+
+		if len(ent.Components) > 0 || len(ent.Removed) > 0 {
+			update.Entities = append(update.Entities, ent)
+		}
+	}
+
+	for _, id := range slices.Sorted(maps.Keys(base)) {
+		_, ok := next[id]
+		if !ok {
+			update.Removed = append(update.Removed, id)
+		}
+	}
+	return update
+}
+
+// Applies the update to the base state, and returns the next state. The base state is not modified.
+func patch(base state, update Update) (state, error) {
+	next := make(state, len(base))
+	for id, comps := range base {
+		next[id] = maps.Clone(comps)
+	}
+
+	for _, id := range update.Removed {
+		delete(next, id)
+	}
+
+	for _, ent := range update.Entities {
+		comps, ok := next[ent.Id]
+		if !ok {
+			comps = make(map[string][]byte, len(ent.Components))
+			next[ent.Id] = comps
+		}
+
+		for _, name := range ent.Removed {
+			delete(comps, name)
+		}
+		for _, c := range ent.Components {
+			if !c.Delta {
+				comps[c.Name] = c.Data
+				continue
+			}
+
+			baseDat, ok := comps[c.Name]
+			if !ok {
+				return nil, errors.New("replicate: delta for a component that isn't in the base state: " + c.Name)
+			}
+			dat, err := applyDelta(baseDat, c.Data)
+			if err != nil {
+				return nil, err
+			}
+			comps[c.Name] = dat
+		}
+	}
+	return next, nil
+}
+
+//--------------------------------------------------------------------------------
+// - Delta Compression
+//--------------------------------------------------------------------------------
+
+func encodeComponent(name string, base, dat []byte) ComponentDelta {
+	// Note: Deltas only work when the encoded size is unchanged. serde encodes numbers with a variable length, so large changes may send the full data
+	if base == nil || len(base) != len(dat) {
+		return ComponentDelta{name, false, dat}
+	}
+
+	delta := encodeDelta(base, dat)
+	if len(delta) >= len(dat) {
+		return ComponentDelta{name, false, dat}
+	}
+	return ComponentDelta{name, true, delta}
+}
+
+// The delta is the xor of the two buffers, with the runs of zeros (ie the unchanged bytes) removed. It is encoded as a list of: [varint skip][varint length][length bytes of xor data]
+func encodeDelta(base, dat []byte) []byte {
+	ret := make([]byte, 0, len(dat))
+	i := 0
+	for i < len(dat) {
+		start := i
+		for start < len(dat) && base[start] == dat[start] {
+			start++
+		}
+		if start >= len(dat) {
+			break
+		}
+		end := start
+		for end < len(dat) && base[end] != dat[end] {
+			end++
+		}
+
+		ret = binary.AppendUvarint(ret, uint64(start-i))
+		ret = binary.AppendUvarint(ret, uint64(end-start))
+		for j := start; j < end; j++ {
+			ret = append(ret, base[j]^dat[j])
+		}
+		i = end
+	}
+	return ret
+}
+
+var errBadDelta = errors.New("replicate: malformed delta")
+
+func applyDelta(base, delta []byte) ([]byte, error) {
+	dat := slices.Clone(base)
+	i := 0
+	for len(delta) > 0 {
+		skip, n := binary.Uvarint(delta)
+		if n <= 0 {
+			return nil, errBadDelta
+		}
+		delta = delta[n:]
+		length, n := binary.Uvarint(delta)
+		if n <= 0 {
+			return nil, errBadDelta
+		}
+		delta = delta[n:]
+
+		remaining := uint64(len(dat) - i)
+		if skip > remaining || length > remaining-skip || length > uint64(len(delta)) {
+			return nil, errBadDelta
+		}
+		i += int(skip)
+		for j := 0; j < int(length); j++ {
+			dat[i+j] ^= delta[j]
+		}
+		i += int(length)
+		delta = delta[length:]
+	}
+	return dat, nil
+}
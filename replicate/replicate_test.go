@@ -0,0 +1,205 @@
+package replicate
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/unitoftime/ecs"
+	"github.com/unitoftime/flow/asset/serde"
+	"github.com/unitoftime/flow/snapshot"
+)
+
+type position struct {
+	X, Y int
+	Name string
+}
+
+type target struct {
+	Id ecs.Id
+}
+
+func (t *target) RemapIds(remap func(ecs.Id) ecs.Id) {
+	t.Id = remap(t.Id)
+}
+
+type serverOnly struct {
+	Secret int
+}
+
+func testRegistry() *snapshot.Registry {
+	r := snapshot.NewRegistry()
+	snapshot.RegisterTo[position](r)
+	snapshot.RegisterTo[target](r)
+	return r
+}
+
+// Records every message that is sent, and can drop messages
+type recordingTransport struct {
+	Transport
+	sent [][]byte
+	drop bool
+}
+
+func (t *recordingTransport) Send(dat []byte) error {
+	t.sent = append(t.sent, dat)
+	if t.drop {
+		return nil
+	}
+	return t.Transport.Send(dat)
+}
+
+func (t *recordingTransport) last(tb testing.TB) Update {
+	tb.Helper()
+	update, err := serde.Unmarshal[Update](t.sent[len(t.sent)-1])
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return update
+}
+
+func step(t *testing.T, server *Server, client *Client, serverWorld, clientWorld *ecs.World) {
+	t.Helper()
+	err := server.Update(serverWorld)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.Update(clientWorld)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDelta(t *testing.T) {
+	base := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	dat := []byte{1, 2, 9, 4, 5, 6, 7, 0, 0, 10}
+	delta := encodeDelta(base, dat)
+	if len(delta) >= len(dat) {
+		t.Errorf("expected delta to be smaller than the data: %v", delta)
+	}
+	out, err := applyDelta(base, delta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, dat) {
+		t.Errorf("expected %v, got %v", dat, out)
+	}
+
+	_, err = applyDelta(base, []byte{200, 1, 5})
+	if err == nil {
+		t.Errorf("expected malformed delta error")
+	}
+}
+
+func TestReplicate(t *testing.T) {
+	serverEnd, clientEnd := NewLoopback()
+	sent := &recordingTransport{Transport: serverEnd}
+
+	registry := testRegistry()
+	server := NewServer(registry)
+	server.AddClient(sent)
+	client := NewClient(registry, clientEnd)
+
+	serverWorld := ecs.NewWorld()
+	clientWorld := ecs.NewWorld()
+	clientWorld.NewId() // Note: Offset the client's ids so that they don't line up with the server's
+
+	player := serverWorld.NewId()
+	enemy := serverWorld.NewId()
+	serverWorld.Write(player, ecs.C(position{1, 2, "player"}), ecs.C(serverOnly{5}))
+	serverWorld.Write(enemy, ecs.C(position{10, 20, "enemy"}), ecs.C(target{player}))
+
+	step(t, server, client, serverWorld, clientWorld)
+
+	localPlayer, ok := client.LocalId(player)
+	if !ok {
+		t.Fatalf("expected player to be replicated")
+	}
+	localEnemy, _ := client.LocalId(enemy)
+	pos, _ := ecs.Read[position](clientWorld, localPlayer)
+	if pos != (position{1, 2, "player"}) {
+		t.Errorf("expected player position, got %v", pos)
+	}
+	tgt, _ := ecs.Read[target](clientWorld, localEnemy)
+	if tgt.Id != localPlayer {
+		t.Errorf("expected target to be remapped to %d, got %d", localPlayer, tgt.Id)
+	}
+	_, ok = ecs.Read[serverOnly](clientWorld, localPlayer)
+	if ok {
+		t.Errorf("expected unregistered components to not be replicated")
+	}
+
+	// Nothing changed, so nothing is sent
+	step(t, server, client, serverWorld, clientWorld)
+	if update := sent.last(t); update.Base == 0 || len(update.Entities) != 0 {
+		t.Errorf("expected an empty delta update, got %+v", update)
+	}
+
+	// Only the changed component is sent, as a delta
+	serverWorld.Write(player, ecs.C(position{1, 3, "player"}))
+	step(t, server, client, serverWorld, clientWorld)
+	update := sent.last(t)
+	if len(update.Entities) != 1 || len(update.Entities[0].Components) != 1 || !update.Entities[0].Components[0].Delta {
+		t.Errorf("expected one delta compressed component, got %+v", update)
+	}
+	pos, _ = ecs.Read[position](clientWorld, localPlayer)
+	if pos != (position{1, 3, "player"}) {
+		t.Errorf("expected player to move, got %v", pos)
+	}
+
+	// Removals
+	ecs.DeleteComponent(serverWorld, enemy, ecs.C(target{}))
+	ecs.Delete(serverWorld, player)
+	step(t, server, client, serverWorld, clientWorld)
+	if clientWorld.Exists(localPlayer) {
+		t.Errorf("expected player to be despawned")
+	}
+	_, ok = ecs.Read[target](clientWorld, localEnemy)
+	if ok {
+		t.Errorf("expected target to be removed")
+	}
+	pos, _ = ecs.Read[position](clientWorld, localEnemy)
+	if pos != (position{10, 20, "enemy"}) {
+		t.Errorf("expected enemy to remain, got %v", pos)
+	}
+}
+
+func TestReplicateDroppedUpdates(t *testing.T) {
+	serverEnd, clientEnd := NewLoopback()
+	lossy := &recordingTransport{Transport: serverEnd}
+
+	registry := testRegistry()
+	server := NewServer(registry)
+	conn := server.AddClient(lossy)
+	client := NewClient(registry, clientEnd)
+
+	serverWorld := ecs.NewWorld()
+	clientWorld := ecs.NewWorld()
+	id := serverWorld.NewId()
+
+	for i := 0; i < 20; i++ {
+		serverWorld.Write(id, ecs.C(position{i, 0, "mover"}))
+		lossy.drop = i%3 != 0
+		step(t, server, client, serverWorld, clientWorld)
+
+		// Every update must be relative to a tick the client acknowledged
+		update := lossy.last(t)
+		if update.Base != 0 && update.Base > client.Tick() {
+			t.Fatalf("update %d used unacknowledged base %d", update.Tick, update.Base)
+		}
+	}
+
+	// Deliver the final state
+	lossy.drop = false
+	step(t, server, client, serverWorld, clientWorld)
+	localId, _ := client.LocalId(id)
+	pos, _ := ecs.Read[position](clientWorld, localId)
+	if pos.X != 19 {
+		t.Errorf("expected client to catch up, got %v", pos)
+	}
+	if conn.Acked() == 0 {
+		t.Errorf("expected the server to receive acks")
+	}
+	if len(server.history) > DefaultHistory {
+		t.Errorf("expected server history to be pruned, got %d", len(server.history))
+	}
+}
@@ -0,0 +1,160 @@
+package replicate
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/unitoftime/ecs"
+	"github.com/unitoftime/flow"
+	"github.com/unitoftime/flow/asset/serde"
+	"github.com/unitoftime/flow/snapshot"
+)
+
+// Server replicates the components in its registry to every connected client. Every update the server captures the replicated components, then sends each client the components that changed since the last tick that the client acknowledged. Changed components are delta compressed against the acknowledged tick's data.
+// The registry decides which components are replicated, so it is usually separate from snapshot.DefaultRegistry:
+//
+//	replicated := snapshot.NewRegistry()
+//	snapshot.RegisterTo[transform.Local](replicated)
+//	server := replicate.NewServer(replicated)
+//	server.AddClient(transport)
+type Server struct {
+	History int // The number of past ticks to keep. Defaults to DefaultHistory
+
+	registry *snapshot.Registry
+	tick     uint64
+	history  map[uint64]state
+	clients  []*Conn
+}
+
+// Conn is the server's connection to a single client
+type Conn struct {
+	transport Transport
+	acked     uint64
+}
+
+// Returns the latest tick that the client has acknowledged, or 0 if it hasn't acknowledged any
+func (c *Conn) Acked() uint64 {
+	return c.acked
+}
+
+func NewServer(registry *snapshot.Registry) *Server {
+	return &Server{
+		History:  DefaultHistory,
+		registry: registry,
+		history:  make(map[uint64]state),
+	}
+}
+
+// Returns the last tick that was sent
+func (s *Server) Tick() uint64 {
+	return s.tick
+}
+
+// Adds a client. The client is sent a full update on the next tick.
+func (s *Server) AddClient(transport Transport) *Conn {
+	conn := &Conn{transport: transport}
+	s.clients = append(s.clients, conn)
+	return conn
+}
+
+func (s *Server) RemoveClient(conn *Conn) {
+	s.clients = slices.DeleteFunc(s.clients, func(c *Conn) bool {
+		return c == conn
+	})
+}
+
+// Reads the acks from every client, then captures the world and sends every client an update
+func (s *Server) Update(world *ecs.World) error {
+	var errs []error
+	for _, conn := range s.clients {
+		err := s.receiveAcks(conn)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	next, err := captureState(s.registry, world)
+	if err != nil {
+		return err
+	}
+	s.tick++
+	s.history[s.tick] = next
+
+	for _, conn := range s.clients {
+		baseTick := conn.acked
+		base, ok := s.history[baseTick]
+		if !ok {
+			baseTick = 0 // Note: The acked tick is too old, or the client hasn't acked anything yet. So send a full update
+		}
+
+		dat, err := serde.Marshal(diff(s.tick, baseTick, base, next))
+		if err != nil {
+			return err
+		}
+		err = conn.transport.Send(dat)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	s.prune()
+	return errors.Join(errs...)
+}
+
+func (s *Server) receiveAcks(conn *Conn) error {
+	for {
+		dat, ok, err := conn.transport.Recv()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+
+		ack, err := serde.Unmarshal[Ack](dat)
+		if err != nil {
+			return err
+		}
+		// Note: Acks can arrive out of order, so only move forward
+		if ack.Tick > conn.acked && ack.Tick <= s.tick {
+			conn.acked = ack.Tick
+		}
+	}
+}
+
+// Drops the ticks that no client can use as a base anymore
+func (s *Server) prune() {
+	history := s.History
+	if history <= 0 {
+		history = DefaultHistory
+	}
+
+	minAcked := s.tick
+	for _, conn := range s.clients {
+		minAcked = min(minAcked, conn.acked)
+	}
+
+	for tick := range s.history {
+		tooOld := s.tick-tick >= uint64(history)
+		if tick < minAcked || tooOld {
+			delete(s.history, tick)
+		}
+	}
+}
+
+// Initialize lets the server be added to a flow.App. It adds a system which sends updates after the fixed updates have run.
+func (s *Server) Initialize(world *ecs.World) {
+	app := flow.MustApp(world)
+
+	app.AddSystems(ecs.StagePostFixedUpdate, ecs.System{
+		Name: "replicate.ServerSystem",
+		Func: func(dt time.Duration) {
+			err := s.Update(world)
+			if err != nil {
+				fmt.Println("Error Replicating:", err)
+			}
+		},
+	})
+}
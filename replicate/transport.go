@@ -0,0 +1,122 @@
+package replicate
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Transport sends and receives whole messages between the server and one client. Implementations may drop or reorder messages (ie UDP), the server and client handle that by delta compressing against acknowledged ticks.
+type Transport interface {
+	// Sends a message to the other side
+	Send(dat []byte) error
+
+	// Returns the next received message. Returns false if there are no messages waiting. This must not block.
+	Recv() ([]byte, bool, error)
+}
+
+var ErrClosed = errors.New("replicate: transport closed")
+
+// Loopback is an in-memory transport. Messages sent on one end are received on the other end. It is safe to use from multiple goroutines.
+type Loopback struct {
+	pipe *pipe
+	side int
+}
+
+type pipe struct {
+	mu     sync.Mutex
+	queues [2][][]byte // The messages waiting to be received by each side
+	closed bool
+}
+
+// Returns the two connected ends of an in-memory transport
+func NewLoopback() (*Loopback, *Loopback) {
+	p := &pipe{}
+	return &Loopback{p, 0}, &Loopback{p, 1}
+}
+
+func (l *Loopback) Send(dat []byte) error {
+	l.pipe.mu.Lock()
+	defer l.pipe.mu.Unlock()
+	if l.pipe.closed {
+		return ErrClosed
+	}
+	other := 1 - l.side
+	l.pipe.queues[other] = append(l.pipe.queues[other], slices.Clone(dat))
+	return nil
+}
+
+func (l *Loopback) Recv() ([]byte, bool, error) {
+	l.pipe.mu.Lock()
+	defer l.pipe.mu.Unlock()
+	queue := l.pipe.queues[l.side]
+	if len(queue) == 0 {
+		if l.pipe.closed {
+			return nil, false, ErrClosed
+		}
+		return nil, false, nil
+	}
+	l.pipe.queues[l.side] = queue[1:]
+	return queue[0], true, nil
+}
+
+// Closes both ends of the loopback. Messages that were already sent can still be received.
+func (l *Loopback) Close() {
+	l.pipe.mu.Lock()
+	defer l.pipe.mu.Unlock()
+	l.pipe.closed = true
+}
+
+// Latency wraps a transport and holds every received message for a delay before returning it. This simulates network latency, which is useful for testing netcode locally.
+type Latency struct {
+	Transport Transport
+	Delay     time.Duration
+	Now       func() time.Time // Optional: The clock used to time the delay. Defaults to time.Now, tests can provide a fake clock.
+
+	pending []delayedMessage
+}
+
+type delayedMessage struct {
+	arrival time.Time
+	dat     []byte
+}
+
+func NewLatency(transport Transport, delay time.Duration) *Latency {
+	return &Latency{
+		Transport: transport,
+		Delay:     delay,
+	}
+}
+
+func (l *Latency) Send(dat []byte) error {
+	return l.Transport.Send(dat)
+}
+
+func (l *Latency) Recv() ([]byte, bool, error) {
+	now := time.Now()
+	if l.Now != nil {
+		now = l.Now()
+	}
+
+	for {
+		dat, ok, err := l.Transport.Recv()
+		if err != nil {
+			if len(l.pending) == 0 {
+				return nil, false, err
+			}
+			break // Note: Deliver the messages that are still in flight first
+		}
+		if !ok {
+			break
+		}
+		l.pending = append(l.pending, delayedMessage{now.Add(l.Delay), dat})
+	}
+
+	if len(l.pending) == 0 || l.pending[0].arrival.After(now) {
+		return nil, false, nil
+	}
+	msg := l.pending[0]
+	l.pending = l.pending[1:]
+	return msg.dat, true, nil
+}
@@ -0,0 +1,31 @@
+package flow
+
+import (
+	"math/rand"
+)
+
+// Rng is a resource which holds the app's seeded random number generator. Systems should use it rather than the global math/rand functions, so that a session can be replayed deterministically by reusing the seed.
+//
+// Systems can access it by injecting the resource: `func(dt time.Duration, rng *flow.Rng)`
+type Rng struct {
+	*rand.Rand
+	seed int64
+}
+
+func NewRng(seed int64) *Rng {
+	return &Rng{
+		Rand: rand.New(rand.NewSource(seed)),
+		seed: seed,
+	}
+}
+
+// Returns the seed that the generator was last seeded with
+func (r *Rng) Seed() int64 {
+	return r.seed
+}
+
+// Reseeds the generator. This resets the generator to the start of the sequence for the seed.
+func (r *Rng) SetSeed(seed int64) {
+	r.seed = seed
+	r.Rand.Seed(seed)
+}
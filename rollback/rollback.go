@@ -0,0 +1,462 @@
+package rollback
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/unitoftime/ecs"
+	"github.com/unitoftime/flow"
+	"github.com/unitoftime/flow/asset/serde"
+	"github.com/unitoftime/flow/ds"
+	"github.com/unitoftime/flow/replicate"
+)
+
+// The default number of frames that a session can predict ahead of the remote players
+const DefaultMaxPrediction = 8
+
+// The most inputs that are sent in a single message
+const maxInputsPerMessage = 64
+
+// Inputs is a resource which holds the input of every player for the frame that is being simulated. Remote inputs that haven't arrived yet are predicted by repeating the player's last known input.
+//
+// Simulation systems can access it by injecting the resource: `func(dt time.Duration, inputs *rollback.Inputs[MyInput])`
+type Inputs[I comparable] struct {
+	Frame   uint64
+	Players []I
+}
+
+// Session runs a peer to peer rollback simulation (GGPO-style). Every fixed update the session:
+// 1. Receives the remote players' inputs. If an input doesn't match what was predicted, the world is restored to that frame and the frames since are simulated again with the corrected inputs.
+// 2. Polls the local player's input and sends it to every peer.
+// 3. Saves the registered components, then runs the simulation systems for the next frame.
+//
+// Simulation systems must be added to the session instead of the app, and they must only depend on registered components, the Inputs resource, and the flow.Rng resource (which is reseeded every frame). Registered components are saved by value, so they shouldn't hold pointers, slices or maps which are modified in place.
+// Note: Entities that are spawned during a simulated frame are given new ids when the frame is simulated again, so ids aren't stable across peers or rollbacks
+//
+//	session := rollback.NewSession(2, localPlayer, pollInput)
+//	session.AddPeer(remotePlayer, transport)
+//	rollback.Register[Position](session)
+//	session.AddSystems(MoveSystem)
+//	app.AddPlugin(session)
+type Session[I comparable] struct {
+	InputDelay    int   // The number of frames that local input is delayed by. This hides latency, at the cost of responsiveness
+	MaxPrediction int   // The most frames that the session can run ahead of the latest confirmed remote input. Defaults to DefaultMaxPrediction
+	Seed          int64 // Every peer must use the same seed
+
+	poll       func(world *ecs.World) I
+	local      int
+	players    []*playerInputs[I]
+	peers      []*peer
+	components []component
+	builders   []ecs.SystemBuilder
+	systems    []ecs.System
+
+	world     *ecs.World
+	inputs    *Inputs[I]
+	rng       *flow.Rng
+	frame     uint64 // The next frame to simulate
+	states    *ds.RingBuffer[savedFrame[I]]
+	rollbacks int
+	stalls    int
+}
+
+// The state of the world before a frame was simulated, and the inputs that the frame was simulated with
+type savedFrame[I comparable] struct {
+	frame  uint64
+	state  []componentState
+	inputs []I
+}
+
+type peer struct {
+	player    int
+	transport replicate.Transport
+	acked     uint64 // The next local frame that the peer needs our input for
+}
+
+type message[I comparable] struct {
+	Player int
+	Start  uint64 // The frame of the first input
+	Inputs []I
+	Ack    uint64 // The next frame that the sender needs the receiver's input for
+}
+
+// Creates a session for the provided number of players. poll is called once per simulated frame to read the local player's input.
+func NewSession[I comparable](players int, localPlayer int, poll func(world *ecs.World) I) *Session[I] {
+	s := &Session[I]{
+		MaxPrediction: DefaultMaxPrediction,
+		poll:          poll,
+		local:         localPlayer,
+		players:       make([]*playerInputs[I], players),
+		inputs: &Inputs[I]{
+			Players: make([]I, players),
+		},
+	}
+	for i := range s.players {
+		s.players[i] = newPlayerInputs[I]()
+	}
+	return s
+}
+
+// Adds a remote player, whose inputs are sent and received over the transport
+func (s *Session[I]) AddPeer(player int, transport replicate.Transport) {
+	s.peers = append(s.peers, &peer{
+		player:    player,
+		transport: transport,
+	})
+}
+
+// Adds systems to the simulation. They run every time a frame is simulated, including when frames are simulated again after a rollback.
+func (s *Session[I]) AddSystems(systems ...ecs.SystemBuilder) {
+	if s.world == nil {
+		s.builders = append(s.builders, systems...)
+		return
+	}
+	for _, sys := range systems {
+		s.systems = append(s.systems, sys.Build(s.world))
+	}
+}
+
+// Registers a component that is saved every frame and restored on rollback
+func Register[T any, I comparable](s *Session[I]) {
+	s.components = append(s.components, typedComponent[T]{})
+}
+
+// Returns the next frame that will be simulated
+func (s *Session[I]) Frame() uint64 {
+	return s.frame
+}
+
+// Returns the first frame that hasn't been confirmed for every player. Frames before this will never be rolled back.
+func (s *Session[I]) ConfirmedFrame() uint64 {
+	confirmed := s.players[s.local].next
+	for _, p := range s.peers {
+		confirmed = min(confirmed, s.players[p.player].next)
+	}
+	return confirmed
+}
+
+// Returns the number of times the session has rolled back
+func (s *Session[I]) Rollbacks() int {
+	return s.rollbacks
+}
+
+// Returns the number of ticks where the session waited for remote inputs, because it was too far ahead
+func (s *Session[I]) Stalls() int {
+	return s.stalls
+}
+
+func (s *Session[I]) maxPrediction() int {
+	if s.MaxPrediction <= 0 {
+		return DefaultMaxPrediction
+	}
+	return s.MaxPrediction
+}
+
+func (s *Session[I]) Initialize(world *ecs.World) {
+	app := flow.MustApp(world)
+	s.rng = ecs.GetResource[flow.Rng](world)
+	if s.rng == nil {
+		panic("rollback: session requires a flow.Rng resource, use flow.NewApp to create your world")
+	}
+
+	s.world = world
+	ecs.PutResource(world, s.inputs)
+	s.AddSystems(s.builders...)
+	s.builders = nil
+
+	// Note: The buffer holds one less than its capacity. We need the frames since the earliest unconfirmed frame, plus the frame being simulated
+	s.states = ds.NewRingBuffer[savedFrame[I]](s.maxPrediction() + 2)
+
+	var zero I
+	for i := 0; i < s.InputDelay; i++ {
+		s.players[s.local].confirm(uint64(i), zero)
+	}
+
+	app.AddSystems(ecs.StageFixedUpdate, ecs.System{
+		Name: "rollback.SessionSystem",
+		Func: func(dt time.Duration) {
+			err := s.Tick(dt)
+			if err != nil {
+				fmt.Println("Error Running Rollback Session:", err)
+			}
+		},
+	})
+}
+
+// Runs one tick of the session. This is called by the session's fixed update system.
+func (s *Session[I]) Tick(dt time.Duration) error {
+	rollbackTo, err := s.receive()
+	if err != nil {
+		return err
+	}
+	if rollbackTo < s.frame {
+		s.rollback(rollbackTo, dt)
+	}
+
+	if s.frame >= s.ConfirmedFrame()+uint64(s.maxPrediction()) {
+		// We are too far ahead of the remote players, so wait for their inputs
+		s.stalls++
+		return s.send()
+	}
+
+	input := s.poll(s.world)
+	s.players[s.local].confirm(s.frame+uint64(s.InputDelay), input)
+
+	s.simulate(s.frame, dt)
+	s.frame++
+	s.prune()
+	return s.send()
+}
+
+// Receives remote inputs. Returns the earliest frame that was mispredicted, or the current frame if every prediction was correct.
+func (s *Session[I]) receive() (uint64, error) {
+	rollbackTo := s.frame
+	for _, p := range s.peers {
+		for {
+			dat, ok, err := p.transport.Recv()
+			if err != nil {
+				return rollbackTo, err
+			}
+			if !ok {
+				break
+			}
+
+			msg, err := serde.Unmarshal[message[I]](dat)
+			if err != nil {
+				return rollbackTo, err
+			}
+			p.acked = max(p.acked, msg.Ack)
+
+			inputs := s.players[p.player]
+			for i, input := range msg.Inputs {
+				frame := msg.Start + uint64(i)
+				if !inputs.confirm(frame, input) {
+					continue
+				}
+				if frame >= s.frame {
+					continue // Not yet simulated, so nothing was predicted
+				}
+
+				saved, ok := s.find(frame)
+				if ok && saved.inputs[p.player] != input {
+					rollbackTo = min(rollbackTo, frame)
+				}
+			}
+		}
+	}
+	return rollbackTo, nil
+}
+
+// Sends every local input that each peer hasn't acknowledged yet
+func (s *Session[I]) send() error {
+	local := s.players[s.local]
+	for _, p := range s.peers {
+		start := p.acked
+		end := min(local.next, start+maxInputsPerMessage)
+
+		msg := message[I]{
+			Player: s.local,
+			Start:  start,
+			Inputs: make([]I, 0, end-start),
+			Ack:    s.players[p.player].next,
+		}
+		for frame := start; frame < end; frame++ {
+			msg.Inputs = append(msg.Inputs, local.get(frame))
+		}
+
+		dat, err := serde.Marshal(msg)
+		if err != nil {
+			return err
+		}
+		err = p.transport.Send(dat)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Restores the world to the start of the frame, then simulates every frame up to the current frame again
+func (s *Session[I]) rollback(frame uint64, dt time.Duration) {
+	saved, ok := s.find(frame)
+	if !ok {
+		panic(fmt.Sprintf("rollback: frame %d is no longer saved", frame))
+	}
+	for _, state := range saved.state {
+		state.restore(s.world)
+	}
+
+	s.rollbacks++
+	for f := frame; f < s.frame; f++ {
+		s.simulate(f, dt)
+	}
+}
+
+func (s *Session[I]) simulate(frame uint64, dt time.Duration) {
+	saved := savedFrame[I]{
+		frame:  frame,
+		state:  make([]componentState, len(s.components)),
+		inputs: make([]I, len(s.players)),
+	}
+	for i, comp := range s.components {
+		saved.state[i] = comp.save(s.world)
+	}
+	for i, p := range s.players {
+		saved.inputs[i] = p.get(frame)
+	}
+	s.store(saved)
+
+	s.inputs.Frame = frame
+	copy(s.inputs.Players, saved.inputs)
+	s.rng.SetSeed(frameSeed(s.Seed, frame))
+
+	for i := range s.systems {
+		s.systems[i].Run(dt)
+	}
+}
+
+// Mixes the frame into the seed, so that every frame draws a different sequence
+func frameSeed(seed int64, frame uint64) int64 {
+	return int64(uint64(seed) ^ (frame * 0x9E3779B97F4A7C15))
+}
+
+// Returns the index of the frame in the states buffer
+func (s *Session[I]) index(frame uint64) (int, bool) {
+	n := s.states.Len()
+	if n == 0 {
+		return 0, false
+	}
+	newest := s.states.Get(n - 1).frame
+	if frame > newest || newest-frame >= uint64(n) {
+		return 0, false
+	}
+	return n - 1 - int(newest-frame), true
+}
+
+func (s *Session[I]) find(frame uint64) (savedFrame[I], bool) {
+	idx, ok := s.index(frame)
+	if !ok {
+		return savedFrame[I]{}, false
+	}
+	return s.states.Get(idx), true
+}
+
+func (s *Session[I]) store(saved savedFrame[I]) {
+	idx, ok := s.index(saved.frame)
+	if ok {
+		s.states.Set(idx, saved)
+		return
+	}
+	s.states.Add(saved)
+}
+
+// Drops the inputs that will never be needed again
+func (s *Session[I]) prune() {
+	var cutoff uint64
+	keep := uint64(s.maxPrediction() + 2)
+	if s.frame > keep {
+		cutoff = s.frame - keep
+	}
+
+	local := cutoff
+	for _, p := range s.peers {
+		local = min(local, p.acked)
+		s.players[p.player].prune(cutoff)
+	}
+	s.players[s.local].prune(local)
+}
+
+//--------------------------------------------------------------------------------
+
+// The confirmed inputs of a single player
+type playerInputs[I comparable] struct {
+	confirmed map[uint64]I
+	next      uint64 // The first frame without a confirmed input
+}
+
+func newPlayerInputs[I comparable]() *playerInputs[I] {
+	return &playerInputs[I]{
+		confirmed: make(map[uint64]I),
+	}
+}
+
+// Confirms the input for the frame. Inputs must be confirmed in order, returns false if the frame was skipped or was already confirmed.
+func (p *playerInputs[I]) confirm(frame uint64, input I) bool {
+	if frame != p.next {
+		return false
+	}
+	p.confirmed[frame] = input
+	p.next++
+	return true
+}
+
+// Returns the input for the frame. If the frame isn't confirmed yet, then the last confirmed input is used as the prediction.
+func (p *playerInputs[I]) get(frame uint64) I {
+	if frame >= p.next {
+		if p.next == 0 {
+			var zero I
+			return zero
+		}
+		frame = p.next - 1
+	}
+	return p.confirmed[frame]
+}
+
+// Deletes the inputs before the frame. The last confirmed input is always kept for predictions.
+func (p *playerInputs[I]) prune(before uint64) {
+	for frame := range p.confirmed {
+		if frame < before && frame+1 != p.next {
+			delete(p.confirmed, frame)
+		}
+	}
+}
+
+//--------------------------------------------------------------------------------
+
+type component interface {
+	save(world *ecs.World) componentState
+}
+
+type componentState interface {
+	restore(world *ecs.World)
+}
+
+type typedComponent[T any] struct{}
+
+func (c typedComponent[T]) save(world *ecs.World) componentState {
+	state := &typedState[T]{}
+	ecs.Query1[T](world).MapId(func(id ecs.Id, val *T) {
+		state.ids = append(state.ids, id)
+		state.vals = append(state.vals, *val)
+	})
+	return state
+}
+
+type typedState[T any] struct {
+	ids  []ecs.Id
+	vals []T
+}
+
+// Removes the component from entities that didn't have it when it was saved, then writes the saved values back
+func (s *typedState[T]) restore(world *ecs.World) {
+	saved := make(map[ecs.Id]struct{}, len(s.ids))
+	for _, id := range s.ids {
+		saved[id] = struct{}{}
+	}
+
+	added := make([]ecs.Id, 0)
+	ecs.Query1[T](world).MapId(func(id ecs.Id, val *T) {
+		_, ok := saved[id]
+		if !ok {
+			added = append(added, id)
+		}
+	})
+
+	var zero T
+	for _, id := range added {
+		ecs.DeleteComponent(world, id, ecs.C(zero))
+	}
+	for i, id := range s.ids {
+		world.Write(id, ecs.C(s.vals[i]))
+	}
+}
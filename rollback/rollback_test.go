@@ -0,0 +1,163 @@
+package rollback
+
+import (
+	"testing"
+	"time"
+
+	"github.com/unitoftime/ecs"
+	"github.com/unitoftime/flow"
+	"github.com/unitoftime/flow/replicate"
+)
+
+type testInput struct {
+	Dx int
+}
+
+type body struct {
+	Player int
+	X, Y   int
+}
+
+const step = 16 * time.Millisecond
+
+// Player 0 moves on every third frame, player 1 moves two units on every fourth frame. Both stop after frame 100.
+func scriptedInput(player int) func(world *ecs.World) testInput {
+	frame := 0
+	return func(world *ecs.World) testInput {
+		defer func() { frame++ }()
+		if frame >= 100 {
+			return testInput{}
+		}
+		if player == 0 && frame%3 == 0 {
+			return testInput{1}
+		}
+		if player == 1 && frame%4 == 1 {
+			return testInput{2}
+		}
+		return testInput{}
+	}
+}
+
+func newPeer(player int, transport replicate.Transport, delay int) (*flow.App, *Session[testInput]) {
+	app := flow.NewApp()
+	app.SetFixedTimeStep(step)
+
+	session := NewSession(2, player, scriptedInput(player))
+	session.Seed = 1234
+	session.InputDelay = delay
+	session.AddPeer(1-player, transport)
+	Register[body](session)
+	session.AddSystems(ecs.NewSystem3(func(dt time.Duration, inputs *Inputs[testInput], rng *flow.Rng, query *ecs.View1[body]) {
+		query.MapId(func(id ecs.Id, b *body) {
+			b.X += inputs.Players[b.Player].Dx
+			b.Y += rng.Intn(3)
+		})
+	}))
+	app.AddPlugin(session)
+
+	app.OnStartup(func(world *ecs.World) {
+		for i := 0; i < 2; i++ {
+			id := world.NewId()
+			world.Write(id, ecs.C(body{Player: i}))
+		}
+	})
+	return app, session
+}
+
+func bodies(app *flow.App) [2]body {
+	var ret [2]body
+	ecs.Query1[body](app.World()).MapId(func(id ecs.Id, b *body) {
+		ret[b.Player] = *b
+	})
+	return ret
+}
+
+func runPeers(t *testing.T, steps int, latency time.Duration, delay int) (*Session[testInput], *Session[testInput], [2]body, [2]body) {
+	t.Helper()
+	now := time.Unix(0, 0)
+	clock := func() time.Time { return now }
+
+	endA, endB := replicate.NewLoopback()
+	transportA := replicate.NewLatency(endA, latency)
+	transportA.Now = clock
+	transportB := replicate.NewLatency(endB, latency)
+	transportB.Now = clock
+
+	appA, sessionA := newPeer(0, transportA, delay)
+	appB, sessionB := newPeer(1, transportB, delay)
+
+	for i := 0; i < steps; i++ {
+		appA.Step(step)
+		appB.Step(step)
+		now = now.Add(step)
+	}
+
+	// Let the peers settle on the same frame
+	for i := 0; i < 50 && sessionA.Frame() != sessionB.Frame(); i++ {
+		if sessionA.Frame() < sessionB.Frame() {
+			appA.Step(step)
+		} else {
+			appB.Step(step)
+		}
+		now = now.Add(step)
+	}
+	if sessionA.Frame() != sessionB.Frame() {
+		t.Fatalf("peers did not settle: %d != %d", sessionA.Frame(), sessionB.Frame())
+	}
+	return sessionA, sessionB, bodies(appA), bodies(appB)
+}
+
+func TestRollbackConverges(t *testing.T) {
+	sessionA, sessionB, a, b := runPeers(t, 200, 50*time.Millisecond, 0)
+
+	if a != b {
+		t.Errorf("peers diverged:\n%v\n%v", a, b)
+	}
+	// 34 of the first 100 frames are divisible by 3, 25 of them are 1 mod 4
+	if a[0].X != 34 || a[1].X != 50 {
+		t.Errorf("expected confirmed inputs to be applied, got %v", a)
+	}
+	if sessionA.Rollbacks() == 0 || sessionB.Rollbacks() == 0 {
+		t.Errorf("expected latency to cause rollbacks: %d %d", sessionA.Rollbacks(), sessionB.Rollbacks())
+	}
+	if sessionA.ConfirmedFrame() < 150 {
+		t.Errorf("expected inputs to be confirmed, got %d", sessionA.ConfirmedFrame())
+	}
+}
+
+func TestRollbackStallsWhenTooFarAhead(t *testing.T) {
+	// Note: 300ms is more than MaxPrediction frames of latency, so the peers must wait for each other
+	sessionA, _, a, b := runPeers(t, 1000, 300*time.Millisecond, 2)
+
+	if a != b {
+		t.Errorf("peers diverged:\n%v\n%v", a, b)
+	}
+	if a[0].X != 34 || a[1].X != 50 {
+		t.Errorf("expected confirmed inputs to be applied, got %v", a)
+	}
+	if sessionA.Stalls() == 0 {
+		t.Errorf("expected stalls")
+	}
+}
+
+func TestRestoreRemovesSpawnedEntities(t *testing.T) {
+	world := ecs.NewWorld()
+	id := world.NewId()
+	world.Write(id, ecs.C(body{X: 1}))
+
+	comp := typedComponent[body]{}
+	saved := comp.save(world)
+
+	world.Write(id, ecs.C(body{X: 5}))
+	spawned := world.NewId()
+	world.Write(spawned, ecs.C(body{X: 9}))
+
+	saved.restore(world)
+	b, _ := ecs.Read[body](world, id)
+	if b.X != 1 {
+		t.Errorf("expected saved value, got %v", b)
+	}
+	if world.Exists(spawned) {
+		t.Errorf("expected entity spawned after the save to be removed")
+	}
+}
@@ -0,0 +1,15 @@
+package scene
+
+import (
+	"github.com/unitoftime/ecs"
+)
+
+var InstanceComp = ecs.NewComp[Instance]()
+
+func (c Instance) CompId() ecs.CompId {
+	return InstanceComp.CompId()
+}
+
+func (c Instance) CompWrite(w ecs.W) {
+	InstanceComp.WriteVal(w, c)
+}
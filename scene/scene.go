@@ -0,0 +1,241 @@
+package scene
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"github.com/unitoftime/ecs"
+	"gopkg.in/yaml.v3"
+
+	"github.com/unitoftime/flow/asset"
+	"github.com/unitoftime/flow/transform"
+)
+
+//go:generate go run ../../cod/cmd/cod
+
+func init() {
+	RegisterDefault(transform.Local{transform.Default()})
+}
+
+// Scene describes a set of entities, their components, and their child heirarchy. Scenes are written in YAML (or JSON, which is a subset of YAML) and loaded with the asset server:
+//
+//	entities:
+//	  - name: Goblin
+//	    prefab: assets/goblin.yaml # Optional: Spawn this entity from a prefab
+//	    components:
+//	      transform.Local:
+//	        Pos: {X: 100, Y: 50}
+//	    children:
+//	      - name: Sword
+//	        components:
+//	          transform.Local:
+//	            Pos: {X: 8}
+//
+// Components are keyed by their registered name. Their fields are decoded the same way as encoding/json, so field names are matched case insensitively and embedded structs are flattened.
+//
+// A prefab is just another scene with exactly one top level entity. When an entity references a prefab, the prefab's root entity is used as its base. The referencing entity's components are merged over the prefab's components field by field, and its children are added after the prefab's children. Prefabs can reference other prefabs.
+type Scene struct {
+	Entities []Entity `yaml:"entities"`
+}
+
+type Entity struct {
+	Name       string         `yaml:"name,omitempty"`
+	Prefab     string         `yaml:"prefab,omitempty"`
+	Components map[string]any `yaml:"components,omitempty"`
+	Children   []Entity       `yaml:"children,omitempty"`
+
+	prefab *asset.Handle[Scene] // Set by the loader if Prefab is set
+}
+
+// Returns the scene's entity name, or a placeholder if it doesn't have one. Used for error messages
+func (e *Entity) label() string {
+	if e.Name != "" {
+		return e.Name
+	}
+	if e.Prefab != "" {
+		return e.Prefab
+	}
+	return "<unnamed>"
+}
+
+// Loader loads scenes through the asset server. Nested prefabs are loaded with the same server.
+type Loader struct {
+	Registry *Registry // Optional: The registry used to validate components. Defaults to DefaultRegistry
+}
+
+func (l Loader) Ext() []string {
+	return []string{".scene.yaml", ".scene.yml", ".scene.json", ".prefab.yaml", ".prefab.yml", ".prefab.json"}
+}
+
+func (l Loader) Load(server *asset.Server, data []byte) (*Scene, error) {
+	scene, err := Unmarshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	registry := l.Registry
+	if registry == nil {
+		registry = DefaultRegistry
+	}
+	err = registry.validate(scene.Entities)
+	if err != nil {
+		return nil, err
+	}
+
+	loadPrefabs(server, scene.Entities)
+	return scene, nil
+}
+
+func (l Loader) Store(server *asset.Server, scene *Scene) ([]byte, error) {
+	return yaml.Marshal(scene)
+}
+
+func loadPrefabs(server *asset.Server, entities []Entity) {
+	for i := range entities {
+		if entities[i].Prefab != "" {
+			entities[i].prefab = asset.Load[Scene](server, entities[i].Prefab)
+		}
+		loadPrefabs(server, entities[i].Children)
+	}
+}
+
+// Parses a scene from YAML or JSON. Prefabs are not loaded, use the Loader for that.
+func Unmarshal(data []byte) (*Scene, error) {
+	var scene Scene
+	err := yaml.Unmarshal(data, &scene)
+	if err != nil {
+		return nil, err
+	}
+	return &scene, nil
+}
+
+//--------------------------------------------------------------------------------
+// - Registry
+//--------------------------------------------------------------------------------
+
+// Registry maps the component names used in scene files to component types
+type Registry struct {
+	types map[string]componentType
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		types: make(map[string]componentType),
+	}
+}
+
+// The registry used by the package level functions
+var DefaultRegistry = NewRegistry()
+
+// Registers a component into the default registry, using its type name as its name in scene files (ie "transform.Local")
+func Register[T any]() {
+	var zero T
+	RegisterTo(DefaultRegistry, typeName[T](), zero)
+}
+
+// Registers a component into the default registry. Fields that aren't set in the scene file keep the value they have in def.
+// Note: Every decoded component gets its own copy of def, which is made by round tripping def through encoding/json. So only the exported fields of def are used.
+func RegisterDefault[T any](def T) {
+	RegisterTo(DefaultRegistry, typeName[T](), def)
+}
+
+// Registers a component into the registry with a custom name and default value. If name is empty, the type name is used.
+func RegisterTo[T any](r *Registry, name string, def T) {
+	if name == "" {
+		name = typeName[T]()
+	}
+	_, exists := r.types[name]
+	if exists {
+		panic(fmt.Sprintf("scene: component name already registered: %s", name))
+	}
+	dat, err := json.Marshal(def)
+	if err != nil {
+		panic(fmt.Sprintf("scene: failed to encode default value of %s: %v", name, err))
+	}
+	r.types[name] = typedComponent[T]{dat}
+}
+
+func typeName[T any]() string {
+	return reflect.TypeFor[T]().String()
+}
+
+// Returns the names of every registered component, in sorted order
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.types))
+	for name := range r.types {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func (r *Registry) decode(name string, raw any) (ecs.Component, error) {
+	compType, ok := r.types[name]
+	if !ok {
+		return nil, fmt.Errorf("unregistered component %s", name)
+	}
+	comp, err := compType.decode(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", name, err)
+	}
+	return comp, nil
+}
+
+// Checks that every component in the entities can be decoded. Entities that reference a prefab only hold overrides, so only their names can be checked.
+func (r *Registry) validate(entities []Entity) error {
+	for i := range entities {
+		e := &entities[i]
+		for name, raw := range e.Components {
+			if e.Prefab != "" {
+				_, ok := r.types[name]
+				if !ok {
+					return fmt.Errorf("scene: entity %s: unregistered component %s", e.label(), name)
+				}
+				continue
+			}
+
+			_, err := r.decode(name, raw)
+			if err != nil {
+				return fmt.Errorf("scene: entity %s: %w", e.label(), err)
+			}
+		}
+
+		err := r.validate(e.Children)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type componentType interface {
+	decode(raw any) (ecs.Component, error)
+}
+
+type typedComponent[T any] struct {
+	def []byte // The default value encoded as json. Each decode starts from a fresh copy, so components never share the default's slices and maps.
+}
+
+// Note: The raw value is round tripped through json so that fields decode the same way for yaml and json files
+func (c typedComponent[T]) decode(raw any) (ecs.Component, error) {
+	var val T
+	err := json.Unmarshal(c.def, &val)
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return ecs.C(val), nil
+	}
+
+	dat, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(dat, &val)
+	if err != nil {
+		return nil, err
+	}
+	return ecs.C(val), nil
+}
@@ -0,0 +1,282 @@
+package scene
+
+import (
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/unitoftime/ecs"
+	"github.com/unitoftime/flow/asset"
+	"github.com/unitoftime/flow/glm"
+	"github.com/unitoftime/flow/transform"
+)
+
+type health struct {
+	Max     int
+	Current int
+}
+
+// Loads the named scene out of files, and spawns it into a new world with the transform and health components registered
+func spawn(files fstest.MapFS, name string) (*ecs.World, []ecs.Id, error) {
+	r := NewRegistry()
+	RegisterTo(r, "", transform.Local{transform.Default()})
+	RegisterTo(r, "health", health{Max: 10, Current: 10})
+
+	server := asset.NewServer()
+	server.RegisterFilesystem("", asset.NewFilesystem("", files))
+	asset.Register(server, Loader{Registry: r})
+
+	scene, err := asset.Load[Scene](server, name).Get()
+	if err != nil {
+		return nil, nil, err
+	}
+	world := ecs.NewWorld()
+	roots, err := r.Spawn(world, scene)
+	return world, roots, err
+}
+
+func TestSpawnPrefabOverrides(t *testing.T) {
+	files := fstest.MapFS{
+		"enemy.prefab.yaml": {Data: []byte(`
+entities:
+  - name: Enemy
+    components:
+      health: {Max: 20, Current: 20}
+      transform.Local:
+        Pos: {X: 1, Y: 2}
+    children:
+      - name: Weapon
+        components:
+          transform.Local:
+            Pos: {X: 5}
+`)},
+		"boss.prefab.json": {Data: []byte(`{
+  "entities": [{
+    "name": "Boss",
+    "prefab": "enemy.prefab.yaml",
+    "components": {"health": {"Max": 100}}
+  }]
+}`)},
+		"level.scene.yaml": {Data: []byte(`
+entities:
+  - name: Boss
+    prefab: boss.prefab.json
+    components:
+      transform.Local:
+        Pos: {X: 50}
+    children:
+      - name: Crown
+        components:
+          health:
+`)},
+	}
+	world, roots, err := spawn(files, "level.scene.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(roots) != 1 {
+		t.Fatalf("expected 1 root, got %d", len(roots))
+	}
+	boss := roots[0]
+
+	hp, ok := ecs.Read[health](world, boss)
+	if !ok || hp != (health{Max: 100, Current: 20}) {
+		t.Errorf("expected merged health, got %v %v", hp, ok)
+	}
+	local, _ := ecs.Read[transform.Local](world, boss)
+	if local.Pos != (glm.Vec2{50, 2}) || local.Scale != (glm.Vec2{1, 1}) {
+		t.Errorf("expected merged transform, got %v", local)
+	}
+	global, ok := ecs.Read[transform.Global](world, boss)
+	if !ok || global.Transform != local.Transform {
+		t.Errorf("expected global to be added, got %v %v", global, ok)
+	}
+
+	children, _ := ecs.Read[transform.Children](world, boss)
+	if children.MiniSlice.Len() != 2 {
+		t.Fatalf("expected prefab child and override child, got %d", children.MiniSlice.Len())
+	}
+	weapon, _ := ecs.Read[transform.Local](world, children.MiniSlice.Get(0))
+	if weapon.Pos != (glm.Vec2{5, 0}) {
+		t.Errorf("expected weapon from prefab, got %v", weapon)
+	}
+	crown, _ := ecs.Read[health](world, children.MiniSlice.Get(1))
+	if crown != (health{10, 10}) {
+		t.Errorf("expected default health, got %v", crown)
+	}
+	parent, _ := ecs.Read[transform.Parent](world, children.MiniSlice.Get(1))
+	if parent.Id != boss {
+		t.Errorf("expected parent %d, got %d", boss, parent.Id)
+	}
+}
+
+func TestSpawnErrors(t *testing.T) {
+	files := fstest.MapFS{
+		"unknown.scene.yaml": {Data: []byte(`
+entities:
+  - components:
+      missing: {}
+`)},
+		"bad.scene.yaml": {Data: []byte(`
+entities:
+  - components:
+      health: {Max: "lots"}
+`)},
+		"a.prefab.yaml": {Data: []byte(`
+entities:
+  - prefab: b.prefab.yaml
+`)},
+		"b.prefab.yaml": {Data: []byte(`
+entities:
+  - prefab: a.prefab.yaml
+`)},
+	}
+	for _, name := range []string{"unknown.scene.yaml", "bad.scene.yaml", "a.prefab.yaml"} {
+		_, roots, err := spawn(files, name)
+		if err == nil || len(roots) != 0 {
+			t.Errorf("%s: expected nothing to be spawned, got %v %v", name, roots, err)
+		}
+	}
+}
+
+func TestInstanceRespawnsOnPrefabEdit(t *testing.T) {
+	files := fstest.MapFS{
+		"enemy.prefab.yaml": {
+			Data:    []byte("entities:\n  - components:\n      health: {Max: 1}\n"),
+			ModTime: time.Unix(1, 0),
+		},
+		"level.scene.yaml": {
+			Data:    []byte("entities:\n  - prefab: enemy.prefab.yaml\n  - prefab: enemy.prefab.yaml\n"),
+			ModTime: time.Unix(1, 0),
+		},
+	}
+	r := NewRegistry()
+	RegisterTo(r, "health", health{})
+	server := asset.NewServer()
+	server.RegisterFilesystem("", asset.NewFilesystem("", files))
+	asset.Register(server, Loader{Registry: r})
+
+	world := ecs.NewWorld()
+	handle := asset.Load[Scene](server, "level.scene.yaml")
+	root := SpawnInstance(world, handle, transform.FromPos(glm.Vec2{10, 10}))
+	world.Write(root, Instance{Handle: handle, Registry: r})
+	system := InstanceSystem(world, server, time.Second)
+
+	countHealth := func(max int) int {
+		count := 0
+		ecs.Query1[health](world).MapId(func(id ecs.Id, hp *health) {
+			if hp.Max == max {
+				count++
+			}
+		})
+		return count
+	}
+	// Note: The instance is spawned once the scene and its prefab have loaded
+	deadline := time.Now().Add(5 * time.Second)
+	for countHealth(1) != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 2 spawned enemies")
+		}
+		system.Run(0)
+		time.Sleep(time.Millisecond)
+	}
+
+	// Edit the nested prefab
+	files["enemy.prefab.yaml"] = &fstest.MapFile{
+		Data:    []byte("entities:\n  - components:\n      health: {Max: 2}\n"),
+		ModTime: time.Unix(2, 0),
+	}
+
+	deadline = time.Now().Add(5 * time.Second)
+	for countHealth(2) != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("instance was not reloaded")
+		}
+		system.Run(time.Second)
+		time.Sleep(10 * time.Millisecond)
+	}
+	if countHealth(1) != 0 {
+		t.Errorf("expected old entities to be despawned")
+	}
+
+	inst, _ := ecs.Read[Instance](world, root)
+	children, _ := ecs.Read[transform.Children](world, root)
+	if len(inst.Roots()) != 2 || children.MiniSlice.Len() != 2 {
+		t.Errorf("expected root to only hold the respawned entities: %v %d", inst.Roots(), children.MiniSlice.Len())
+	}
+}
+
+// Instances wait for their prefabs to load, rather than blocking the system until they have
+func TestInstanceWaitsForPrefabs(t *testing.T) {
+	files := fstest.MapFS{
+		"enemy.prefab.yaml": {Data: []byte("entities:\n  - components:\n      health: {Max: 1}\n")},
+		"level.scene.yaml":  {Data: []byte("entities:\n  - children:\n      - prefab: enemy.prefab.yaml\n")},
+	}
+	r := NewRegistry()
+	RegisterTo(r, "health", health{})
+	server := asset.NewServer()
+	server.RegisterFilesystem("", asset.NewFilesystem("", files))
+	asset.Register(server, Loader{Registry: r})
+
+	handle := asset.Load[Scene](server, "level.scene.yaml")
+	scene, err := handle.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	loading := new(asset.Handle[Scene]) // Never finishes loading
+	scene.Entities[0].Children[0].prefab = loading
+
+	world := ecs.NewWorld()
+	root := SpawnInstance(world, handle, transform.Default())
+	system := InstanceSystem(world, nil, time.Second)
+
+	done := make(chan struct{})
+	go func() {
+		system.Run(0)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected the system not to block on a loading prefab")
+	}
+	inst, _ := ecs.Read[Instance](world, root)
+	if len(inst.Roots()) != 0 {
+		t.Errorf("expected nothing to be spawned until the prefab loads, got %v", inst.Roots())
+	}
+}
+
+type inventory struct {
+	Items []string
+	Slots map[string]int
+}
+
+func TestDefaultsAreCopied(t *testing.T) {
+	def := inventory{Items: []string{"sword"}, Slots: map[string]int{"hand": 1}}
+	r := NewRegistry()
+	RegisterTo(r, "inventory", def)
+
+	world := ecs.NewWorld()
+	var ids []ecs.Id
+	for _, raw := range []any{nil, map[string]any{"Slots": map[string]any{"belt": 2}}} {
+		comp, err := r.decode("inventory", raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		id := world.NewId()
+		world.Write(id, comp)
+		ids = append(ids, id)
+	}
+
+	first := ecs.ReadPtr[inventory](world, ids[0])
+	first.Items[0] = "shield"
+	first.Slots["hand"] = 5
+
+	second, _ := ecs.Read[inventory](world, ids[1])
+	if second.Items[0] != "sword" || second.Slots["hand"] != 1 || second.Slots["belt"] != 2 {
+		t.Errorf("expected decoded components not to share the default's slices and maps: %+v", second)
+	}
+	if def.Items[0] != "sword" || def.Slots["hand"] != 1 {
+		t.Errorf("expected the registered default to be unchanged: %+v", def)
+	}
+}
@@ -0,0 +1,393 @@
+package scene
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/unitoftime/ecs"
+	"github.com/unitoftime/flow"
+	"github.com/unitoftime/flow/asset"
+	"github.com/unitoftime/flow/transform"
+)
+
+// Spawns every entity in the scene into the world with the default registry. Returns the ids of the top level entities.
+// Note: If the scene uses prefabs, this blocks until they are loaded
+func Spawn(world *ecs.World, scene *Scene) ([]ecs.Id, error) {
+	return DefaultRegistry.Spawn(world, scene)
+}
+
+// Spawns every entity in the scene into the world. Returns the ids of the top level entities. Child entities are attached to their parents with transform.Parent and transform.Children, and entities with a transform.Local are given a transform.Global so that the heirarchy can be resolved.
+// If any component fails to decode, then nothing is spawned.
+func (r *Registry) Spawn(world *ecs.World, scene *Scene) ([]ecs.Id, error) {
+	s := newSpawner(r)
+	nodes, err := s.build(scene.Entities, nil)
+	if err != nil {
+		return nil, err
+	}
+	return s.spawn(world, nodes, ecs.InvalidEntity), nil
+}
+
+// A fully resolved entity, with its prefabs merged in and its components decoded
+type node struct {
+	comps    []ecs.Component
+	children []node
+}
+
+// An entity with its prefabs merged in, but not yet decoded
+type resolved struct {
+	label      string
+	components map[string]any
+	children   []resolved
+}
+
+type spawner struct {
+	registry *Registry
+	deps     map[*asset.Handle[Scene]]int // The generation of every scene handle that was used
+	entities []ecs.Id                     // Every entity that was spawned
+}
+
+func newSpawner(registry *Registry) *spawner {
+	return &spawner{
+		registry: registry,
+		deps:     make(map[*asset.Handle[Scene]]int),
+	}
+}
+
+func (s *spawner) build(entities []Entity, stack []string) ([]node, error) {
+	res, err := s.resolveAll(entities, stack)
+	if err != nil {
+		return nil, err
+	}
+	return s.decodeAll(res)
+}
+
+func (s *spawner) resolveAll(entities []Entity, stack []string) ([]resolved, error) {
+	ret := make([]resolved, 0, len(entities))
+	for i := range entities {
+		res, err := s.resolve(&entities[i], stack)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, res)
+	}
+	return ret, nil
+}
+
+func (s *spawner) resolve(e *Entity, stack []string) (resolved, error) {
+	res := resolved{
+		label:      e.label(),
+		components: e.Components,
+	}
+
+	if e.Prefab != "" {
+		base, err := s.resolvePrefab(e, stack)
+		if err != nil {
+			return res, err
+		}
+		res.components = merge(base.components, e.Components)
+		res.children = base.children
+	}
+
+	children, err := s.resolveAll(e.Children, stack)
+	if err != nil {
+		return res, err
+	}
+	res.children = append(res.children, children...)
+	return res, nil
+}
+
+func (s *spawner) resolvePrefab(e *Entity, stack []string) (resolved, error) {
+	if e.prefab == nil {
+		return resolved{}, fmt.Errorf("scene: entity %s: prefab %s is not loaded, scenes with prefabs must be loaded with the scene.Loader", e.label(), e.Prefab)
+	}
+	if slices.Contains(stack, e.Prefab) {
+		return resolved{}, fmt.Errorf("scene: entity %s: prefab cycle: %v -> %s", e.label(), stack, e.Prefab)
+	}
+
+	prefab, err := e.prefab.Get()
+	if err != nil {
+		return resolved{}, fmt.Errorf("scene: entity %s: failed to load prefab %s: %w", e.label(), e.Prefab, err)
+	}
+	s.deps[e.prefab] = e.prefab.Gen()
+
+	if len(prefab.Entities) != 1 {
+		return resolved{}, fmt.Errorf("scene: entity %s: prefab %s must have exactly one top level entity, found %d", e.label(), e.Prefab, len(prefab.Entities))
+	}
+	return s.resolve(&prefab.Entities[0], append(slices.Clip(stack), e.Prefab))
+}
+
+// Merges the override components over the base components. Nested maps are merged key by key, everything else is replaced. Neither map is modified.
+func merge(base, override map[string]any) map[string]any {
+	ret := make(map[string]any, len(base)+len(override))
+	maps.Copy(ret, base)
+	for key, val := range override {
+		if val == nil {
+			continue // Note: An empty override (ie `transform.Local:`) keeps the base value
+		}
+
+		baseMap, baseOk := ret[key].(map[string]any)
+		valMap, valOk := val.(map[string]any)
+		if baseOk && valOk {
+			ret[key] = merge(baseMap, valMap)
+			continue
+		}
+		ret[key] = val
+	}
+	return ret
+}
+
+func (s *spawner) decodeAll(res []resolved) ([]node, error) {
+	nodes := make([]node, 0, len(res))
+	for i := range res {
+		comps := make([]ecs.Component, 0, len(res[i].components)+2)
+
+		// Note: Decode in sorted order so that errors are reported consistently
+		names := slices.Sorted(maps.Keys(res[i].components))
+		for _, name := range names {
+			comp, err := s.registry.decode(name, res[i].components[name])
+			if err != nil {
+				return nil, fmt.Errorf("scene: entity %s: %w", res[i].label, err)
+			}
+			comps = append(comps, comp)
+		}
+
+		children, err := s.decodeAll(res[i].children)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node{comps, children})
+	}
+	return nodes, nil
+}
+
+func (s *spawner) spawn(world *ecs.World, nodes []node, parent ecs.Id) []ecs.Id {
+	ids := make([]ecs.Id, 0, len(nodes))
+	for i := range nodes {
+		id := world.NewId()
+		ids = append(ids, id)
+		s.entities = append(s.entities, id)
+
+		comps := nodes[i].comps
+		if parent != ecs.InvalidEntity {
+			comps = append(comps, transform.Parent{parent})
+		}
+
+		childIds := s.spawn(world, nodes[i].children, id)
+		if len(childIds) > 0 {
+			children := transform.Children{}
+			for _, childId := range childIds {
+				children.Add(childId)
+			}
+			comps = append(comps, children)
+		}
+
+		world.Write(id, comps...)
+
+		local, ok := ecs.Read[transform.Local](world, id)
+		if ok {
+			_, hasGlobal := ecs.Read[transform.Global](world, id)
+			if !hasGlobal {
+				world.Write(id, transform.Global{local.Transform})
+			}
+		}
+	}
+	return ids
+}
+
+//--------------------------------------------------------------------------------
+// - Instances
+//--------------------------------------------------------------------------------
+
+// Instance is a component which marks the root entity of a scene that was spawned from an asset handle. The scene's top level entities are spawned as children of the root entity. Whenever the scene, or any prefab it uses, is reloaded then the instance's entities are despawned and spawned again from the new version. The root entity is kept, so its id and transform don't change.
+// Instances are spawned by the InstanceSystem, which is added by the Plugin.
+//
+//cod:component
+type Instance struct {
+	Handle   *asset.Handle[Scene]
+	Registry *Registry // Optional: The registry used to decode components. Defaults to DefaultRegistry
+
+	roots    []ecs.Id                     // The spawned top level entities
+	entities []ecs.Id                     // Every spawned entity
+	deps     map[*asset.Handle[Scene]]int // The generation of every scene handle used by the last spawn
+}
+
+// Creates a root entity at the provided transform. The scene is spawned under it once the handle has finished loading.
+func SpawnInstance(world *ecs.World, handle *asset.Handle[Scene], local transform.Transform) ecs.Id {
+	id := world.NewId()
+	world.Write(id,
+		Instance{Handle: handle},
+		transform.Local{local},
+		transform.Global{local},
+	)
+	return id
+}
+
+// Returns the spawned top level entities of the instance
+func (i *Instance) Roots() []ecs.Id {
+	return i.roots
+}
+
+// Returns true if the instance needs to be spawned, or respawned. Instances wait until every prefab they use has finished loading, so that spawning doesn't block.
+func (i *Instance) stale() bool {
+	if i.Handle == nil || !i.Handle.Done() || i.Handle.Err() != nil {
+		return false
+	}
+	changed := i.deps == nil
+	for handle, gen := range i.deps {
+		if handle.Gen() != gen {
+			changed = true
+		}
+	}
+	if !changed {
+		return false
+	}
+
+	scene, _ := i.Handle.Get()
+	return prefabsDone(scene.Entities, make(map[*asset.Handle[Scene]]bool))
+}
+
+// Returns true if every prefab used by the entities, including nested prefabs, has finished loading. Prefabs which failed to load count as done, so that their error is reported when spawning.
+func prefabsDone(entities []Entity, seen map[*asset.Handle[Scene]]bool) bool {
+	for i := range entities {
+		handle := entities[i].prefab
+		if handle != nil && !seen[handle] {
+			seen[handle] = true // Note: Prefab cycles are reported when spawning
+			if !handle.Done() {
+				return false
+			}
+			prefab, err := handle.Get()
+			if err == nil && !prefabsDone(prefab.Entities, seen) {
+				return false
+			}
+		}
+		if !prefabsDone(entities[i].Children, seen) {
+			return false
+		}
+	}
+	return true
+}
+
+// Marks the current generations as seen, so that a broken scene isn't respawned every frame
+func (i *Instance) markFailed() {
+	if i.deps == nil {
+		i.deps = make(map[*asset.Handle[Scene]]int)
+	}
+	i.deps[i.Handle] = i.Handle.Gen()
+	for handle := range i.deps {
+		i.deps[handle] = handle.Gen()
+	}
+}
+
+func (i *Instance) respawn(world *ecs.World, root ecs.Id) error {
+	registry := i.Registry
+	if registry == nil {
+		registry = DefaultRegistry
+	}
+
+	s := newSpawner(registry)
+	s.deps[i.Handle] = i.Handle.Gen()
+	scene, err := i.Handle.Get()
+	if err != nil {
+		return err
+	}
+	nodes, err := s.build(scene.Entities, []string{i.Handle.Name})
+	if err != nil {
+		return err
+	}
+
+	for _, id := range i.entities {
+		ecs.Delete(world, id)
+	}
+
+	children, _ := ecs.Read[transform.Children](world, root)
+	for _, id := range i.roots {
+		children.Remove(id)
+	}
+	roots := s.spawn(world, nodes, root)
+	for _, id := range roots {
+		children.Add(id)
+	}
+	world.Write(root, children)
+
+	i.roots = roots
+	i.entities = s.entities
+	i.deps = s.deps
+	return nil
+}
+
+// Spawns instances once their scenes have loaded, and respawns them when their scenes are reloaded. If server is set, then the files of every instance are checked for changes once per interval.
+func InstanceSystem(world *ecs.World, server *asset.Server, interval time.Duration) ecs.System {
+	query := ecs.Query1[Instance](world)
+	var elapsed time.Duration
+	return ecs.System{
+		Name: "scene.InstanceSystem",
+		Func: func(dt time.Duration) {
+			elapsed += dt
+			if server != nil && elapsed >= interval {
+				elapsed = 0
+				pollInstances(server, query)
+			}
+
+			// Note: Spawning changes the world, so collect the ids first
+			stale := make([]ecs.Id, 0)
+			query.MapId(func(id ecs.Id, inst *Instance) {
+				if inst.stale() {
+					stale = append(stale, id)
+				}
+			})
+
+			for _, id := range stale {
+				inst, ok := ecs.Read[Instance](world, id)
+				if !ok {
+					continue
+				}
+				err := inst.respawn(world, id)
+				if err != nil {
+					fmt.Println("Error Spawning Scene:", inst.Handle.Name, err)
+					inst.markFailed()
+				}
+				world.Write(id, inst)
+			}
+		},
+	}
+}
+
+// Asks the asset server to reload every scene used by an instance. Reloads only happen if the file has changed.
+func pollInstances(server *asset.Server, query *ecs.View1[Instance]) {
+	handles := make(map[*asset.Handle[Scene]]struct{})
+	query.MapId(func(id ecs.Id, inst *Instance) {
+		if inst.Handle != nil {
+			handles[inst.Handle] = struct{}{}
+		}
+		for handle := range inst.deps {
+			handles[handle] = struct{}{}
+		}
+	})
+
+	for handle := range handles {
+		asset.Reload(server, handle)
+	}
+}
+
+// Plugin adds the InstanceSystem to the app
+// Note: The scene Loader must still be registered with your asset server: `asset.Register(server, scene.Loader{})`
+type Plugin struct {
+	Server         *asset.Server // Optional: If set, then the files of spawned instances are checked for changes and hot reloaded
+	ReloadInterval time.Duration // How often to check for changes. Defaults to 1 second
+}
+
+func (p Plugin) Dependencies() []flow.Plugin {
+	return []flow.Plugin{transform.DefaultPlugin{}}
+}
+
+func (p Plugin) Initialize(world *ecs.World) {
+	app := flow.MustApp(world)
+
+	interval := p.ReloadInterval
+	if interval <= 0 {
+		interval = 1 * time.Second
+	}
+	app.AddSystems(ecs.StagePreFixedUpdate, InstanceSystem(world, p.Server, interval))
+}
@@ -0,0 +1,159 @@
+package flow
+
+import (
+	"time"
+
+	"github.com/unitoftime/ecs"
+)
+
+// Every frame the stages run in this order:
+// 0. Frame start hooks run (ie event buffers are swapped and state transitions are applied)
+// 1. StagePreFixedUpdate: Runs once per frame, before any fixed updates (ie input handling)
+// 2. StageFixedUpdate: Runs zero or more times per frame, once for every fixed time step that has accumulated
+// 3. StagePostFixedUpdate: Runs once per frame, after all of the fixed updates
+// 4. StageUpdate: Runs once per frame (ie rendering)
+// StageStartup systems run once, before the first frame.
+type schedule struct {
+	startup   []ecs.System
+	preFixed  []ecs.System
+	fixed     []ecs.System
+	postFixed []ecs.System
+	update    []ecs.System
+
+	first []func(dt time.Duration) // Hooks which run at the start of every frame
+
+	fixedTimeStep time.Duration
+	accumulator   time.Duration
+	maxLoopCount  int
+	maxFrameTime  time.Duration
+	fixedTime     *FixedTime
+	time          *Time
+
+	profiler Profiler
+}
+
+// Profiler is notified of the time spent running every system, stage, and frame. See App.SetProfiler
+// Note: The fixed update stage can run several times per frame, so its systems may be reported several times before the frame is reported
+type Profiler interface {
+	ProfileSystem(stage ecs.Stage, index int, name string, elapsed time.Duration)
+	ProfileStage(stage ecs.Stage, elapsed time.Duration)
+	ProfileFrame(elapsed time.Duration)
+}
+
+func newSchedule() *schedule {
+	return &schedule{
+		fixedTimeStep: 16 * time.Millisecond,
+		maxFrameTime:  250 * time.Millisecond,
+		fixedTime:     &FixedTime{},
+		time:          newTime(),
+	}
+}
+
+// FixedTime is a resource which describes the fixed update stage. Its values are updated every frame, after the fixed updates have run.
+type FixedTime struct {
+	Step        time.Duration // The amount of time simulated by each fixed update
+	Accumulated time.Duration // The amount of time that has accumulated towards the next fixed update
+	Ticks       uint64        // The total number of fixed updates that have run
+	FrameTicks  int           // The number of fixed updates that ran this frame
+
+	// Between 0 and 1: How far we are between the last fixed update and the next one. Render systems can use this to interpolate between the last two fixed update states.
+	Alpha float64
+}
+
+func (s *schedule) add(world *ecs.World, stage ecs.Stage, systems ...ecs.SystemBuilder) {
+	for _, sys := range systems {
+		system := sys.Build(world)
+		switch stage {
+		case ecs.StageStartup:
+			s.startup = append(s.startup, system)
+		case ecs.StagePreFixedUpdate:
+			s.preFixed = append(s.preFixed, system)
+		case ecs.StageFixedUpdate:
+			s.fixed = append(s.fixed, system)
+		case ecs.StagePostFixedUpdate:
+			s.postFixed = append(s.postFixed, system)
+		case ecs.StageUpdate:
+			s.update = append(s.update, system)
+		default:
+			panic("flow: unknown stage")
+		}
+	}
+}
+
+// Returns true if the schedule only has fixed update systems
+func (s *schedule) onlyFixed() bool {
+	return len(s.preFixed) == 0 && len(s.postFixed) == 0 && len(s.update) == 0
+}
+
+func (s *schedule) runStartup() {
+	s.runStage(ecs.StageStartup, s.startup, 0)
+}
+
+func (s *schedule) runFrame(dt time.Duration) {
+	frameStart := time.Now()
+
+	// Note: Clamp the frame time so that one long frame (ie a breakpoint, or a hidden browser tab) can't cause a spiral of death, where every frame has more fixed updates to run than the last
+	frameDt := dt
+	if s.maxFrameTime > 0 {
+		frameDt = min(dt, s.maxFrameTime)
+	}
+	s.time.startFrame(dt, frameDt, s.fixedTimeStep)
+
+	// Note: Everything after this point, including the pre fixed update stage, sees the clamped frame time. Time.RealDelta keeps the raw frame time.
+	dt = frameDt
+	for _, hook := range s.first {
+		hook(dt)
+	}
+
+	s.runStage(ecs.StagePreFixedUpdate, s.preFixed, dt)
+
+	s.accumulator += s.time.VirtualDelta
+	maxLoopCount := time.Duration(s.maxLoopCount)
+	if maxLoopCount > 0 {
+		if s.accumulator > (maxLoopCount * s.fixedTimeStep) {
+			s.accumulator = s.fixedTimeStep // Just run one loop
+		}
+	}
+
+	frameTicks := 0
+	for s.accumulator >= s.fixedTimeStep {
+		s.time.startFixed(s.fixedTimeStep)
+		s.runStage(ecs.StageFixedUpdate, s.fixed, s.fixedTimeStep)
+		s.accumulator -= s.fixedTimeStep
+		frameTicks++
+	}
+	s.time.endFixed()
+
+	s.fixedTime.Step = s.fixedTimeStep
+	s.fixedTime.Accumulated = s.accumulator
+	s.fixedTime.Ticks += uint64(frameTicks)
+	s.fixedTime.FrameTicks = frameTicks
+	s.fixedTime.Alpha = s.accumulator.Seconds() / s.fixedTimeStep.Seconds()
+
+	s.runStage(ecs.StagePostFixedUpdate, s.postFixed, dt)
+	s.runStage(ecs.StageUpdate, s.update, dt)
+
+	if s.profiler != nil {
+		s.profiler.ProfileFrame(time.Since(frameStart))
+	}
+}
+
+func (s *schedule) runStage(stage ecs.Stage, systems []ecs.System, dt time.Duration) {
+	if s.profiler == nil {
+		runSystems(systems, dt)
+		return
+	}
+
+	start := time.Now()
+	for i := range systems {
+		elapsed := systems[i].Run(dt)
+		s.profiler.ProfileSystem(stage, i, systems[i].Name, elapsed)
+	}
+	s.profiler.ProfileStage(stage, time.Since(start))
+}
+
+func runSystems(systems []ecs.System, dt time.Duration) {
+	for i := range systems {
+		systems[i].Run(dt)
+	}
+}
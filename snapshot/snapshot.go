@@ -0,0 +1,294 @@
+package snapshot
+
+import (
+	"cmp"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"reflect"
+	"slices"
+
+	"github.com/unitoftime/ecs"
+	"github.com/unitoftime/flow/asset/serde"
+)
+
+// The snapshot format version. This is bumped whenever the layout of Snapshot changes.
+const Version = 1
+
+// Snapshot holds a serialized copy of every registered component of every entity in a world
+type Snapshot struct {
+	Version  int
+	Entities []Entity
+}
+
+type Entity struct {
+	Id         ecs.Id
+	Components []Component
+}
+
+type Component struct {
+	Name string
+	Data []byte
+}
+
+// Components that hold entity ids should implement this on their pointer type so that their ids can be fixed up when a snapshot is loaded. The remap function returns ecs.InvalidEntity for ids that aren't in the snapshot.
+type IdRemapper interface {
+	RemapIds(remap func(ecs.Id) ecs.Id)
+}
+
+// Registry tracks the set of components which are saved in snapshots. Components are only saved if they are registered, so components that hold live resources (ie windows, textures, or sprites) should not be registered.
+type Registry struct {
+	types    map[string]componentType
+	excluded map[string]bool
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		types:    make(map[string]componentType),
+		excluded: make(map[string]bool),
+	}
+}
+
+// The registry used by the package level functions. Flow packages register their components here.
+var DefaultRegistry = NewRegistry()
+
+// Registers a component into the default registry, using its type name as its name in the snapshot (ie "transform.Local")
+func Register[T any]() {
+	RegisterTo[T](DefaultRegistry)
+}
+
+// Registers a component into the default registry with a custom name
+func RegisterName[T any](name string) {
+	RegisterNameTo[T](DefaultRegistry, name)
+}
+
+func RegisterTo[T any](r *Registry) {
+	RegisterNameTo[T](r, typeName[T]())
+}
+
+func RegisterNameTo[T any](r *Registry, name string) {
+	_, exists := r.types[name]
+	if exists {
+		panic(fmt.Sprintf("snapshot: component name already registered: %s", name))
+	}
+	r.types[name] = typedComponent[T]{name}
+}
+
+// Opts a registered component out of snapshots. Use this to skip components that a package registered, but that your game doesn't want saved.
+func Exclude[T any](r *Registry) {
+	r.excluded[typeName[T]()] = true
+}
+
+// Opts a component out of snapshots by its registered name
+func (r *Registry) ExcludeName(name string) {
+	r.excluded[name] = true
+}
+
+func typeName[T any]() string {
+	return reflect.TypeFor[T]().String()
+}
+
+// Returns the names of every component that will be saved, in sorted order
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.types))
+	for name := range r.types {
+		if r.excluded[name] {
+			continue
+		}
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Captures a snapshot of every registered component in the world
+func (r *Registry) Capture(world *ecs.World) (*Snapshot, error) {
+	entities := make(map[ecs.Id]*Entity)
+	for _, name := range r.Names() {
+		err := r.types[name].collect(world, func(id ecs.Id, dat []byte) {
+			ent, ok := entities[id]
+			if !ok {
+				ent = &Entity{Id: id}
+				entities[id] = ent
+			}
+			ent.Components = append(ent.Components, Component{name, dat})
+		})
+		if err != nil {
+			return nil, fmt.Errorf("snapshot: failed to capture %s: %w", name, err)
+		}
+	}
+
+	snap := &Snapshot{
+		Version:  Version,
+		Entities: make([]Entity, 0, len(entities)),
+	}
+	for _, ent := range entities {
+		snap.Entities = append(snap.Entities, *ent)
+	}
+	slices.SortFunc(snap.Entities, func(a, b Entity) int {
+		return cmp.Compare(a.Id, b.Id)
+	})
+	return snap, nil
+}
+
+// Spawns every entity in the snapshot into the world. Each entity gets a new id, and the ids stored inside of components are remapped to the new ids. Returns the mapping from snapshot ids to world ids.
+func (r *Registry) Restore(world *ecs.World, snap *Snapshot) (map[ecs.Id]ecs.Id, error) {
+	if snap.Version != Version {
+		return nil, fmt.Errorf("snapshot: unsupported version %d, expected %d", snap.Version, Version)
+	}
+
+	mapping := make(map[ecs.Id]ecs.Id, len(snap.Entities))
+	for _, ent := range snap.Entities {
+		mapping[ent.Id] = world.NewId()
+	}
+	remap := func(id ecs.Id) ecs.Id {
+		newId, ok := mapping[id]
+		if !ok {
+			return ecs.InvalidEntity
+		}
+		return newId
+	}
+
+	for _, ent := range snap.Entities {
+		comps := make([]ecs.Component, 0, len(ent.Components))
+		for _, c := range ent.Components {
+			if r.excluded[c.Name] {
+				continue
+			}
+			compType, ok := r.types[c.Name]
+			if !ok {
+				return mapping, fmt.Errorf("snapshot: unregistered component %s", c.Name)
+			}
+
+			comp, err := compType.decode(c.Data, remap)
+			if err != nil {
+				return mapping, fmt.Errorf("snapshot: failed to restore %s: %w", c.Name, err)
+			}
+			comps = append(comps, comp)
+		}
+		world.Write(mapping[ent.Id], comps...)
+	}
+	return mapping, nil
+}
+
+// Returns a hash of the snapshot's entity ids and component data. Two worlds which hold the same registered state produce the same hash, so this can be used to check that a simulation is deterministic.
+// Note: Components are hashed by their serialized data, so components which hold maps may not hash consistently
+func (s *Snapshot) Hash() uint64 {
+	h := fnv.New64a()
+	var buf [8]byte
+	for _, ent := range s.Entities {
+		binary.LittleEndian.PutUint64(buf[:], uint64(ent.Id))
+		h.Write(buf[:])
+		for _, c := range ent.Components {
+			h.Write([]byte(c.Name))
+			binary.LittleEndian.PutUint64(buf[:], uint64(len(c.Data)))
+			h.Write(buf[:])
+			h.Write(c.Data)
+		}
+	}
+	return h.Sum64()
+}
+
+// Decodes a component that was captured by the registry. If the component holds entity ids, they are passed through remap.
+func (r *Registry) Decode(name string, dat []byte, remap func(ecs.Id) ecs.Id) (ecs.Component, error) {
+	compType, ok := r.types[name]
+	if !ok {
+		return nil, fmt.Errorf("snapshot: unregistered component %s", name)
+	}
+	return compType.decode(dat, remap)
+}
+
+// Deletes a registered component from an entity by its name
+func (r *Registry) DeleteComponent(world *ecs.World, id ecs.Id, name string) error {
+	compType, ok := r.types[name]
+	if !ok {
+		return fmt.Errorf("snapshot: unregistered component %s", name)
+	}
+	ecs.DeleteComponent(world, id, compType.zero())
+	return nil
+}
+
+func Marshal(snap *Snapshot) ([]byte, error) {
+	return serde.Marshal(*snap)
+}
+
+func Unmarshal(dat []byte) (*Snapshot, error) {
+	snap, err := serde.Unmarshal[Snapshot](dat)
+	if err != nil {
+		return nil, err
+	}
+	return &snap, nil
+}
+
+// Captures the world with the default registry and writes it to a file
+func Save(path string, world *ecs.World) error {
+	snap, err := DefaultRegistry.Capture(world)
+	if err != nil {
+		return err
+	}
+	dat, err := Marshal(snap)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, dat, 0644)
+}
+
+// Reads a snapshot from a file and spawns it into the world with the default registry. Returns the mapping from snapshot ids to world ids.
+func Load(path string, world *ecs.World) (map[ecs.Id]ecs.Id, error) {
+	dat, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	snap, err := Unmarshal(dat)
+	if err != nil {
+		return nil, err
+	}
+	return DefaultRegistry.Restore(world, snap)
+}
+
+//--------------------------------------------------------------------------------
+
+type componentType interface {
+	collect(world *ecs.World, add func(ecs.Id, []byte)) error
+	decode(dat []byte, remap func(ecs.Id) ecs.Id) (ecs.Component, error)
+	zero() ecs.Component
+}
+
+type typedComponent[T any] struct {
+	name string
+}
+
+func (c typedComponent[T]) collect(world *ecs.World, add func(ecs.Id, []byte)) error {
+	var err error
+	ecs.Query1[T](world).MapId(func(id ecs.Id, val *T) {
+		if err != nil {
+			return
+		}
+		var dat []byte
+		dat, err = serde.Marshal(*val)
+		if err != nil {
+			return
+		}
+		add(id, dat)
+	})
+	return err
+}
+
+func (c typedComponent[T]) decode(dat []byte, remap func(ecs.Id) ecs.Id) (ecs.Component, error) {
+	val, err := serde.Unmarshal[T](dat)
+	if err != nil {
+		return nil, err
+	}
+
+	remapper, ok := any(&val).(IdRemapper)
+	if ok {
+		remapper.RemapIds(remap)
+	}
+	return ecs.C(val), nil
+}
+
+func (c typedComponent[T]) zero() ecs.Component {
+	var val T
+	return ecs.C(val)
+}
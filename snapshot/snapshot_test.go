@@ -0,0 +1,118 @@
+package snapshot
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/unitoftime/ecs"
+)
+
+type position struct {
+	X, Y float64
+}
+
+type target struct {
+	Id ecs.Id
+}
+
+func (t *target) RemapIds(remap func(ecs.Id) ecs.Id) {
+	t.Id = remap(t.Id)
+}
+
+type window struct {
+	Handle int
+}
+
+func newTestRegistry() *Registry {
+	r := NewRegistry()
+	RegisterTo[position](r)
+	RegisterTo[target](r)
+	RegisterTo[window](r)
+	Exclude[window](r)
+	return r
+}
+
+func TestCaptureRestore(t *testing.T) {
+	r := newTestRegistry()
+
+	world := ecs.NewWorld()
+	a := world.NewId()
+	b := world.NewId()
+	outside := world.NewId()
+	world.Write(a, ecs.C(position{1, 2}), ecs.C(target{b}), ecs.C(window{5}))
+	world.Write(b, ecs.C(position{3, 4}), ecs.C(target{outside}))
+
+	snap, err := r.Capture(world)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snap.Entities) != 2 {
+		t.Fatalf("expected 2 entities, got %d", len(snap.Entities))
+	}
+
+	dat, err := Marshal(snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap, err = Unmarshal(dat)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newWorld := ecs.NewWorld()
+	newWorld.NewId() // Make sure that the ids don't line up between worlds
+	mapping, err := r.Restore(newWorld, snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newA := mapping[a]
+	newB := mapping[b]
+	if newA == a || newB == b {
+		t.Errorf("expected entities to be given new ids")
+	}
+
+	pos, ok := ecs.Read[position](newWorld, newA)
+	if !ok || pos != (position{1, 2}) {
+		t.Errorf("unexpected position: %v %v", pos, ok)
+	}
+	targ, ok := ecs.Read[target](newWorld, newA)
+	if !ok || targ.Id != newB {
+		t.Errorf("expected target to be remapped to %d, got %d", newB, targ.Id)
+	}
+	targ, _ = ecs.Read[target](newWorld, newB)
+	if targ.Id != ecs.InvalidEntity {
+		t.Errorf("expected ids outside of the snapshot to be invalid, got %d", targ.Id)
+	}
+	_, ok = ecs.Read[window](newWorld, newA)
+	if ok {
+		t.Errorf("expected excluded component to be skipped")
+	}
+}
+
+func TestRestoreUnregistered(t *testing.T) {
+	r := NewRegistry()
+	snap := &Snapshot{
+		Version: Version,
+		Entities: []Entity{
+			{Id: 5, Components: []Component{{Name: "missing"}}},
+		},
+	}
+	_, err := r.Restore(ecs.NewWorld(), snap)
+	if err == nil {
+		t.Fatal("expected unregistered component error")
+	}
+}
+
+func TestSaveLoadFile(t *testing.T) {
+	world := ecs.NewWorld()
+	path := filepath.Join(t.TempDir(), "world.snap")
+	err := Save(path, world)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Load(path, ecs.NewWorld())
+	if err != nil {
+		t.Fatal(err)
+	}
+}
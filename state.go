@@ -0,0 +1,185 @@
+package flow
+
+import (
+	"time"
+
+	"github.com/unitoftime/ecs"
+)
+
+// State is a resource which holds the current value of an application state (ie MainMenu, Loading, Playing, Paused). State changes are queued with Set, then applied at the start of the next frame:
+// 1. The OnExit systems of the old state run
+// 2. Every entity with a StateScoped component for the old state is despawned
+// 3. The OnEnter systems of the new state run
+//
+// Systems can change the state by injecting the resource: `func(dt time.Duration, state *flow.State[GameState])`
+type State[S comparable] struct {
+	current S
+	next    S
+	queued  bool
+	entered bool
+
+	onEnter stateSystems[S]
+	onExit  stateSystems[S]
+}
+
+// Holds the transition systems for each state. Systems are built the first time they are needed, so that they are built after all plugins have added their resources
+type stateSystems[S comparable] struct {
+	builders map[S][]ecs.SystemBuilder
+	systems  map[S][]ecs.System
+}
+
+func newStateSystems[S comparable]() stateSystems[S] {
+	return stateSystems[S]{
+		builders: make(map[S][]ecs.SystemBuilder),
+		systems:  make(map[S][]ecs.System),
+	}
+}
+
+func (s stateSystems[S]) add(state S, systems ...ecs.SystemBuilder) {
+	s.builders[state] = append(s.builders[state], systems...)
+}
+
+func (s stateSystems[S]) run(world *ecs.World, state S) {
+	for _, builder := range s.builders[state] {
+		s.systems[state] = append(s.systems[state], builder.Build(world))
+	}
+	delete(s.builders, state)
+
+	runSystems(s.systems[state], 0)
+}
+
+// Returns the current state
+func (s *State[S]) Get() S {
+	return s.current
+}
+
+// Queues a transition to the next state. Setting the state to the current state does nothing.
+func (s *State[S]) Set(next S) {
+	s.next = next
+	s.queued = true
+}
+
+// Returns true if the state is currently the provided state
+func (s *State[S]) Is(state S) bool {
+	return s.current == state
+}
+
+func (s *State[S]) transition(world *ecs.World) {
+	if !s.entered {
+		s.entered = true
+		s.onEnter.run(world, s.current)
+	}
+
+	if !s.queued {
+		return
+	}
+	s.queued = false
+	if s.next == s.current {
+		return
+	}
+
+	prev := s.current
+	s.onExit.run(world, prev)
+	despawnStateScoped(world, prev)
+
+	s.current = s.next
+	s.onEnter.run(world, s.current)
+}
+
+func despawnStateScoped[S comparable](world *ecs.World, state S) {
+	ids := make([]ecs.Id, 0)
+	ecs.Query1[StateScoped[S]](world).MapId(func(id ecs.Id, scoped *StateScoped[S]) {
+		if scoped.State == state {
+			ids = append(ids, id)
+		}
+	})
+	for _, id := range ids {
+		ecs.Delete(world, id)
+	}
+}
+
+// StateScoped is a component that causes its entity to be despawned when the app exits the provided state
+type StateScoped[S comparable] struct {
+	State S
+}
+
+func (c StateScoped[S]) CompId() ecs.CompId {
+	return ecs.NewComp[StateScoped[S]]().CompId()
+}
+
+func (c StateScoped[S]) CompWrite(w ecs.W) {
+	ecs.NewComp[StateScoped[S]]().WriteVal(w, c)
+}
+
+// Adds a state to the app with an initial value. The OnEnter systems of the initial state run after the startup systems.
+func AddState[S comparable](app *App, initial S) *State[S] {
+	state := getState[S](app)
+	if state != nil {
+		panic("flow: state has already been added")
+	}
+
+	state = &State[S]{
+		current: initial,
+		onEnter: newStateSystems[S](),
+		onExit:  newStateSystems[S](),
+	}
+	ecs.PutResource(app.world, state)
+	app.schedule.first = append(app.schedule.first, func(dt time.Duration) {
+		state.transition(app.world)
+	})
+	return state
+}
+
+func getState[S comparable](app *App) *State[S] {
+	return ecs.GetResource[State[S]](app.world)
+}
+
+func mustGetState[S comparable](app *App) *State[S] {
+	state := getState[S](app)
+	if state == nil {
+		panic("flow: state must be added with AddState before adding state systems")
+	}
+	return state
+}
+
+// Adds systems to the stage which only run while the app is in the provided state
+func AddStateSystems[S comparable](app *App, state S, stage ecs.Stage, systems ...ecs.SystemBuilder) {
+	appState := mustGetState[S](app)
+
+	builders := make([]ecs.SystemBuilder, len(systems))
+	for i := range systems {
+		builders[i] = inStateSystem[S]{appState, state, systems[i]}
+	}
+	app.AddSystems(stage, builders...)
+}
+
+// Adds systems which run once every time the app enters the provided state
+func OnEnter[S comparable](app *App, state S, systems ...ecs.SystemBuilder) {
+	appState := mustGetState[S](app)
+	appState.onEnter.add(state, systems...)
+}
+
+// Adds systems which run once every time the app exits the provided state
+func OnExit[S comparable](app *App, state S, systems ...ecs.SystemBuilder) {
+	appState := mustGetState[S](app)
+	appState.onExit.add(state, systems...)
+}
+
+type inStateSystem[S comparable] struct {
+	appState *State[S]
+	state    S
+	builder  ecs.SystemBuilder
+}
+
+func (s inStateSystem[S]) Build(world *ecs.World) ecs.System {
+	system := s.builder.Build(world)
+	return ecs.System{
+		Name: system.Name,
+		Func: func(dt time.Duration) {
+			if s.appState.current != s.state {
+				return
+			}
+			system.Func(dt)
+		},
+	}
+}
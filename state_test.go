@@ -0,0 +1,94 @@
+package flow
+
+import (
+	"testing"
+	"time"
+
+	"github.com/unitoftime/ecs"
+)
+
+type gameState uint8
+
+const (
+	stateMenu gameState = iota
+	statePlaying
+	statePaused
+)
+
+type marker struct{}
+
+func TestStateTransitions(t *testing.T) {
+	app := NewApp()
+	state := AddState(app, stateMenu)
+
+	log := &orderLog{}
+	logSystem := func(name string) ecs.System {
+		return ecs.NewSystem(func(dt time.Duration) {
+			log.order = append(log.order, name)
+		})
+	}
+
+	OnEnter(app, stateMenu, logSystem("enterMenu"))
+	OnExit(app, stateMenu, logSystem("exitMenu"))
+	OnEnter(app, statePlaying, logSystem("enterPlaying"))
+	AddStateSystems(app, statePlaying, ecs.StageUpdate, logSystem("updatePlaying"))
+
+	app.Step(16 * time.Millisecond)
+	checkOrder(t, log.order, "enterMenu")
+
+	// Queued state changes are applied at the start of the next frame
+	state.Set(statePlaying)
+	if !state.Is(stateMenu) {
+		t.Errorf("expected state change to be queued")
+	}
+	app.Step(16 * time.Millisecond)
+	checkOrder(t, log.order, "enterMenu", "exitMenu", "enterPlaying", "updatePlaying")
+
+	// Setting the same state does nothing
+	state.Set(statePlaying)
+	app.Step(16 * time.Millisecond)
+	checkOrder(t, log.order, "enterMenu", "exitMenu", "enterPlaying", "updatePlaying", "updatePlaying")
+
+	state.Set(statePaused)
+	app.Step(16 * time.Millisecond)
+	checkOrder(t, log.order, "enterMenu", "exitMenu", "enterPlaying", "updatePlaying", "updatePlaying")
+	if state.Get() != statePaused {
+		t.Errorf("expected paused state, got %v", state.Get())
+	}
+}
+
+func TestStateScopedDespawn(t *testing.T) {
+	app := NewApp()
+	state := AddState(app, stateMenu)
+	world := app.World()
+
+	menuId := world.NewId()
+	world.Write(menuId, StateScoped[gameState]{stateMenu}, ecs.C(marker{}))
+	playingId := world.NewId()
+	world.Write(playingId, StateScoped[gameState]{statePlaying}, ecs.C(marker{}))
+
+	app.Step(16 * time.Millisecond)
+	state.Set(statePlaying)
+	app.Step(16 * time.Millisecond)
+
+	if world.Exists(menuId) {
+		t.Errorf("expected menu entity to be despawned")
+	}
+	if !world.Exists(playingId) {
+		t.Errorf("expected playing entity to remain")
+	}
+}
+
+func TestStateSystemInjection(t *testing.T) {
+	app := NewApp()
+	AddState(app, stateMenu)
+	app.AddSystems(ecs.StageUpdate, ecs.NewSystem1(func(dt time.Duration, state *State[gameState]) {
+		state.Set(statePlaying)
+	}))
+
+	app.Step(16 * time.Millisecond)
+	app.Step(16 * time.Millisecond)
+	if ecs.GetResource[State[gameState]](app.World()).Get() != statePlaying {
+		t.Errorf("expected system to change the state")
+	}
+}
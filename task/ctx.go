@@ -0,0 +1,91 @@
+package task
+
+import (
+	"time"
+
+	"github.com/unitoftime/ecs"
+	"github.com/unitoftime/flow"
+)
+
+// Ctx is passed to a task's Func. Its wait methods suspend the task until a later frame.
+type Ctx struct {
+	World *ecs.World
+	Owner ecs.Id // The entity which owns the task, or ecs.InvalidEntity if it has no owner
+
+	task  *Task
+	yield func(struct{}) bool
+}
+
+// Returns the scaled time of the current frame
+func (c *Ctx) Dt() time.Duration {
+	return c.task.runner.dt
+}
+
+// Returns the runner which is running the task
+func (c *Ctx) Runner() *Runner {
+	return c.task.runner
+}
+
+// Suspends the task until the next frame. If the task was cancelled, this unwinds the task instead of returning.
+func (c *Ctx) NextFrame() {
+	if c.task.cancel || !c.yield(struct{}{}) || c.task.cancel {
+		panic(errUnwind)
+	}
+}
+
+// Suspends the task for a number of frames
+func (c *Ctx) Frames(n int) {
+	for i := 0; i < n; i++ {
+		c.NextFrame()
+	}
+}
+
+// Suspends the task until the duration of scaled time has passed. The task resumes on the first frame where the total time reaches the duration.
+func (c *Ctx) Wait(d time.Duration) {
+	for d > 0 {
+		c.NextFrame()
+		d -= c.Dt()
+	}
+}
+
+// Suspends the task until cond returns true. The condition is checked once per frame, starting with the current frame.
+func (c *Ctx) Until(cond func() bool) {
+	for !cond() {
+		c.NextFrame()
+	}
+}
+
+// Spawns a child task, which is owned by the same entity. The child first runs on the next frame.
+func (c *Ctx) Go(fn Func) *Task {
+	return c.task.runner.SpawnFor(c.Owner, fn)
+}
+
+// Suspends the task until every one of the tasks has finished or been cancelled
+func (c *Ctx) Await(tasks ...*Task) {
+	c.Until(func() bool {
+		for _, t := range tasks {
+			if !t.Done() {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// Suspends the task until an event which matches is sent, then returns that event. Only events sent after the wait starts are considered. If match is nil, the first event is returned.
+// The event must already be added with flow.AddEvent.
+func WaitEvent[T any](c *Ctx, match func(T) bool) T {
+	reader := flow.NewEventReader[T](c.World)
+	for range reader.Read() {
+		// Skip the events that were sent before we started waiting
+	}
+
+	for {
+		c.NextFrame()
+		for event := range reader.Read() {
+			if match == nil || match(event) {
+				return event
+			}
+		}
+	}
+}
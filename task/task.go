@@ -0,0 +1,229 @@
+package task
+
+import (
+	"iter"
+	"time"
+
+	"github.com/unitoftime/ecs"
+	"github.com/unitoftime/flow"
+)
+
+// Func is the body of a task. It is written sequentially, and suspends itself until a later frame by calling one of the wait methods on its Ctx.
+//
+//	runner.SpawnFor(door, func(ctx *task.Ctx) {
+//		ctx.Wait(2 * time.Second)
+//		ctx.Until(func() bool { return openDoor(ctx.World, ctx.Owner, ctx.Dt()) })
+//		task.WaitEvent(ctx, func(e PlayerEntered) bool { return e.Door == ctx.Owner })
+//	})
+//
+// Tasks are resumed one at a time, in the order that they were spawned, by the runner's system. They never run concurrently with systems, so they can freely read and write the world. If the task is cancelled while it is waiting, the wait method unwinds the task (running its deferred calls) and the task never resumes.
+type Func func(ctx *Ctx)
+
+// The status of a task
+type Status uint8
+
+const (
+	Running Status = iota
+	Finished
+	Cancelled
+)
+
+// Task is a handle to a spawned task
+type Task struct {
+	runner *Runner
+	fn     Func
+	ctx    *Ctx
+	status Status
+	cancel bool
+
+	started bool
+	next    func() (struct{}, bool)
+	stop    func()
+}
+
+// Returns the status of the task
+func (t *Task) Status() Status {
+	return t.status
+}
+
+// Returns true if the task has finished or was cancelled
+func (t *Task) Done() bool {
+	return t.status != Running
+}
+
+// Cancels the task. The task is unwound the next time the runner updates, or at its next wait if the task is cancelling itself.
+func (t *Task) Cancel() {
+	if t.status != Running {
+		return
+	}
+	t.cancel = true
+}
+
+func (t *Task) resume() {
+	if t.cancel {
+		t.finish(Cancelled)
+		return
+	}
+	if !t.started {
+		t.started = true
+		t.next, t.stop = iter.Pull(t.run)
+	}
+
+	_, ok := t.next()
+	if !ok {
+		if t.cancel {
+			t.finish(Cancelled)
+		} else {
+			t.finish(Finished)
+		}
+	}
+}
+
+func (t *Task) finish(status Status) {
+	t.status = status
+	if t.stop != nil {
+		// Note: If the task is still suspended, stop makes its current wait unwind the task
+		t.stop()
+		t.stop = nil
+		t.next = nil
+	}
+}
+
+// The body of the coroutine
+func (t *Task) run(yield func(struct{}) bool) {
+	defer func() {
+		r := recover()
+		if r != nil && r != errUnwind {
+			panic(r)
+		}
+	}()
+
+	t.ctx.yield = yield
+	t.fn(t.ctx)
+}
+
+// Sentinel value which is panicked to unwind a cancelled task
+var errUnwind = &struct{ name string }{"task: unwind"}
+
+//--------------------------------------------------------------------------------
+// - Runner
+//--------------------------------------------------------------------------------
+
+// Runner is a resource which holds every task and resumes each of them once per frame. Tasks are driven by the app's virtual time, so they respect pause and time scaling. Tasks only depend on the app's time, events and world, so they are deterministic under replay.
+//
+// Systems can spawn tasks by injecting the resource: `func(dt time.Duration, runner *task.Runner)`
+type Runner struct {
+	world *ecs.World
+	clock *flow.Time
+	tasks []*Task
+	dt    time.Duration
+}
+
+func NewRunner(world *ecs.World) *Runner {
+	return &Runner{
+		world: world,
+		clock: ecs.GetResource[flow.Time](world),
+	}
+}
+
+// Spawns a task which isn't owned by any entity. It first runs on the next update.
+func (r *Runner) Spawn(fn Func) *Task {
+	return r.SpawnFor(ecs.InvalidEntity, fn)
+}
+
+// Spawns a task which is owned by an entity. The task is cancelled when its owner despawns. It first runs on the next update.
+func (r *Runner) SpawnFor(owner ecs.Id, fn Func) *Task {
+	t := &Task{
+		runner: r,
+		fn:     fn,
+	}
+	t.ctx = &Ctx{
+		World: r.world,
+		Owner: owner,
+		task:  t,
+	}
+	r.tasks = append(r.tasks, t)
+	return t
+}
+
+// Returns the number of tasks that haven't finished
+func (r *Runner) Len() int {
+	return len(r.tasks)
+}
+
+// Cancels every task owned by the entity
+func (r *Runner) CancelOwned(owner ecs.Id) {
+	for _, t := range r.tasks {
+		if t.ctx.Owner == owner {
+			t.Cancel()
+		}
+	}
+}
+
+// Cancels and unwinds every task. This must not be called from inside a task.
+func (r *Runner) CancelAll() {
+	for _, t := range r.tasks {
+		t.Cancel()
+		t.finish(Cancelled)
+	}
+	r.tasks = r.tasks[:0]
+}
+
+// Resumes every task once. Tasks spawned during the update first run on the next update. If the world has a flow.Time resource, its Delta is used as the frame time, else dt is.
+func (r *Runner) Update(dt time.Duration) {
+	r.dt = dt
+	if r.clock != nil {
+		r.dt = r.clock.Delta
+	}
+
+	count := len(r.tasks)
+	for i := 0; i < count; i++ {
+		t := r.tasks[i]
+		if t.status != Running {
+			continue
+		}
+		owner := t.ctx.Owner
+		if owner != ecs.InvalidEntity && !r.world.Exists(owner) {
+			t.Cancel()
+		}
+		t.resume()
+	}
+
+	// Remove the finished tasks, keeping the spawn order
+	alive := r.tasks[:0]
+	for _, t := range r.tasks {
+		if t.status == Running {
+			alive = append(alive, t)
+		}
+	}
+	clear(r.tasks[len(alive):])
+	r.tasks = alive
+}
+
+//--------------------------------------------------------------------------------
+// - Plugin
+//--------------------------------------------------------------------------------
+
+// Plugin adds the Runner resource, and a system which resumes the tasks once per frame in StageUpdate
+type Plugin struct{}
+
+func (p Plugin) Initialize(world *ecs.World) {
+	app := flow.MustApp(world)
+
+	runner := NewRunner(world)
+	ecs.PutResource(world, runner)
+
+	app.AddSystems(ecs.StageUpdate, ecs.System{
+		Name: "task.RunnerSystem",
+		Func: runner.Update,
+	})
+}
+
+// Cancels every task when the app exits, so that none of them are left suspended
+func (p Plugin) Exit(world *ecs.World) {
+	runner := ecs.GetResource[Runner](world)
+	if runner == nil {
+		return
+	}
+	runner.CancelAll()
+}
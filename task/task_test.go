@@ -0,0 +1,185 @@
+package task
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/unitoftime/ecs"
+	"github.com/unitoftime/flow"
+)
+
+type opened struct {
+	Door int
+}
+
+const step = 100 * time.Millisecond
+
+func newApp() (*flow.App, *Runner) {
+	app := flow.NewApp()
+	app.SetFixedTimeStep(step)
+	app.AddPlugin(Plugin{})
+	flow.AddEvent[opened](app)
+	app.Step(0) // Build the app
+	return app, ecs.GetResource[Runner](app.World())
+}
+
+func TestSequence(t *testing.T) {
+	app, runner := newApp()
+	clock := ecs.GetResource[flow.Time](app.World())
+
+	var log []string
+	var frame int
+	x := 0
+	runner.Spawn(func(ctx *Ctx) {
+		log = append(log, "start")
+		ctx.Wait(300 * time.Millisecond)
+		log = append(log, "waited")
+		ctx.Until(func() bool {
+			x++
+			return x >= 3
+		})
+		log = append(log, "moved")
+		event := WaitEvent(ctx, func(e opened) bool { return e.Door == 2 })
+		log = append(log, "opened")
+		if event.Door != 2 {
+			t.Errorf("expected matching event, got %v", event)
+		}
+	})
+
+	expected := map[int][]string{
+		1: {"start"},
+		4: {"start", "waited"},
+		6: {"start", "waited", "moved"},
+	}
+	events := ecs.GetResource[flow.Events[opened]](app.World())
+	for frame = 1; frame <= 10; frame++ {
+		if frame == 7 {
+			events.Send(opened{1})
+			events.Send(opened{2})
+		}
+		app.Step(step)
+		if e, ok := expected[frame]; ok && !slices.Equal(log, e) {
+			t.Errorf("frame %d: expected %v, got %v", frame, e, log)
+		}
+		if frame == 2 {
+			// Pausing stops the task's wait
+			clock.Pause()
+			app.Step(step)
+			app.Step(step)
+			clock.Resume()
+		}
+	}
+
+	if !slices.Equal(log, []string{"start", "waited", "moved", "opened"}) {
+		t.Errorf("expected the task to finish, got %v", log)
+	}
+	if runner.Len() != 0 {
+		t.Errorf("expected finished task to be removed")
+	}
+}
+
+func TestOwnerDespawnCancels(t *testing.T) {
+	app, runner := newApp()
+	world := app.World()
+
+	owner := world.NewId()
+	world.Write(owner, ecs.C(opened{}))
+
+	cleanedUp := false
+	var child *Task
+	parent := runner.SpawnFor(owner, func(ctx *Ctx) {
+		defer func() { cleanedUp = true }()
+		child = ctx.Go(func(ctx *Ctx) {
+			ctx.Wait(time.Hour)
+		})
+		ctx.Await(child)
+		t.Errorf("expected the task to be cancelled before its child finishes")
+	})
+
+	app.Step(step)
+	app.Step(step)
+	if parent.Done() || child.Done() {
+		t.Fatalf("expected tasks to be running")
+	}
+
+	ecs.Delete(world, owner)
+	app.Step(step)
+	if parent.Status() != Cancelled || child.Status() != Cancelled {
+		t.Errorf("expected tasks to be cancelled: %v %v", parent.Status(), child.Status())
+	}
+	if !cleanedUp {
+		t.Errorf("expected deferred calls to run when a task is cancelled")
+	}
+	if runner.Len() != 0 {
+		t.Errorf("expected cancelled tasks to be removed")
+	}
+}
+
+func TestCancelSelf(t *testing.T) {
+	_, runner := newApp()
+
+	var task *Task
+	frames := 0
+	task = runner.Spawn(func(ctx *Ctx) {
+		for {
+			frames++
+			if frames == 3 {
+				task.Cancel()
+			}
+			ctx.NextFrame()
+		}
+	})
+	for i := 0; i < 5; i++ {
+		runner.Update(step)
+	}
+	if frames != 3 || task.Status() != Cancelled {
+		t.Errorf("expected task to cancel itself on its third frame: %d %v", frames, task.Status())
+	}
+}
+
+func TestExitCancels(t *testing.T) {
+	app, runner := newApp()
+
+	cleanedUp := false
+	task := runner.Spawn(func(ctx *Ctx) {
+		defer func() { cleanedUp = true }()
+		ctx.Wait(time.Hour)
+	})
+	app.Step(step)
+
+	app.Exit()
+	app.RunHeadless(0, step)
+	if !cleanedUp || task.Status() != Cancelled {
+		t.Errorf("expected tasks to be unwound on exit")
+	}
+}
+
+// Tasks only depend on the app's time and world, so the same frames give the same results
+func TestDeterministic(t *testing.T) {
+	run := func() []int {
+		app, runner := newApp()
+		rng := ecs.GetResource[flow.Rng](app.World())
+		rng.SetSeed(7)
+
+		var order []int
+		for i := 0; i < 4; i++ {
+			runner.Spawn(func(ctx *Ctx) {
+				for j := 0; j < 3; j++ {
+					ctx.Wait(time.Duration(1+rng.Intn(3)) * step)
+					order = append(order, i)
+				}
+			})
+		}
+		for i := 0; i < 20; i++ {
+			app.Step(time.Duration(50+i%3*50) * time.Millisecond)
+		}
+		return order
+	}
+
+	a := run()
+	b := run()
+	if len(a) != 12 || !slices.Equal(a, b) {
+		t.Errorf("expected the same order: %v %v", a, b)
+	}
+}
@@ -0,0 +1,101 @@
+package flow
+
+import (
+	"time"
+)
+
+// Time is a resource which tracks the app's clocks. It is updated by the schedule, so systems should treat its fields as read only and use its methods to pause or scale time.
+//   - Real time is the unscaled frame time that was passed into the app
+//   - Virtual time is real time multiplied by the time scale. It stops while paused. The fixed update stage is driven by virtual time, so pausing also stops the fixed updates.
+//   - Fixed time advances by one fixed time step for every fixed update
+//
+// Delta and Elapsed hold the clock for the stage that is currently running: Fixed time inside StageFixedUpdate, else virtual time. Systems that should respect pause and slow motion can read Delta rather than the dt that they are passed.
+//
+// Systems can access it by injecting the resource: `func(dt time.Duration, t *flow.Time)`
+type Time struct {
+	Frame uint64 // The number of frames that have started
+
+	Delta   time.Duration // The scaled time step for the current stage
+	Elapsed time.Duration // The scaled time that has elapsed for the current stage
+
+	RealDelta   time.Duration
+	RealElapsed time.Duration
+
+	VirtualDelta   time.Duration
+	VirtualElapsed time.Duration
+
+	FixedDelta   time.Duration
+	FixedElapsed time.Duration
+
+	scale  float64
+	paused bool
+	steps  int
+}
+
+func newTime() *Time {
+	return &Time{
+		scale: 1,
+	}
+}
+
+// Pauses virtual time
+func (t *Time) Pause() {
+	t.paused = true
+}
+
+// Resumes virtual time. Any pending steps are discarded.
+func (t *Time) Resume() {
+	t.paused = false
+	t.steps = 0
+}
+
+func (t *Time) Paused() bool {
+	return t.paused
+}
+
+// Sets the multiplier applied to real time to get virtual time. For example, 0.5 is half speed. Negative values are treated as 0.
+func (t *Time) SetScale(scale float64) {
+	t.scale = max(0, scale)
+}
+
+func (t *Time) Scale() float64 {
+	return t.scale
+}
+
+// While paused, advances the next frames by exactly one fixed time step each, so that each of them runs exactly one fixed update. This is useful for stepping through the game one tick at a time while debugging.
+func (t *Time) Step(frames int) {
+	t.steps += max(0, frames)
+}
+
+// Starts a new frame. frameDt is the real frame time, after it has been clamped by the max frame time.
+func (t *Time) startFrame(realDt, frameDt, fixedStep time.Duration) {
+	t.Frame++
+	t.RealDelta = realDt
+	t.RealElapsed += realDt
+
+	virtual := frameDt
+	switch {
+	case t.paused && t.steps > 0:
+		t.steps--
+		virtual = fixedStep
+	case t.paused:
+		virtual = 0
+	case t.scale != 1:
+		virtual = time.Duration(float64(frameDt) * t.scale)
+	}
+	t.VirtualDelta = virtual
+	t.VirtualElapsed += virtual
+	t.endFixed()
+}
+
+func (t *Time) startFixed(fixedStep time.Duration) {
+	t.FixedDelta = fixedStep
+	t.FixedElapsed += fixedStep
+	t.Delta = t.FixedDelta
+	t.Elapsed = t.FixedElapsed
+}
+
+func (t *Time) endFixed() {
+	t.Delta = t.VirtualDelta
+	t.Elapsed = t.VirtualElapsed
+}
@@ -0,0 +1,15 @@
+package timer
+
+import (
+	"github.com/unitoftime/ecs"
+)
+
+var TimerComp = ecs.NewComp[Timer]()
+
+func (c Timer) CompId() ecs.CompId {
+	return TimerComp.CompId()
+}
+
+func (c Timer) CompWrite(w ecs.W) {
+	TimerComp.WriteVal(w, c)
+}
@@ -0,0 +1,65 @@
+package timer
+
+import (
+	"time"
+
+	"github.com/unitoftime/ecs"
+	"github.com/unitoftime/flow"
+)
+
+// Fired is sent when an entity's timer fires
+type Fired struct {
+	Entity ecs.Id
+	Name   string
+	Times  int  // The number of times the timer fired during the update, which is more than one when it caught up on several intervals
+	Done   bool // The timer has run all of its intervals, and was removed
+}
+
+// Returns a system which updates the Timer component of every entity with the app's scaled time and rng. Fired events are sent for every timer that fired, and finished timers are removed.
+func UpdateSystem(world *ecs.World) ecs.System {
+	query := ecs.Query1[Timer](world)
+	clock := ecs.GetResource[flow.Time](world)
+	rng := ecs.GetResource[flow.Rng](world)
+	events := ecs.GetResource[flow.Events[Fired]](world)
+
+	var done []ecs.Id
+	return ecs.System{
+		Name: "timer.UpdateSystem",
+		Func: func(dt time.Duration) {
+			if clock != nil {
+				dt = clock.Delta
+			}
+
+			done = done[:0]
+			query.MapId(func(id ecs.Id, t *Timer) {
+				times := t.Update(dt, rng)
+				finished := t.Done()
+				if finished {
+					done = append(done, id)
+				}
+				if (times > 0 || finished) && events != nil {
+					events.Send(Fired{
+						Entity: id,
+						Name:   t.Name,
+						Times:  times,
+						Done:   finished,
+					})
+				}
+			})
+
+			for _, id := range done {
+				ecs.DeleteComponent(world, id, Timer{})
+			}
+		},
+	}
+}
+
+// Plugin adds the Fired event, and the system which updates timers in StageFixedUpdate, so that they are deterministic under replay and rollback
+type Plugin struct{}
+
+func (p Plugin) Initialize(world *ecs.World) {
+	app := flow.MustApp(world)
+
+	flow.AddEvent[Fired](app)
+	app.AddSystems(ecs.StageFixedUpdate, UpdateSystem(world))
+}
@@ -3,50 +3,169 @@ package timer
 import (
 	"math/rand"
 	"time"
+
+	"github.com/unitoftime/flow"
+	"github.com/unitoftime/flow/snapshot"
 )
 
+//go:generate go run ../../cod/cmd/cod
+
+func init() {
+	snapshot.Register[Timer]()
+}
+
+// CatchUp decides what a timer does when one update spans several of its intervals (ie after a long frame)
+type CatchUp uint8
+
+const (
+	CatchUpAll   CatchUp = iota // Fire once for every interval that elapsed
+	CatchUpOnce                 // Fire once, and stay on the original schedule
+	CatchUpReset                // Fire once, and start a full interval from now
+)
+
+// Timer fires every interval. It fires forever, or Count times if Count is set. Each firing only happens with the timer's Probability, which is rolled with the rng that is passed to Update, so timers are deterministic when the rng is seeded. If the rng is nil, the global math/rand source is used instead, which isn't deterministic.
+//
+//cod:component
 type Timer struct {
+	Name        string        // Sent with the Fired event
 	Interval    time.Duration // How long the interval is
 	Remaining   time.Duration // Remaining time
 	Probability float64       // From [0 to 1]
-	// Func func()					// Anonymous function to execute
+	Count       int           // The number of intervals to run for, 0 runs forever
+	Elapsed     int           // The number of intervals that have elapsed
+	CatchUp     CatchUp
+	Paused      bool
 }
 
+// Returns a repeating timer which fires with the probability every interval
 func New(interval time.Duration, probability float64) Timer {
-	if probability > 1.0 {
-		probability = 1.0
-	} else if probability < 0 {
-		probability = 0.0
-	}
 	return Timer{
 		Interval:    interval,
 		Remaining:   interval,
-		Probability: probability,
+		Probability: min(max(probability, 0), 1),
 	}
 }
 
-func (t *Timer) Update(dt time.Duration) bool {
-	if t.Interval == 0 {
-		return false
+// Returns a timer which fires once, after the delay
+func OneShot(delay time.Duration) Timer {
+	return Repeat(delay, 1)
+}
+
+// Returns a timer which fires every interval, count times
+func Repeat(interval time.Duration, count int) Timer {
+	t := New(interval, 1)
+	t.Count = count
+	return t
+}
+
+// Returns true if the timer has run all of its intervals
+func (t *Timer) Done() bool {
+	return t.Count > 0 && t.Elapsed >= t.Count
+}
+
+// Counts the timer down by dt, and returns the number of times that it fired. If the timer's probability is below 1, the rng is used to roll each firing. A nil rng rolls with the global math/rand source.
+func (t *Timer) Update(dt time.Duration, rng *flow.Rng) int {
+	if t.Interval <= 0 || t.Paused || t.Done() {
+		return 0
+	}
+
+	t.Remaining -= dt
+	if t.Remaining > 0 {
+		return 0
 	}
 
-	ret := false
-	if t.Remaining <= 0 {
-		// Else if the l is done, then roll the dice to see if we evaluate it
-		random := rand.Float64()
-		if random < t.Probability {
-			// t.Func()
-			ret = true
+	// Count the intervals that elapsed
+	elapsed := 1 + int(-t.Remaining/t.Interval)
+	if t.Count > 0 {
+		elapsed = min(elapsed, t.Count-t.Elapsed)
+	}
+	t.Elapsed += elapsed
+
+	rolls := elapsed
+	switch t.CatchUp {
+	case CatchUpAll:
+		t.Remaining += time.Duration(elapsed) * t.Interval
+	case CatchUpOnce:
+		rolls = 1
+		t.Remaining += time.Duration(elapsed) * t.Interval
+	case CatchUpReset:
+		rolls = 1
+		t.Remaining = t.Interval
+	}
+
+	fired := 0
+	for i := 0; i < rolls; i++ {
+		if t.roll(rng) {
+			fired++
 		}
+	}
+	return fired
+}
+
+// Updates the timer using the scaled time, so that it respects pause and slow motion
+func (t *Timer) UpdateTime(clock *flow.Time, rng *flow.Rng) int {
+	return t.Update(clock.Delta, rng)
+}
 
-		t.Reset()
-	} else {
-		// If the l is not done, then count it down
-		t.Remaining -= dt
+func (t *Timer) roll(rng *flow.Rng) bool {
+	if t.Probability >= 1 {
+		return true
+	}
+	if t.Probability <= 0 {
+		return false
+	}
+	if rng == nil {
+		return rand.Float64() < t.Probability
 	}
-	return ret
+	return rng.Float64() < t.Probability
 }
 
+// Restarts the timer from the beginning
 func (t *Timer) Reset() {
 	t.Remaining = t.Interval
+	t.Elapsed = 0
+}
+
+//--------------------------------------------------------------------------------
+// - Cooldown
+//--------------------------------------------------------------------------------
+
+// Cooldown limits how often something can happen. Cooldowns are usually embedded in components and counted down with the scaled time:
+//
+//	if input.Pressed("dash") && dash.Cooldown.Trigger() { ... }
+//	dash.Cooldown.Update(clock.Delta)
+type Cooldown struct {
+	Duration  time.Duration
+	Remaining time.Duration
+}
+
+func NewCooldown(dur time.Duration) Cooldown {
+	return Cooldown{Duration: dur}
+}
+
+// Returns true if the cooldown has run out
+func (c *Cooldown) Ready() bool {
+	return c.Remaining <= 0
+}
+
+// Starts the cooldown if it is ready, and returns true if it was
+func (c *Cooldown) Trigger() bool {
+	if !c.Ready() {
+		return false
+	}
+	c.Remaining = c.Duration
+	return true
+}
+
+// Counts the cooldown down by dt
+func (c *Cooldown) Update(dt time.Duration) {
+	c.Remaining = max(c.Remaining-dt, 0)
+}
+
+// Returns how far through the cooldown is, from 0 (just triggered) to 1 (ready)
+func (c *Cooldown) Ratio() float64 {
+	if c.Duration <= 0 {
+		return 1
+	}
+	return 1 - c.Remaining.Seconds()/c.Duration.Seconds()
 }
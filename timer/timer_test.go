@@ -0,0 +1,157 @@
+package timer
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/unitoftime/ecs"
+	"github.com/unitoftime/flow"
+)
+
+func TestCatchUp(t *testing.T) {
+	tests := []struct {
+		mode      CatchUp
+		fired     int
+		remaining time.Duration
+	}{
+		{CatchUpAll, 3, 500 * time.Millisecond},
+		{CatchUpOnce, 1, 500 * time.Millisecond},
+		{CatchUpReset, 1, time.Second},
+	}
+	for _, test := range tests {
+		timer := New(time.Second, 1)
+		timer.CatchUp = test.mode
+		fired := timer.Update(3500*time.Millisecond, nil)
+		if fired != test.fired || timer.Remaining != test.remaining {
+			t.Errorf("mode %d: expected %d fires with %v remaining, got %d with %v", test.mode, test.fired, test.remaining, fired, timer.Remaining)
+		}
+		if timer.Elapsed != 3 {
+			t.Errorf("mode %d: expected every interval to be counted, got %d", test.mode, timer.Elapsed)
+		}
+	}
+}
+
+func TestRepeatCount(t *testing.T) {
+	timer := Repeat(time.Second, 3)
+	if timer.Update(999*time.Millisecond, nil) != 0 {
+		t.Errorf("expected no fire before the interval")
+	}
+	if timer.Update(time.Millisecond, nil) != 1 {
+		t.Errorf("expected a fire on the interval")
+	}
+	if timer.Update(10*time.Second, nil) != 2 || !timer.Done() {
+		t.Errorf("expected the remaining fires to be capped by the count")
+	}
+	if timer.Update(10*time.Second, nil) != 0 {
+		t.Errorf("expected finished timer to not fire")
+	}
+
+	timer.Reset()
+	if timer.Done() || timer.Update(time.Second, nil) != 1 {
+		t.Errorf("expected reset timer to fire again")
+	}
+
+	once := OneShot(time.Second)
+	if once.Update(5*time.Second, nil) != 1 || !once.Done() {
+		t.Errorf("expected one shot timer to fire once")
+	}
+}
+
+func TestProbability(t *testing.T) {
+	run := func(seed int64) []int {
+		rng := flow.NewRng(seed)
+		timer := New(time.Second, 0.5)
+		var fires []int
+		for i := 0; i < 50; i++ {
+			fires = append(fires, timer.Update(time.Second, rng))
+		}
+		return fires
+	}
+
+	a, b := run(1), run(1)
+	if !slices.Equal(a, b) {
+		t.Errorf("expected the same seed to fire the same way")
+	}
+	total := 0
+	for _, f := range a {
+		total += f
+	}
+	if total == 0 || total == 50 {
+		t.Errorf("expected some rolls to fail, got %d of 50", total)
+	}
+
+	// Without an rng, the global source is rolled
+	timer := New(time.Second, 0.5)
+	total = 0
+	for i := 0; i < 200; i++ {
+		total += timer.Update(time.Second, nil)
+	}
+	if total == 0 || total == 200 {
+		t.Errorf("expected a nil rng to roll with the global source, got %d of 200", total)
+	}
+}
+
+func TestCooldown(t *testing.T) {
+	c := NewCooldown(time.Second)
+	if !c.Trigger() || c.Trigger() {
+		t.Errorf("expected only the first trigger to succeed")
+	}
+	c.Update(500 * time.Millisecond)
+	if c.Ready() || c.Ratio() != 0.5 {
+		t.Errorf("expected cooldown to be half done, got %v", c.Ratio())
+	}
+	c.Update(time.Second)
+	if !c.Ready() || !c.Trigger() {
+		t.Errorf("expected cooldown to be ready")
+	}
+}
+
+func TestPlugin(t *testing.T) {
+	const step = 100 * time.Millisecond
+	app := flow.NewApp()
+	app.SetFixedTimeStep(step)
+	app.AddPlugin(Plugin{})
+	app.Step(0) // Build the app
+
+	world := app.World()
+	reader := flow.NewEventReader[Fired](world)
+	clock := ecs.GetResource[flow.Time](world)
+
+	id := world.NewId()
+	timer := Repeat(200*time.Millisecond, 2)
+	timer.Name = "spawn"
+	world.Write(id, timer)
+
+	read := func() []Fired {
+		var fired []Fired
+		for e := range reader.Read() {
+			fired = append(fired, e)
+		}
+		return fired
+	}
+
+	app.Step(step)
+	app.Step(step)
+	if fired := read(); len(fired) != 1 || fired[0] != (Fired{id, "spawn", 1, false}) {
+		t.Errorf("expected one fire, got %v", fired)
+	}
+
+	// Paused game time pauses the timers
+	clock.Pause()
+	app.Step(step)
+	app.Step(step)
+	if fired := read(); len(fired) != 0 {
+		t.Errorf("expected no fires while paused, got %v", fired)
+	}
+	clock.Resume()
+
+	app.Step(step)
+	app.Step(step)
+	if fired := read(); len(fired) != 1 || !fired[0].Done {
+		t.Errorf("expected the final fire, got %v", fired)
+	}
+	if _, ok := ecs.Read[Timer](world, id); ok {
+		t.Errorf("expected finished timer to be removed")
+	}
+}
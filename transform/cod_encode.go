@@ -24,6 +24,16 @@ func (c Global) CompWrite(w ecs.W) {
 	GlobalComp.WriteVal(w, c)
 }
 
+var InterpolatedComp = ecs.NewComp[Interpolated]()
+
+func (c Interpolated) CompId() ecs.CompId {
+	return InterpolatedComp.CompId()
+}
+
+func (c Interpolated) CompWrite(w ecs.W) {
+	InterpolatedComp.WriteVal(w, c)
+}
+
 var LocalComp = ecs.NewComp[Local]()
 
 func (c Local) CompId() ecs.CompId {
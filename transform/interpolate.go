@@ -0,0 +1,84 @@
+package transform
+
+import (
+	"math"
+	"time"
+
+	"github.com/unitoftime/ecs"
+	"github.com/unitoftime/flow"
+)
+
+// Entities with an Interpolated component have their Global transform smoothed out when the frame rate is higher than the fixed update rate. The DefaultPlugin adds these systems, every frame:
+// 1. StagePreFixedUpdate: Global is restored to the latest fixed update state
+// 2. StageFixedUpdate: Before each fixed update, the current Global is saved as the previous state
+// 3. StagePostFixedUpdate: The latest fixed update state is saved, and Global is interpolated between the previous and latest states using the FixedTime alpha
+//
+// Note: This means that the rendered transform lags one fixed update behind the simulation.
+
+// Interpolated holds the last two fixed update states of an entity's Global transform
+//
+//cod:component
+type Interpolated struct {
+	Prev, Curr Transform
+	valid      bool
+}
+
+// Resets the interpolation so that the entity snaps to its current Global transform, rather than smoothly moving there. Use this after teleporting an entity.
+func (i *Interpolated) Reset() {
+	i.valid = false
+}
+
+func (i *Interpolated) capture(global Transform) {
+	i.Prev = global
+	i.Curr = global
+	i.valid = true
+}
+
+// Restores every interpolated Global transform back to its latest fixed update state
+func RestoreInterpolationSystem(world *ecs.World) ecs.System {
+	query := ecs.Query2[Interpolated, Global](world)
+	return ecs.NewSystem(func(dt time.Duration) {
+		query.MapId(func(id ecs.Id, interp *Interpolated, global *Global) {
+			if !interp.valid {
+				return
+			}
+			global.Transform = interp.Curr
+		})
+	})
+}
+
+// Saves the current Global transform as the previous fixed update state
+func CaptureInterpolationSystem(world *ecs.World) ecs.System {
+	query := ecs.Query2[Interpolated, Global](world)
+	return ecs.NewSystem(func(dt time.Duration) {
+		query.MapId(func(id ecs.Id, interp *Interpolated, global *Global) {
+			interp.capture(global.Transform)
+		})
+	})
+}
+
+// Saves the latest fixed update state and then interpolates the Global transform between the last two fixed update states
+func InterpolateSystem(world *ecs.World) ecs.System {
+	fixedTime := ecs.GetResource[flow.FixedTime](world)
+	query := ecs.Query2[Interpolated, Global](world)
+	return ecs.NewSystem(func(dt time.Duration) {
+		alpha := fixedTime.Alpha
+		query.MapId(func(id ecs.Id, interp *Interpolated, global *Global) {
+			if !interp.valid {
+				interp.capture(global.Transform)
+			}
+			interp.Curr = global.Transform
+			global.Transform = interp.Prev.Lerp(interp.Curr, alpha)
+		})
+	})
+}
+
+// Linearly interpolates from t to u. Rotation is interpolated along the shortest direction.
+func (t Transform) Lerp(u Transform, alpha float64) Transform {
+	rotDelta := math.Remainder(u.Rot-t.Rot, 2*math.Pi)
+	return Transform{
+		Pos:   t.Pos.Add(u.Pos.Sub(t.Pos).Scaled(alpha)),
+		Rot:   t.Rot + rotDelta*alpha,
+		Scale: t.Scale.Add(u.Scale.Sub(t.Scale).Scaled(alpha)),
+	}
+}
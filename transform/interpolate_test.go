@@ -0,0 +1,73 @@
+package transform
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/unitoftime/ecs"
+	"github.com/unitoftime/flow"
+	"github.com/unitoftime/flow/glm"
+)
+
+func TestInterpolation(t *testing.T) {
+	app := flow.NewApp()
+	app.SetFixedTimeStep(10 * time.Millisecond)
+	app.AddPlugin(DefaultPlugin{})
+
+	// Moves 10 units every fixed update
+	app.AddSystems(ecs.StageFixedUpdate, ecs.NewSystem1(func(dt time.Duration, query *ecs.View1[Local]) {
+		query.MapId(func(id ecs.Id, local *Local) {
+			local.Pos.X += 10
+		})
+	}))
+
+	world := app.World()
+	id := world.NewId()
+	world.Write(id, Local{Default()}, Global{Default()}, Interpolated{})
+
+	checkX := func(expected float64) {
+		t.Helper()
+		global, _ := ecs.Read[Global](world, id)
+		if math.Abs(global.Pos.X-expected) > 1e-9 {
+			t.Errorf("expected global x %f, got %f", expected, global.Pos.X)
+		}
+	}
+
+	// Note: The heirarchy resolves at the start of each fixed update, so Global lags behind Local by one fixed update. Then the rendered Global lags one more fixed update behind that.
+	app.Step(10 * time.Millisecond)
+	checkX(0)
+	app.Step(10 * time.Millisecond)
+	checkX(0)
+
+	app.Step(5 * time.Millisecond) // Halfway to the next fixed update
+	checkX(5)
+	app.Step(10 * time.Millisecond) // Runs one fixed update and leaves us halfway to the next
+	checkX(15)
+	app.Step(5 * time.Millisecond) // Runs one fixed update and lands exactly on it
+	checkX(20)
+
+	local, _ := ecs.Read[Local](world, id)
+	if local.Pos.X != 40 {
+		t.Errorf("expected interpolation not to affect local transform, got %f", local.Pos.X)
+	}
+}
+
+func TestTransformLerp(t *testing.T) {
+	a := Transform{Pos: glm.Vec2{X: 0, Y: 0}, Rot: 3, Scale: glm.Vec2{X: 1, Y: 1}}
+	b := Transform{Pos: glm.Vec2{X: 10, Y: 20}, Rot: -3, Scale: glm.Vec2{X: 3, Y: 1}}
+
+	mid := a.Lerp(b, 0.5)
+	if mid.Pos != (glm.Vec2{X: 5, Y: 10}) {
+		t.Errorf("unexpected position %v", mid.Pos)
+	}
+	if mid.Scale != (glm.Vec2{X: 2, Y: 1}) {
+		t.Errorf("unexpected scale %v", mid.Scale)
+	}
+
+	// Should rotate through pi, not through 0
+	expectedRot := 3 + (2*math.Pi-6)/2
+	if math.Abs(mid.Rot-expectedRot) > 1e-9 {
+		t.Errorf("expected rotation %f, got %f", expectedRot, mid.Rot)
+	}
+}
@@ -0,0 +1,42 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/unitoftime/ecs"
+	"github.com/unitoftime/flow/snapshot"
+)
+
+func TestSnapshotHeirarchy(t *testing.T) {
+	world := ecs.NewWorld()
+	parent := world.NewId()
+	child := world.NewId()
+
+	children := Children{}
+	children.Add(child)
+	world.Write(parent, Local{Default()}, Global{Default()}, children)
+	world.Write(child, Local{Default()}, Global{Default()}, Parent{parent})
+
+	snap, err := snapshot.DefaultRegistry.Capture(world)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newWorld := ecs.NewWorld()
+	newWorld.NewId()
+	mapping, err := snapshot.DefaultRegistry.Restore(newWorld, snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newParent := mapping[parent]
+	newChild := mapping[child]
+	p, ok := ecs.Read[Parent](newWorld, newChild)
+	if !ok || p.Id != newParent {
+		t.Errorf("expected parent %d, got %d", newParent, p.Id)
+	}
+	c, ok := ecs.Read[Children](newWorld, newParent)
+	if !ok || c.MiniSlice.Len() != 1 || c.MiniSlice.Get(0) != newChild {
+		t.Errorf("expected children [%d], got %v", newChild, c.MiniSlice)
+	}
+}
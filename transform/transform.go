@@ -4,8 +4,10 @@ import (
 	"time"
 
 	"github.com/unitoftime/ecs"
+	"github.com/unitoftime/flow"
 	"github.com/unitoftime/flow/ds"
 	"github.com/unitoftime/flow/glm"
+	"github.com/unitoftime/flow/snapshot"
 )
 
 //go:generate go run ../../cod/cmd/cod
@@ -14,14 +16,26 @@ import (
 // 1. Migrate to 3D transforms
 // 2. Revisit optimizations for heirarchy resolutions
 
+func init() {
+	snapshot.Register[Local]()
+	snapshot.Register[Global]()
+	snapshot.Register[Parent]()
+	snapshot.Register[Children]()
+	snapshot.Register[Interpolated]()
+}
+
 type DefaultPlugin struct {
 }
 
 func (p DefaultPlugin) Initialize(world *ecs.World) {
-	scheduler := ecs.GetResource[ecs.Scheduler](world)
+	app := flow.MustApp(world)
 
+	// Note: Interpolation must capture the previous state before the heirarchy is resolved
+	app.AddSystems(ecs.StagePreFixedUpdate, RestoreInterpolationSystem(world))
+	app.AddSystems(ecs.StageFixedUpdate, CaptureInterpolationSystem(world))
 	// TODO: This should be added to a better stage
-	scheduler.AppendPhysics(ResolveHeirarchySystem(world))
+	app.AddSystems(ecs.StageFixedUpdate, ResolveHeirarchySystem(world))
+	app.AddSystems(ecs.StagePostFixedUpdate, InterpolateSystem(world))
 }
 
 type Transform3D struct {
@@ -146,6 +160,10 @@ type Parent struct {
 	Id ecs.Id
 }
 
+func (p *Parent) RemapIds(remap func(ecs.Id) ecs.Id) {
+	p.Id = remap(p.Id)
+}
+
 // 3. You could reorganize so that parent's know their transform children, and recursively calculate each child's GlobalTransform
 //
 //cod:component
@@ -183,6 +201,12 @@ func (c *Children) Clear() {
 	c.MiniSlice.Clear()
 }
 
+func (c *Children) RemapIds(remap func(ecs.Id) ecs.Id) {
+	for i, id := range c.MiniSlice.All() {
+		c.MiniSlice.Set(i, remap(id))
+	}
+}
+
 // Recursively goes through the transform heirarchy and calculates entity GlobalTransform based on their Parent's GlobalTransform and their local Transform.
 func ResolveHeirarchySystem(world *ecs.World) ecs.System {
 	queryTopLevel := ecs.Query3[Children, Local, Global](world,
@@ -0,0 +1,15 @@
+package tween
+
+import (
+	"github.com/unitoftime/ecs"
+)
+
+var TweenComp = ecs.NewComp[Tween]()
+
+func (c Tween) CompId() ecs.CompId {
+	return TweenComp.CompId()
+}
+
+func (c Tween) CompWrite(w ecs.W) {
+	TweenComp.WriteVal(w, c)
+}
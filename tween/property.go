@@ -0,0 +1,84 @@
+package tween
+
+import (
+	"fmt"
+
+	"github.com/unitoftime/ecs"
+	"github.com/unitoftime/flow/glm"
+	"github.com/unitoftime/flow/interp"
+	"github.com/unitoftime/flow/transform"
+)
+
+// Value is a type that can be tweened
+type Value interface {
+	float64 | glm.Vec2 | glm.RGBA
+}
+
+// Property is a value of an entity that can be tweened. Get returns false if the entity doesn't have the value, in which case the tween skips it.
+type Property[T Value] struct {
+	Name string
+	Get  func(world *ecs.World, id ecs.Id) (T, bool)
+	Set  func(world *ecs.World, id ecs.Id, val T)
+}
+
+// Returns a property which reads and writes a field of the component C
+//
+//	var Health = tween.Field("game.Health", func(h *Health) *float64 { return &h.Current })
+func Field[C any, T Value](name string, field func(*C) *T) Property[T] {
+	return Property[T]{
+		Name: name,
+		Get: func(world *ecs.World, id ecs.Id) (T, bool) {
+			comp := ecs.ReadPtr[C](world, id)
+			if comp == nil {
+				var zero T
+				return zero, false
+			}
+			return *field(comp), true
+		},
+		Set: func(world *ecs.World, id ecs.Id, val T) {
+			comp := ecs.ReadPtr[C](world, id)
+			if comp == nil {
+				return
+			}
+			*field(comp) = val
+		},
+	}
+}
+
+var registry = make(map[string]any)
+
+// Registers a property by name, so that tweens can be built from data with Lookup. Panics if the name is already registered.
+func Register[T Value](prop Property[T]) Property[T] {
+	_, exists := registry[prop.Name]
+	if exists {
+		panic(fmt.Sprintf("tween: property %s is already registered", prop.Name))
+	}
+	registry[prop.Name] = prop
+	return prop
+}
+
+// Returns the registered property with the name. Returns false if there isn't one, or if it has a different value type.
+func Lookup[T Value](name string) (Property[T], bool) {
+	prop, ok := registry[name].(Property[T])
+	return prop, ok
+}
+
+// The transform.Local properties
+var (
+	Position = Register(Field("transform.Position", func(l *transform.Local) *glm.Vec2 { return &l.Pos }))
+	Rotation = Register(Field("transform.Rotation", func(l *transform.Local) *float64 { return &l.Rot }))
+	Scale    = Register(Field("transform.Scale", func(l *transform.Local) *glm.Vec2 { return &l.Scale }))
+)
+
+// Interpolates between a and b with the curve
+func lerp[T Value](curve interp.Interp, a, b T, t float64) T {
+	switch a := any(a).(type) {
+	case float64:
+		return any(curve.Float64(a, any(b).(float64), t)).(T)
+	case glm.Vec2:
+		return any(curve.Vec2(a, any(b).(glm.Vec2), t)).(T)
+	case glm.RGBA:
+		return any(interp.Color(curve, a, any(b).(glm.RGBA), t)).(T)
+	}
+	panic("tween: unsupported value type")
+}
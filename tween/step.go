@@ -0,0 +1,290 @@
+package tween
+
+import (
+	"time"
+
+	"github.com/unitoftime/ecs"
+	"github.com/unitoftime/flow/interp"
+)
+
+// Forever is the duration of steps which never finish, like a Repeat with a negative count
+const Forever time.Duration = -1
+
+// Step is one part of a tween. Steps are evaluated at a time since they started, so a tween can jump forward (or backward, when yoyoing) by any amount and every property still ends up where it should be.
+//
+// Steps hold the start values that they capture from the entity, so a step must only be used by one tween.
+type Step interface {
+	// Returns the length of the step, or Forever
+	Duration() time.Duration
+
+	// Applies the step to the entity at time t since the step started
+	apply(world *ecs.World, id ecs.Id, t time.Duration)
+
+	// Returns true if the step has been applied since it was last reset
+	started() bool
+
+	// Forgets the captured start values, so that they are captured again the next time the step is applied
+	reset()
+}
+
+//--------------------------------------------------------------------------------
+// - Property
+//--------------------------------------------------------------------------------
+
+// PropertyStep moves a property from a start value to an end value
+type PropertyStep[T Value] struct {
+	prop     Property[T]
+	from, to T
+	hasFrom  bool
+	dur      time.Duration
+	curve    interp.Interp
+	began    bool
+}
+
+// Returns a step which moves the property to the value over the duration, along the curve. The start value is read from the entity when the step begins, unless it is set with From. A nil curve is linear.
+func To[T Value](prop Property[T], to T, dur time.Duration, curve interp.Interp) *PropertyStep[T] {
+	if curve == nil {
+		curve = interp.Linear
+	}
+	return &PropertyStep[T]{
+		prop:  prop,
+		to:    to,
+		dur:   dur,
+		curve: curve,
+	}
+}
+
+// Sets the start value of the step
+func (s *PropertyStep[T]) From(from T) *PropertyStep[T] {
+	s.from = from
+	s.hasFrom = true
+	return s
+}
+
+func (s *PropertyStep[T]) Duration() time.Duration {
+	return s.dur
+}
+
+func (s *PropertyStep[T]) apply(world *ecs.World, id ecs.Id, t time.Duration) {
+	if !s.began {
+		if !s.hasFrom {
+			from, ok := s.prop.Get(world, id)
+			if !ok {
+				return
+			}
+			s.from = from
+		}
+		s.began = true
+	}
+
+	alpha := 1.0
+	if s.dur > 0 {
+		alpha = min(max(t.Seconds()/s.dur.Seconds(), 0), 1)
+	}
+	s.prop.Set(world, id, lerp(s.curve, s.from, s.to, alpha))
+}
+
+func (s *PropertyStep[T]) started() bool {
+	return s.began
+}
+
+func (s *PropertyStep[T]) reset() {
+	s.began = false
+}
+
+//--------------------------------------------------------------------------------
+// - Delay
+//--------------------------------------------------------------------------------
+
+type delayStep struct {
+	dur   time.Duration
+	began bool
+}
+
+// Returns a step which does nothing for the duration
+func Delay(dur time.Duration) Step {
+	return &delayStep{dur: dur}
+}
+
+func (s *delayStep) Duration() time.Duration                            { return s.dur }
+func (s *delayStep) apply(world *ecs.World, id ecs.Id, t time.Duration) { s.began = true }
+func (s *delayStep) started() bool                                      { return s.began }
+func (s *delayStep) reset()                                             { s.began = false }
+
+//--------------------------------------------------------------------------------
+// - Sequence
+//--------------------------------------------------------------------------------
+
+type sequenceStep struct {
+	steps  []Step
+	starts []time.Duration // The start time of each step, up to the first step that lasts forever
+	dur    time.Duration
+}
+
+// Returns a step which runs the steps one after another
+func Sequence(steps ...Step) Step {
+	s := &sequenceStep{steps: steps}
+	for _, step := range steps {
+		s.starts = append(s.starts, s.dur)
+		dur := step.Duration()
+		if dur == Forever {
+			s.dur = Forever
+			break
+		}
+		s.dur += dur
+	}
+	return s
+}
+
+func (s *sequenceStep) Duration() time.Duration {
+	return s.dur
+}
+
+func (s *sequenceStep) apply(world *ecs.World, id ecs.Id, t time.Duration) {
+	// When playing backwards or looping, steps that haven't been reached yet are rewound to their start. They are rewound last to first, and before the reached steps are applied, so that the earliest step wins when steps share a property.
+	for i := len(s.starts) - 1; i >= 0; i-- {
+		if t < s.starts[i] && s.steps[i].started() {
+			s.steps[i].apply(world, id, 0)
+		}
+	}
+
+	for i, start := range s.starts {
+		if t < start {
+			break
+		}
+		step := s.steps[i]
+		dur := step.Duration()
+		if dur == Forever {
+			step.apply(world, id, t-start)
+		} else {
+			// Steps that were skipped over are finished, so that their end values are applied
+			step.apply(world, id, min(t-start, dur))
+		}
+	}
+}
+
+func (s *sequenceStep) started() bool {
+	return anyStarted(s.steps)
+}
+
+func (s *sequenceStep) reset() {
+	for _, step := range s.steps {
+		step.reset()
+	}
+}
+
+//--------------------------------------------------------------------------------
+// - Parallel
+//--------------------------------------------------------------------------------
+
+type parallelStep struct {
+	steps []Step
+}
+
+// Returns a step which runs the steps at the same time. It lasts as long as its longest step.
+func Parallel(steps ...Step) Step {
+	return &parallelStep{steps}
+}
+
+func (s *parallelStep) Duration() time.Duration {
+	longest := time.Duration(0)
+	for _, step := range s.steps {
+		dur := step.Duration()
+		if dur == Forever {
+			return Forever
+		}
+		longest = max(longest, dur)
+	}
+	return longest
+}
+
+func (s *parallelStep) apply(world *ecs.World, id ecs.Id, t time.Duration) {
+	for _, step := range s.steps {
+		dur := step.Duration()
+		if dur == Forever {
+			step.apply(world, id, t)
+		} else {
+			step.apply(world, id, min(t, dur))
+		}
+	}
+}
+
+func (s *parallelStep) started() bool {
+	return anyStarted(s.steps)
+}
+
+func (s *parallelStep) reset() {
+	for _, step := range s.steps {
+		step.reset()
+	}
+}
+
+func anyStarted(steps []Step) bool {
+	for _, step := range steps {
+		if step.started() {
+			return true
+		}
+	}
+	return false
+}
+
+//--------------------------------------------------------------------------------
+// - Repeat
+//--------------------------------------------------------------------------------
+
+type repeatStep struct {
+	step  Step
+	count int
+	yoyo  bool
+}
+
+// Returns a step which plays the step count times, or forever if count is negative. If yoyo is set, every other play runs backwards. The start values are only captured on the first play, so every play moves between the same values.
+func Repeat(step Step, count int, yoyo bool) Step {
+	return &repeatStep{
+		step:  step,
+		count: count,
+		yoyo:  yoyo,
+	}
+}
+
+func (s *repeatStep) Duration() time.Duration {
+	dur := s.step.Duration()
+	if s.count < 0 || dur == Forever {
+		return Forever
+	}
+	return dur * time.Duration(s.count)
+}
+
+func (s *repeatStep) apply(world *ecs.World, id ecs.Id, t time.Duration) {
+	dur := s.step.Duration()
+	if s.count == 0 {
+		return
+	}
+	if dur == Forever {
+		s.step.apply(world, id, t)
+		return
+	}
+	if dur <= 0 {
+		s.step.apply(world, id, 0)
+		return
+	}
+
+	play := int(t / dur)
+	local := t % dur
+	if s.count > 0 && play >= s.count {
+		play = s.count - 1
+		local = dur
+	}
+	if s.yoyo && play%2 == 1 {
+		local = dur - local
+	}
+	s.step.apply(world, id, local)
+}
+
+func (s *repeatStep) started() bool {
+	return s.step.started()
+}
+
+func (s *repeatStep) reset() {
+	s.step.reset()
+}
@@ -0,0 +1,147 @@
+package tween
+
+import (
+	"time"
+
+	"github.com/unitoftime/ecs"
+	"github.com/unitoftime/flow"
+	"github.com/unitoftime/flow/transform"
+)
+
+//go:generate go run ../../cod/cmd/cod
+
+// Note: Tween isn't registered for snapshots because its steps hold functions and captured state
+
+// Tween animates the properties of its entity with a step. Steps are usually built from To, Delay, Sequence, Parallel and Repeat:
+//
+//	ecs.Write(world, id, tween.New("pop",
+//		tween.Parallel(
+//			tween.To(tween.Scale, glm.Vec2{2, 2}, 200*time.Millisecond, interp.EaseOut),
+//			tween.To(render.SpriteColor, glm.RGBA{1, 1, 1, 0}, 200*time.Millisecond, nil),
+//		),
+//		tween.Delay(time.Second),
+//	))
+//
+// When the tween finishes, a Completed event is sent and the Tween component is removed (or the entity is despawned, if Despawn is set).
+//
+//cod:component
+type Tween struct {
+	Name    string // Sent with the Completed event
+	Step    Step
+	Elapsed time.Duration
+	Speed   float64 // Multiplies the frame time, 0 is treated as 1
+	Paused  bool
+	Despawn bool // Despawns the entity when the tween finishes
+}
+
+// Returns a tween which runs the steps one after another
+func New(name string, steps ...Step) Tween {
+	var step Step
+	if len(steps) == 1 {
+		step = steps[0]
+	} else {
+		step = Sequence(steps...)
+	}
+	return Tween{
+		Name: name,
+		Step: step,
+	}
+}
+
+// Returns true if the tween has run to the end of its step
+func (t *Tween) Done() bool {
+	if t.Step == nil {
+		return true
+	}
+	dur := t.Step.Duration()
+	return dur != Forever && t.Elapsed >= dur
+}
+
+// Restarts the tween from the beginning. The start values are captured from the entity again.
+func (t *Tween) Restart() {
+	t.Elapsed = 0
+	if t.Step != nil {
+		t.Step.reset()
+	}
+}
+
+// Advances the tween by dt and applies it to the entity. Returns true if the tween finished during this update.
+func (t *Tween) Update(world *ecs.World, id ecs.Id, dt time.Duration) bool {
+	if t.Paused || t.Done() {
+		return false
+	}
+	speed := t.Speed
+	if speed == 0 {
+		speed = 1
+	}
+	t.Elapsed += time.Duration(float64(dt) * speed)
+
+	dur := t.Step.Duration()
+	if dur != Forever {
+		t.Elapsed = min(t.Elapsed, dur)
+	}
+	t.Step.apply(world, id, t.Elapsed)
+	return t.Done()
+}
+
+// Completed is sent when a tween finishes
+type Completed struct {
+	Entity ecs.Id
+	Name   string
+}
+
+// Returns a system which updates every tween by the app's scaled time, so that tweens respect pause and time scaling. Finished tweens send a Completed event, and are removed.
+func UpdateSystem(world *ecs.World) ecs.System {
+	query := ecs.Query1[Tween](world)
+	clock := ecs.GetResource[flow.Time](world)
+	events := ecs.GetResource[flow.Events[Completed]](world)
+
+	type finished struct {
+		id      ecs.Id
+		despawn bool
+	}
+	var done []finished
+
+	return ecs.System{
+		Name: "tween.UpdateSystem",
+		Func: func(dt time.Duration) {
+			if clock != nil {
+				dt = clock.Delta
+			}
+
+			done = done[:0]
+			query.MapId(func(id ecs.Id, t *Tween) {
+				if t.Update(world, id, dt) {
+					done = append(done, finished{id, t.Despawn})
+					if events != nil {
+						events.Send(Completed{Entity: id, Name: t.Name})
+					}
+				}
+			})
+
+			for _, f := range done {
+				if f.despawn {
+					ecs.Delete(world, f.id)
+				} else {
+					ecs.DeleteComponent(world, f.id, Tween{})
+				}
+			}
+		},
+	}
+}
+
+// Plugin adds the Completed event, and the system which updates tweens in StageFixedUpdate, so that transform tweens are resolved and interpolated like any other movement
+type Plugin struct{}
+
+func (p Plugin) Dependencies() []flow.Plugin {
+	return []flow.Plugin{
+		transform.DefaultPlugin{},
+	}
+}
+
+func (p Plugin) Initialize(world *ecs.World) {
+	app := flow.MustApp(world)
+
+	flow.AddEvent[Completed](app)
+	app.AddSystems(ecs.StageFixedUpdate, UpdateSystem(world))
+}
@@ -0,0 +1,182 @@
+package tween
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/unitoftime/ecs"
+	"github.com/unitoftime/flow"
+	"github.com/unitoftime/flow/glm"
+	"github.com/unitoftime/flow/transform"
+)
+
+const step = 100 * time.Millisecond
+
+func newApp() (*flow.App, ecs.Id) {
+	app := flow.NewApp()
+	app.SetFixedTimeStep(step)
+	app.AddPlugin(transform.DefaultPlugin{})
+	app.AddPlugin(Plugin{})
+	app.Step(0) // Build the app
+
+	world := app.World()
+	id := world.NewId()
+	world.Write(id, transform.Local{transform.Default()}, transform.Global{transform.Default()})
+	return app, id
+}
+
+func expectFloat(t *testing.T, name string, got, expected float64) {
+	t.Helper()
+	if math.Abs(got-expected) > 1e-9 {
+		t.Errorf("%s: expected %v, got %v", name, expected, got)
+	}
+}
+
+func expectVec(t *testing.T, name string, got, expected glm.Vec2) {
+	t.Helper()
+	if math.Abs(got.X-expected.X) > 1e-9 || math.Abs(got.Y-expected.Y) > 1e-9 {
+		t.Errorf("%s: expected %v, got %v", name, expected, got)
+	}
+}
+
+func TestPlugin(t *testing.T) {
+	app, id := newApp()
+	world := app.World()
+	reader := flow.NewEventReader[Completed](world)
+
+	world.Write(id, New("intro",
+		To(Position, glm.Vec2{10, 0}, time.Second, nil),
+		Parallel(
+			To(Rotation, 1, 500*time.Millisecond, nil),
+			To(Scale, glm.Vec2{3, 3}, time.Second, nil),
+		),
+		Delay(500*time.Millisecond),
+	))
+
+	local := func() transform.Local {
+		l, _ := ecs.Read[transform.Local](world, id)
+		return l
+	}
+
+	for i := 0; i < 5; i++ {
+		app.Step(step)
+	}
+	expectVec(t, "halfway", local().Pos, glm.Vec2{5, 0})
+
+	for i := 0; i < 10; i++ {
+		app.Step(step)
+	}
+	expectVec(t, "moved", local().Pos, glm.Vec2{10, 0})
+	expectFloat(t, "rotated", local().Rot, 1)
+	expectVec(t, "half scaled", local().Scale, glm.Vec2{2, 2})
+
+	// Pausing the app pauses the tweens
+	clock := ecs.GetResource[flow.Time](world)
+	clock.Pause()
+	for i := 0; i < 10; i++ {
+		app.Step(step)
+	}
+	expectVec(t, "paused", local().Scale, glm.Vec2{2, 2})
+	clock.Resume()
+
+	for i := 0; i < 9; i++ {
+		app.Step(step)
+	}
+	expectVec(t, "scaled", local().Scale, glm.Vec2{3, 3})
+	if _, ok := ecs.Read[Tween](world, id); !ok || reader.Len() != 0 {
+		t.Errorf("expected tween to still be running its delay")
+	}
+
+	app.Step(step)
+	if _, ok := ecs.Read[Tween](world, id); ok {
+		t.Errorf("expected finished tween to be removed")
+	}
+	var completed []Completed
+	for e := range reader.Read() {
+		completed = append(completed, e)
+	}
+	if len(completed) != 1 || completed[0] != (Completed{id, "intro"}) {
+		t.Errorf("expected one completed event, got %v", completed)
+	}
+
+	// Despawning tweens delete their entity
+	tw := New("fade", Delay(step))
+	tw.Despawn = true
+	world.Write(id, tw)
+	app.Step(step)
+	if world.Exists(id) {
+		t.Errorf("expected entity to be despawned")
+	}
+}
+
+func TestRepeatAndYoyo(t *testing.T) {
+	world := ecs.NewWorld()
+	id := world.NewId()
+	world.Write(id, transform.Local{transform.Default()})
+	rot := func() float64 {
+		l, _ := ecs.Read[transform.Local](world, id)
+		return l.Rot
+	}
+
+	tw := New("spin", Repeat(To(Rotation, 10, time.Second, nil), 3, true))
+	if tw.Step.Duration() != 3*time.Second {
+		t.Errorf("expected three plays, got %v", tw.Step.Duration())
+	}
+
+	tw.Update(world, id, 250*time.Millisecond)
+	expectFloat(t, "forward", rot(), 2.5)
+	tw.Update(world, id, time.Second)
+	expectFloat(t, "backward", rot(), 7.5)
+	tw.Update(world, id, time.Second)
+	expectFloat(t, "forward again", rot(), 2.5)
+
+	// Jumping past the end lands on the end of the last play
+	if !tw.Update(world, id, 10*time.Second) {
+		t.Errorf("expected tween to finish")
+	}
+	expectFloat(t, "end", rot(), 10)
+	if tw.Update(world, id, time.Second) {
+		t.Errorf("expected finished tween to not finish again")
+	}
+
+	// Looping sequences restart from the first value
+	tw = New("patrol", Repeat(Sequence(
+		To(Position, glm.Vec2{10, 0}, time.Second, nil).From(glm.Vec2{0, 0}),
+		To(Position, glm.Vec2{10, 10}, time.Second, nil),
+	), -1, false))
+	tw.Speed = 2
+	tw.Update(world, id, 750*time.Millisecond) // 1.5s
+	pos, _ := ecs.Read[transform.Local](world, id)
+	expectVec(t, "second step", pos.Pos, glm.Vec2{10, 5})
+	tw.Update(world, id, 500*time.Millisecond) // 2.5s
+	pos, _ = ecs.Read[transform.Local](world, id)
+	expectVec(t, "looped", pos.Pos, glm.Vec2{5, 0})
+	if tw.Done() {
+		t.Errorf("expected endless tween to never finish")
+	}
+
+	// Restarting captures the start values again
+	tw = New("", To(Rotation, 0, time.Second, nil))
+	tw.Update(world, id, 500*time.Millisecond)
+	expectFloat(t, "from end", rot(), 5)
+	tw.Restart()
+	tw.Update(world, id, 500*time.Millisecond)
+	expectFloat(t, "restarted", rot(), 2.5)
+}
+
+func TestRegistry(t *testing.T) {
+	if _, ok := Lookup[glm.Vec2]("transform.Position"); !ok {
+		t.Errorf("expected position to be registered")
+	}
+	if _, ok := Lookup[float64]("transform.Position"); ok {
+		t.Errorf("expected lookup with the wrong type to fail")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected duplicate registration to panic")
+		}
+	}()
+	Register(Field("transform.Rotation", func(l *transform.Local) *float64 { return &l.Rot }))
+}
@@ -0,0 +1,350 @@
+package ui
+
+import (
+	"hash/fnv"
+	"slices"
+	"strings"
+
+	"github.com/unitoftime/flow/glm"
+)
+
+// Input is the state of the user's input for one frame. Games fill it from their window (or an input.Map), and tests fill it directly to simulate input.
+type Input struct {
+	Mouse     glm.Vec2
+	MouseDown bool    // The primary mouse button is held
+	Scroll    float64 // Mouse wheel movement this frame, positive scrolls towards the top of the content
+
+	Text []rune // Characters typed this frame
+
+	// Navigation, from the keyboard or a gamepad. These should only be true on the frame that the key is pressed (or repeated).
+	NavUp, NavDown    bool // Moves focus to the previous or next widget
+	NavLeft, NavRight bool // Adjusts the focused slider, or moves the text cursor
+	Activate          bool // Clicks the focused widget
+	Cancel            bool // Closes dropdowns, and unfocuses
+	Backspace         bool
+}
+
+// Id identifies a widget across frames. It is the hash of the widget's label and the id stack.
+type Id uint64
+
+// The persistent state of a widget
+type widgetState struct {
+	open      bool    // Dropdowns
+	cursor    int     // Text inputs
+	scroll    float64 // Scroll panels
+	hovered   int     // Tooltips: The number of frames that the widget has been hovered
+	seenFrame uint64
+}
+
+// Context holds the state of the UI between frames. Every frame, call Begin with the frame's input, then call the widget functions, then call End. Widgets don't draw anything directly, they add commands to the draw list which can then be drawn by a Painter.
+type Context struct {
+	Style Style
+
+	frame     uint64
+	input     Input
+	prevMouse bool
+	pressed   bool // The mouse was pressed this frame
+	released  bool // The mouse was released this frame
+
+	hot, active, focus Id
+	hotThisFrame       bool
+
+	focusOrder, prevFocusOrder  []Id
+	navDelta                    int // -1, 0, 1: The focus was moved this frame
+	navCaptured, nextNavCapture Id  // A widget (ie an open dropdown) which uses navigation itself
+
+	idStack []uint64
+	states  map[Id]*widgetState
+
+	clipStack []glm.Rect
+	commands  []Command
+	overlay   []Command  // Commands which are drawn on top of everything (dropdown lists, tooltips)
+	blockers  []glm.Rect // Overlay regions from the last frame, which block the mouse from reaching widgets under them
+	nextBlock []glm.Rect
+
+	scrollStack []scrollPanel
+	last        lastItem
+}
+
+func NewContext(style Style) *Context {
+	return &Context{
+		Style:  style,
+		states: make(map[Id]*widgetState),
+	}
+}
+
+// Starts a frame with the input
+func (c *Context) Begin(input Input) {
+	c.frame++
+	c.input = input
+	c.pressed = input.MouseDown && !c.prevMouse
+	c.released = !input.MouseDown && c.prevMouse
+	c.prevMouse = input.MouseDown
+
+	c.hot = 0
+	c.hotThisFrame = false
+	c.commands = c.commands[:0]
+	c.overlay = c.overlay[:0]
+	c.clipStack = c.clipStack[:0]
+	c.blockers, c.nextBlock = c.nextBlock, c.blockers[:0]
+	c.prevFocusOrder, c.focusOrder = c.focusOrder, c.prevFocusOrder[:0]
+
+	c.navCaptured, c.nextNavCapture = c.nextNavCapture, 0
+	c.last = lastItem{}
+
+	// Navigation moves the focus through the widgets in the order that they were added last frame
+	c.navDelta = 0
+	if c.navCaptured != 0 && c.navCaptured == c.focus {
+		return
+	}
+	if input.NavDown {
+		c.navDelta = 1
+	} else if input.NavUp {
+		c.navDelta = -1
+	}
+	if c.navDelta != 0 && len(c.prevFocusOrder) > 0 {
+		idx := slices.Index(c.prevFocusOrder, c.focus)
+		switch {
+		case idx < 0 && c.navDelta > 0:
+			idx = 0
+		case idx < 0:
+			idx = len(c.prevFocusOrder) - 1
+		default:
+			idx = (idx + c.navDelta + len(c.prevFocusOrder)) % len(c.prevFocusOrder)
+		}
+		c.focus = c.prevFocusOrder[idx]
+	}
+	if input.Cancel {
+		c.focus = 0
+	}
+}
+
+// Ends the frame, and returns the draw list
+func (c *Context) End() []Command {
+	// Clicking on empty space clears the focus
+	if c.pressed && !c.hotThisFrame {
+		c.focus = 0
+	}
+	if !c.input.MouseDown {
+		c.active = 0
+	}
+
+	// Drop the state of widgets that weren't used this frame
+	for id, s := range c.states {
+		if s.seenFrame != c.frame {
+			delete(c.states, id)
+		}
+	}
+
+	c.commands = append(c.commands, c.overlay...)
+	return c.commands
+}
+
+// Returns the draw list of the last frame
+func (c *Context) Commands() []Command {
+	return c.commands
+}
+
+// Returns the focused widget, or 0 if nothing is focused
+func (c *Context) Focus() Id {
+	return c.focus
+}
+
+// Focuses the widget with the label
+func (c *Context) SetFocus(label string) {
+	c.focus = c.id(label)
+}
+
+// Returns the hovered widget, or 0 if nothing is hovered
+func (c *Context) Hot() Id {
+	return c.hot
+}
+
+// Returns true if a widget is using the mouse. Games can use this to ignore clicks that were meant for the UI.
+func (c *Context) WantsMouse() bool {
+	return c.hotThisFrame || c.active != 0
+}
+
+// Pushes an id onto the id stack, so that widgets with the same label in different places (ie rows of a list) get different ids
+func (c *Context) PushId(id uint64) {
+	c.idStack = append(c.idStack, id)
+}
+
+func (c *Context) PopId() {
+	c.idStack = c.idStack[:len(c.idStack)-1]
+}
+
+// Returns the id of a label. Everything after "##" in a label is only used for the id, and isn't displayed.
+func (c *Context) Id(label string) Id {
+	return c.id(label)
+}
+
+func (c *Context) id(label string) Id {
+	h := fnv.New64a()
+	for _, id := range c.idStack {
+		var buf [8]byte
+		for i := range buf {
+			buf[i] = byte(id >> (8 * i))
+		}
+		h.Write(buf[:])
+	}
+	h.Write([]byte(label))
+	return Id(h.Sum64())
+}
+
+// Returns the part of the label that is displayed
+func display(label string) string {
+	before, _, _ := strings.Cut(label, "##")
+	return before
+}
+
+func (c *Context) state(id Id) *widgetState {
+	s, ok := c.states[id]
+	if !ok {
+		s = &widgetState{}
+		c.states[id] = s
+	}
+	s.seenFrame = c.frame
+	return s
+}
+
+//--------------------------------------------------------------------------------
+// - Interaction
+//--------------------------------------------------------------------------------
+
+// The result of the shared widget interaction
+type interaction struct {
+	hovered bool
+	clicked bool // Clicked with the mouse, or activated while focused
+	focused bool
+	state   State
+}
+
+// Handles hovering, pressing, clicking and focus for a widget in the rect
+func (c *Context) interact(id Id, r glm.Rect, focusable bool) interaction {
+	if focusable {
+		c.focusOrder = append(c.focusOrder, id)
+	}
+
+	hovered := c.mouseOver(r)
+	if hovered {
+		c.hot = id
+		c.hotThisFrame = true
+		if c.pressed {
+			c.active = id
+			if focusable {
+				c.focus = id
+			}
+		}
+	}
+
+	ret := interaction{hovered: hovered}
+	if c.released && c.active == id && hovered {
+		ret.clicked = true
+	}
+	ret.focused = c.focus == id
+	if ret.focused && c.input.Activate {
+		ret.clicked = true
+	}
+
+	switch {
+	case c.active == id && c.input.MouseDown:
+		ret.state = StateActive
+	case hovered:
+		ret.state = StateHover
+	case ret.focused:
+		ret.state = StateFocus
+	}
+	return ret
+}
+
+// Returns true if the mouse is over the rect, inside the current clip, and not blocked by an overlay
+func (c *Context) mouseOver(r glm.Rect) bool {
+	m := c.input.Mouse
+	if !inside(r, m) {
+		return false
+	}
+	if len(c.clipStack) > 0 && !inside(c.clipStack[len(c.clipStack)-1], m) {
+		return false
+	}
+	for _, b := range c.blockers {
+		if inside(b, m) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns true if the point is inside or on the edge of the rect
+func inside(r glm.Rect, p glm.Vec2) bool {
+	return p.X >= r.Min.X && p.X <= r.Max.X && p.Y >= r.Min.Y && p.Y <= r.Max.Y
+}
+
+//--------------------------------------------------------------------------------
+// - Drawing
+//--------------------------------------------------------------------------------
+
+type CommandKind uint8
+
+const (
+	CommandRect CommandKind = iota
+	CommandText
+	CommandPushClip
+	CommandPopClip
+)
+
+// Command is one entry of the draw list
+type Command struct {
+	Kind  CommandKind
+	Rect  glm.Rect
+	Color glm.RGBA
+	Text  string
+}
+
+// Painter draws the draw list. Implement it with your renderer (ie glitch) to display the UI.
+type Painter interface {
+	Rect(r glm.Rect, color glm.RGBA)
+	Text(r glm.Rect, text string, color glm.RGBA)
+	PushClip(r glm.Rect)
+	PopClip()
+}
+
+// Draws the commands with the painter
+func Paint(p Painter, commands []Command) {
+	for _, cmd := range commands {
+		switch cmd.Kind {
+		case CommandRect:
+			p.Rect(cmd.Rect, cmd.Color)
+		case CommandText:
+			p.Text(cmd.Rect, cmd.Text, cmd.Color)
+		case CommandPushClip:
+			p.PushClip(cmd.Rect)
+		case CommandPopClip:
+			p.PopClip()
+		}
+	}
+}
+
+func (c *Context) drawRect(r glm.Rect, color glm.RGBA) {
+	c.commands = append(c.commands, Command{Kind: CommandRect, Rect: r, Color: color})
+}
+
+func (c *Context) drawText(r glm.Rect, text string, color glm.RGBA) {
+	if text == "" {
+		return
+	}
+	c.commands = append(c.commands, Command{Kind: CommandText, Rect: r, Color: color, Text: text})
+}
+
+func (c *Context) pushClip(r glm.Rect) {
+	if len(c.clipStack) > 0 {
+		r = r.Intersect(c.clipStack[len(c.clipStack)-1])
+	}
+	c.clipStack = append(c.clipStack, r)
+	c.commands = append(c.commands, Command{Kind: CommandPushClip, Rect: r})
+}
+
+func (c *Context) popClip() {
+	c.clipStack = c.clipStack[:len(c.clipStack)-1]
+	c.commands = append(c.commands, Command{Kind: CommandPopClip})
+}
@@ -0,0 +1,89 @@
+package ui
+
+import (
+	"unicode/utf8"
+
+	"github.com/unitoftime/flow/glm"
+)
+
+// State is the interaction state of a widget, used to pick its colors
+type State uint8
+
+const (
+	StateNormal State = iota
+	StateHover
+	StateActive
+	StateFocus
+)
+
+// Colors holds one color for each widget state
+type Colors [4]glm.RGBA
+
+func (c Colors) Get(state State) glm.RGBA {
+	return c[state]
+}
+
+// Style is the theme of the UI
+type Style struct {
+	Background Colors // Buttons, toggle boxes, slider tracks, text boxes
+	Accent     Colors // Toggle checks, slider handles, the selected dropdown option
+	Text       glm.RGBA
+	TextMuted  glm.RGBA // Placeholder and disabled text
+	Panel      glm.RGBA // Scroll panels and dropdown lists
+	Tooltip    glm.RGBA
+	Cursor     glm.RGBA
+
+	Padding      float64 // Space between the edge of a widget and its contents
+	HandleSize   float64 // Width of the slider handle
+	ItemHeight   float64 // Height of dropdown options
+	ScrollSpeed  float64 // Pixels scrolled per unit of mouse wheel
+	SliderStep   float64 // Fraction of the slider range moved by NavLeft and NavRight
+	TooltipDelay int     // Frames that a widget must be hovered before its tooltip shows
+
+	// Measures the width of text, so that the text cursor and tooltips can be placed. Defaults to CharWidth per rune.
+	MeasureText func(string) float64
+	CharWidth   float64
+}
+
+func DefaultStyle() Style {
+	return Style{
+		Background: Colors{
+			StateNormal: glm.RGBA{0.2, 0.2, 0.2, 1},
+			StateHover:  glm.RGBA{0.3, 0.3, 0.3, 1},
+			StateActive: glm.RGBA{0.15, 0.15, 0.15, 1},
+			StateFocus:  glm.RGBA{0.25, 0.25, 0.35, 1},
+		},
+		Accent: Colors{
+			StateNormal: glm.RGBA{0.3, 0.5, 0.9, 1},
+			StateHover:  glm.RGBA{0.4, 0.6, 1, 1},
+			StateActive: glm.RGBA{0.2, 0.4, 0.8, 1},
+			StateFocus:  glm.RGBA{0.4, 0.6, 1, 1},
+		},
+		Text:      glm.White,
+		TextMuted: glm.RGBA{0.6, 0.6, 0.6, 1},
+		Panel:     glm.RGBA{0.1, 0.1, 0.1, 0.9},
+		Tooltip:   glm.RGBA{0, 0, 0, 0.9},
+		Cursor:    glm.White,
+
+		Padding:      4,
+		HandleSize:   10,
+		ItemHeight:   20,
+		ScrollSpeed:  20,
+		SliderStep:   0.05,
+		TooltipDelay: 30,
+
+		CharWidth: 8,
+	}
+}
+
+func (s *Style) measure(text string) float64 {
+	if s.MeasureText != nil {
+		return s.MeasureText(text)
+	}
+	return float64(utf8.RuneCountInString(text)) * s.CharWidth
+}
+
+// Returns the padding as a rect, for Rect.Unpad
+func (s *Style) pad() glm.Rect {
+	return glm.R(s.Padding, s.Padding, s.Padding, s.Padding)
+}
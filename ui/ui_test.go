@@ -0,0 +1,313 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/unitoftime/flow/glm"
+)
+
+var (
+	buttonRect   = glm.R(0, 80, 100, 100)
+	toggleRect   = glm.R(0, 60, 100, 80)
+	sliderRect   = glm.R(0, 40, 110, 60)
+	textRect     = glm.R(0, 20, 100, 40)
+	dropdownRect = glm.R(0, 0, 100, 20)
+)
+
+// A test form with one of each widget
+type form struct {
+	ctx *Context
+
+	clicked  bool
+	toggled  bool
+	volume   float64
+	name     string
+	option   int
+	commands []Command
+}
+
+func newForm() *form {
+	style := DefaultStyle()
+	style.Padding = 0
+	style.HandleSize = 10
+	style.SliderStep = 0.1
+	style.TooltipDelay = 2
+	return &form{ctx: NewContext(style)}
+}
+
+func (f *form) frame(input Input) {
+	c := f.ctx
+	c.Begin(input)
+	f.clicked = c.Button("Play", buttonRect)
+	c.Toggle("Fullscreen", toggleRect, &f.toggled)
+	c.Slider("Volume", sliderRect, &f.volume, 0, 1)
+	c.TextInput("Name", textRect, &f.name)
+	c.Dropdown("Mode", dropdownRect, []string{"Easy", "Normal", "Hard"}, &f.option)
+	f.commands = c.End()
+}
+
+// Presses and releases the mouse at the position
+func (f *form) click(pos glm.Vec2) {
+	f.frame(Input{Mouse: pos, MouseDown: true})
+	f.frame(Input{Mouse: pos})
+}
+
+func TestButton(t *testing.T) {
+	f := newForm()
+	center := buttonRect.Center()
+
+	f.frame(Input{Mouse: center})
+	if f.ctx.Hot() != f.ctx.Id("Play") || f.clicked {
+		t.Errorf("expected button to be hovered")
+	}
+
+	f.frame(Input{Mouse: center, MouseDown: true})
+	if f.clicked || f.ctx.Focus() != f.ctx.Id("Play") {
+		t.Errorf("expected button to be focused but not clicked on press")
+	}
+	f.frame(Input{Mouse: center})
+	if !f.clicked {
+		t.Errorf("expected button to be clicked on release")
+	}
+
+	// Pressing and dragging off of the button doesn't click it
+	f.frame(Input{Mouse: center, MouseDown: true})
+	f.frame(Input{Mouse: glm.Vec2{500, 500}})
+	if f.clicked {
+		t.Errorf("expected no click when released outside")
+	}
+	if f.ctx.WantsMouse() {
+		t.Errorf("expected ui to not want the mouse")
+	}
+
+	// The hovered button is drawn with the hover color
+	f.frame(Input{Mouse: center})
+	if f.commands[0].Color != f.ctx.Style.Background.Get(StateHover) {
+		t.Errorf("expected hover color, got %v", f.commands[0].Color)
+	}
+}
+
+func TestToggleAndSlider(t *testing.T) {
+	f := newForm()
+
+	f.click(toggleRect.Center())
+	if !f.toggled {
+		t.Errorf("expected toggle to be on")
+	}
+
+	// Dragging sets the value from the mouse position along the track
+	f.frame(Input{Mouse: glm.Vec2{5, 50}, MouseDown: true})
+	f.frame(Input{Mouse: glm.Vec2{80, 50}, MouseDown: true})
+	if f.volume != 0.75 {
+		t.Errorf("expected 0.75, got %v", f.volume)
+	}
+	f.frame(Input{Mouse: glm.Vec2{500, 50}, MouseDown: true})
+	if f.volume != 1 {
+		t.Errorf("expected value to be clamped, got %v", f.volume)
+	}
+	f.frame(Input{})
+
+	// Focused sliders step with left and right
+	f.frame(Input{NavLeft: true})
+	f.frame(Input{NavLeft: true})
+	if f.volume < 0.799 || f.volume > 0.801 {
+		t.Errorf("expected 0.8, got %v", f.volume)
+	}
+}
+
+func TestTextInput(t *testing.T) {
+	f := newForm()
+
+	f.click(textRect.Center())
+	f.frame(Input{Text: []rune("hllo")})
+	f.frame(Input{NavLeft: true})
+	f.frame(Input{NavLeft: true})
+	f.frame(Input{NavLeft: true})
+	f.frame(Input{Text: []rune("e")})
+	if f.name != "hello" {
+		t.Errorf("expected hello, got %q", f.name)
+	}
+
+	// Clicking places the cursor, 8 pixels per character
+	f.click(glm.Vec2{13, 30})
+	f.frame(Input{Backspace: true})
+	if f.name != "hllo" {
+		t.Errorf("expected backspace at the clicked position, got %q", f.name)
+	}
+
+	f.frame(Input{Activate: true})
+	f.frame(Input{Text: []rune("x")})
+	if f.name != "hllo" || f.ctx.Focus() != 0 {
+		t.Errorf("expected activate to unfocus the text input")
+	}
+}
+
+func TestDropdown(t *testing.T) {
+	f := newForm()
+
+	f.click(dropdownRect.Center())
+	if !f.ctx.states[f.ctx.Id("Mode")].open {
+		t.Fatalf("expected dropdown to be open")
+	}
+
+	// The third option is 40 to 60 pixels below the dropdown
+	f.click(glm.Vec2{50, -50})
+	if f.option != 2 || f.ctx.states[f.ctx.Id("Mode")].open {
+		t.Errorf("expected hard to be picked, got %v", f.option)
+	}
+
+	// Options over other widgets block the mouse from reaching them
+	ctx := NewContext(DefaultStyle())
+	option, toggled := 1, false
+	frame := func(input Input) {
+		ctx.Begin(input)
+		ctx.Dropdown("Mode", buttonRect, []string{"Easy", "Normal"}, &option)
+		ctx.Toggle("Fullscreen", toggleRect, &toggled)
+		ctx.End()
+	}
+	ctx.SetFocus("Mode")
+	frame(Input{Activate: true})
+	frame(Input{Mouse: toggleRect.Center(), MouseDown: true})
+	frame(Input{Mouse: toggleRect.Center()})
+	if toggled || option != 0 {
+		t.Errorf("expected the open dropdown to block the toggle")
+	}
+}
+
+func TestNavigation(t *testing.T) {
+	f := newForm()
+	f.frame(Input{})
+
+	ids := []Id{f.ctx.Id("Play"), f.ctx.Id("Fullscreen"), f.ctx.Id("Volume"), f.ctx.Id("Name"), f.ctx.Id("Mode")}
+	for i, id := range ids {
+		f.frame(Input{NavDown: true})
+		if f.ctx.Focus() != id {
+			t.Errorf("step %d: expected focus on %v, got %v", i, id, f.ctx.Focus())
+		}
+	}
+	f.frame(Input{NavDown: true})
+	if f.ctx.Focus() != ids[0] {
+		t.Errorf("expected focus to wrap around")
+	}
+	f.frame(Input{NavUp: true})
+	if f.ctx.Focus() != ids[4] {
+		t.Errorf("expected focus to wrap backwards")
+	}
+
+	// An open dropdown captures navigation
+	f.frame(Input{Activate: true})
+	f.frame(Input{NavDown: true})
+	f.frame(Input{NavDown: true})
+	f.frame(Input{Activate: true})
+	if f.option != 2 || f.ctx.Focus() != ids[4] {
+		t.Errorf("expected dropdown navigation to pick hard, got %v", f.option)
+	}
+
+	f.frame(Input{NavDown: true})
+	f.frame(Input{Activate: true})
+	if !f.clicked {
+		t.Errorf("expected activate to click the focused button")
+	}
+
+	f.frame(Input{Cancel: true})
+	if f.ctx.Focus() != 0 {
+		t.Errorf("expected cancel to clear focus")
+	}
+}
+
+func TestScrollAndTooltip(t *testing.T) {
+	ctx := NewContext(DefaultStyle())
+	ctx.Style.TooltipDelay = 2
+	view := glm.R(0, 0, 100, 100)
+
+	var clicked []int
+	var tooltip bool
+	frame := func(input Input) {
+		clicked = clicked[:0]
+		ctx.Begin(input)
+		content := ctx.BeginScroll("list", view, 500)
+		for i := 0; i < 10; i++ {
+			row := content
+			row.Max.Y -= float64(i) * 50
+			row.Min.Y = row.Max.Y - 50
+			ctx.PushId(uint64(i))
+			if ctx.Button("Row", row) {
+				clicked = append(clicked, i)
+			}
+			if i == 0 {
+				ctx.Tooltip("First")
+			}
+			ctx.PopId()
+		}
+		ctx.EndScroll()
+		cmds := ctx.End()
+
+		tooltip = false
+		for _, cmd := range cmds {
+			if cmd.Kind == CommandText && cmd.Text == "First" {
+				tooltip = true
+			}
+		}
+	}
+
+	frame(Input{Mouse: glm.Vec2{50, 75}})
+	if tooltip {
+		t.Errorf("expected tooltip to wait for the delay")
+	}
+	frame(Input{Mouse: glm.Vec2{50, 75}})
+	if !tooltip {
+		t.Errorf("expected tooltip after the delay")
+	}
+
+	frame(Input{Mouse: glm.Vec2{50, 75}, Scroll: -3})
+	if ctx.ScrollOffset("list") != 60 {
+		t.Errorf("expected offset of 60, got %v", ctx.ScrollOffset("list"))
+	}
+	frame(Input{Mouse: glm.Vec2{50, 75}, Scroll: -100})
+	if ctx.ScrollOffset("list") != 400 {
+		t.Errorf("expected offset to be clamped to 400, got %v", ctx.ScrollOffset("list"))
+	}
+
+	// Rows outside of the view are clipped, so only the visible row is clicked
+	frame(Input{Mouse: glm.Vec2{50, 75}, MouseDown: true})
+	frame(Input{Mouse: glm.Vec2{50, 75}})
+	if len(clicked) != 1 || clicked[0] != 8 {
+		t.Errorf("expected row 8 to be clicked, got %v", clicked)
+	}
+
+	// Navigating to a row outside of the view scrolls to it
+	frame(Input{NavDown: true})
+	frame(Input{})
+	if ctx.ScrollOffset("list") != 400 {
+		t.Errorf("expected row 9 to be visible, got offset %v", ctx.ScrollOffset("list"))
+	}
+	frame(Input{NavDown: true})
+	frame(Input{})
+	if ctx.ScrollOffset("list") != 0 {
+		t.Errorf("expected scroll back to row 0, got offset %v", ctx.ScrollOffset("list"))
+	}
+}
+
+type recordPainter struct {
+	calls []string
+}
+
+func (p *recordPainter) Rect(r glm.Rect, color glm.RGBA) { p.calls = append(p.calls, "rect") }
+func (p *recordPainter) Text(r glm.Rect, text string, color glm.RGBA) {
+	p.calls = append(p.calls, text)
+}
+func (p *recordPainter) PushClip(r glm.Rect) { p.calls = append(p.calls, "push") }
+func (p *recordPainter) PopClip()            { p.calls = append(p.calls, "pop") }
+
+func TestPaint(t *testing.T) {
+	ctx := NewContext(DefaultStyle())
+	ctx.Begin(Input{})
+	ctx.Button("Ok##confirm", glm.R(0, 0, 10, 10))
+	cmds := ctx.End()
+
+	p := &recordPainter{}
+	Paint(p, cmds)
+	if len(p.calls) != 2 || p.calls[0] != "rect" || p.calls[1] != "Ok" {
+		t.Errorf("expected a rect and the display label, got %v", p.calls)
+	}
+}
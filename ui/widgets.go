@@ -0,0 +1,319 @@
+package ui
+
+import (
+	"github.com/unitoftime/flow/glm"
+)
+
+// The last widget that was added, for Tooltip
+type lastItem struct {
+	id      Id
+	rect    glm.Rect
+	hovered bool
+	focused bool
+}
+
+// An open scroll panel
+type scrollPanel struct {
+	id      Id
+	view    glm.Rect
+	content float64
+	focused *glm.Rect // The rect of the focused widget, if it is inside the panel
+}
+
+func (c *Context) widget(label string, r glm.Rect, focusable bool) (Id, interaction) {
+	id := c.id(label)
+	in := c.interact(id, r, focusable)
+	c.last = lastItem{id: id, rect: r, hovered: in.hovered, focused: in.focused}
+	if in.focused && len(c.scrollStack) > 0 {
+		rect := r
+		c.scrollStack[len(c.scrollStack)-1].focused = &rect
+	}
+	return id, in
+}
+
+// Draws text in the rect
+func (c *Context) Label(r glm.Rect, text string) {
+	c.drawText(r.Unpad(c.Style.pad()), text, c.Style.Text)
+}
+
+// Draws a button, and returns true if it was clicked
+func (c *Context) Button(label string, r glm.Rect) bool {
+	_, in := c.widget(label, r, true)
+	c.drawRect(r, c.Style.Background.Get(in.state))
+	c.drawText(r.Unpad(c.Style.pad()), display(label), c.Style.Text)
+	return in.clicked
+}
+
+// Draws a checkbox with a label, and returns true if the value was changed
+func (c *Context) Toggle(label string, r glm.Rect, value *bool) bool {
+	_, in := c.widget(label, r, true)
+	if in.clicked {
+		*value = !*value
+	}
+
+	rest := r
+	box := rest.CutLeft(r.H())
+	c.drawRect(box, c.Style.Background.Get(in.state))
+	if *value {
+		c.drawRect(box.Unpad(c.Style.pad()), c.Style.Accent.Get(in.state))
+	}
+	c.drawText(rest.Unpad(c.Style.pad()), display(label), c.Style.Text)
+	return in.clicked
+}
+
+// Draws a horizontal slider between min and max, and returns true if the value was changed. The value can be dragged with the mouse, or stepped with NavLeft and NavRight while focused.
+func (c *Context) Slider(label string, r glm.Rect, value *float64, min, max float64) bool {
+	id, in := c.widget(label, r, true)
+	old := *value
+
+	track := r.Unpad(glm.R(c.Style.HandleSize/2, 0, c.Style.HandleSize/2, 0))
+	if c.active == id && c.input.MouseDown && track.W() > 0 {
+		t := (c.input.Mouse.X - track.Min.X) / track.W()
+		*value = min + t*(max-min)
+	}
+	if in.focused {
+		step := c.Style.SliderStep * (max - min)
+		if c.input.NavLeft {
+			*value -= step
+		}
+		if c.input.NavRight {
+			*value += step
+		}
+	}
+	*value = glm.Clamp(min, max, *value)
+
+	t := 0.0
+	if max != min {
+		t = (*value - min) / (max - min)
+	}
+	x := track.Min.X + t*track.W()
+	handle := glm.R(x-c.Style.HandleSize/2, r.Min.Y, x+c.Style.HandleSize/2, r.Max.Y)
+
+	c.drawRect(r, c.Style.Background.Get(in.state))
+	c.drawRect(handle, c.Style.Accent.Get(in.state))
+	c.drawText(r.Unpad(c.Style.pad()), display(label), c.Style.Text)
+	return *value != old
+}
+
+// Draws a single line text box, and returns true if the text was changed. The text box takes typed text while focused, and Activate unfocuses it.
+func (c *Context) TextInput(label string, r glm.Rect, text *string) bool {
+	id, in := c.widget(label, r, true)
+	s := c.state(id)
+	inner := r.Unpad(c.Style.pad())
+
+	runes := []rune(*text)
+	changed := false
+	s.cursor = min(max(s.cursor, 0), len(runes))
+
+	// Clicking places the cursor at the nearest character
+	if in.hovered && c.pressed {
+		s.cursor = len(runes)
+		for i := range runes {
+			mid := (c.Style.measure(string(runes[:i])) + c.Style.measure(string(runes[:i+1]))) / 2
+			if c.input.Mouse.X-inner.Min.X < mid {
+				s.cursor = i
+				break
+			}
+		}
+	}
+
+	if in.focused {
+		if len(c.input.Text) > 0 {
+			runes = append(runes[:s.cursor], append(append([]rune{}, c.input.Text...), runes[s.cursor:]...)...)
+			s.cursor += len(c.input.Text)
+			changed = true
+		}
+		if c.input.Backspace && s.cursor > 0 {
+			runes = append(runes[:s.cursor-1], runes[s.cursor:]...)
+			s.cursor--
+			changed = true
+		}
+		if c.input.NavLeft && s.cursor > 0 {
+			s.cursor--
+		}
+		if c.input.NavRight && s.cursor < len(runes) {
+			s.cursor++
+		}
+		if c.input.Activate {
+			c.focus = 0
+		}
+	}
+	if changed {
+		*text = string(runes)
+	}
+
+	c.drawRect(r, c.Style.Background.Get(in.state))
+	if len(runes) == 0 && !in.focused {
+		c.drawText(inner, display(label), c.Style.TextMuted)
+	} else {
+		c.drawText(inner, *text, c.Style.Text)
+	}
+	if c.focus == id {
+		x := inner.Min.X + c.Style.measure(string(runes[:s.cursor]))
+		c.drawRect(glm.R(x, inner.Min.Y, x+1, inner.Max.Y), c.Style.Cursor)
+	}
+	return changed
+}
+
+// Draws a dropdown, and returns true if the selected option was changed. While the dropdown is open and focused, NavUp and NavDown move through the options and Activate picks one.
+func (c *Context) Dropdown(label string, r glm.Rect, options []string, selected *int) bool {
+	id, in := c.widget(label, r, true)
+	s := c.state(id)
+	old := *selected
+
+	list := glm.R(r.Min.X, r.Min.Y-float64(len(options))*c.Style.ItemHeight, r.Max.X, r.Min.Y)
+	option := func(i int) glm.Rect {
+		top := r.Min.Y - float64(i)*c.Style.ItemHeight
+		return glm.R(r.Min.X, top-c.Style.ItemHeight, r.Max.X, top)
+	}
+
+	if !s.open {
+		if in.clicked {
+			s.open = true
+			s.cursor = max(*selected, 0)
+		}
+	} else {
+		switch {
+		case c.input.Cancel || (c.pressed && !inside(r, c.input.Mouse) && !inside(list, c.input.Mouse)):
+			s.open = false
+		case c.pressed && inside(list, c.input.Mouse):
+			for i := range options {
+				if inside(option(i), c.input.Mouse) {
+					*selected = i
+					s.open = false
+					break
+				}
+			}
+		case in.focused && c.input.Activate:
+			*selected = s.cursor
+			s.open = false
+		case in.clicked:
+			s.open = false
+		}
+	}
+
+	if s.open {
+		// Capture navigation so that it moves through the options instead of the widgets
+		if in.focused {
+			c.nextNavCapture = id
+			if c.navCaptured == id {
+				if c.input.NavDown {
+					s.cursor++
+				}
+				if c.input.NavUp {
+					s.cursor--
+				}
+			}
+		}
+		s.cursor = min(max(s.cursor, 0), len(options)-1)
+		c.nextBlock = append(c.nextBlock, list)
+
+		c.overlayRect(list, c.Style.Panel)
+		for i, opt := range options {
+			o := option(i)
+			if i == s.cursor || inside(o, c.input.Mouse) {
+				c.overlayRect(o, c.Style.Background.Get(StateHover))
+			}
+			if i == *selected {
+				c.overlayRect(o.CutLeft(c.Style.Padding/2), c.Style.Accent.Get(StateNormal))
+			}
+			c.overlayText(o.Unpad(c.Style.pad()), opt, c.Style.Text)
+		}
+	}
+
+	text := display(label)
+	if *selected >= 0 && *selected < len(options) {
+		text = options[*selected]
+	}
+	c.drawRect(r, c.Style.Background.Get(in.state))
+	c.drawText(r.Unpad(c.Style.pad()), text, c.Style.Text)
+	return *selected != old
+}
+
+// Starts a vertically scrolling panel in the rect, with content of the given height. The returned rect is where the content should be laid out, and it moves as the panel scrolls. Widgets outside of the panel are clipped. Must be followed by EndScroll.
+func (c *Context) BeginScroll(label string, r glm.Rect, contentHeight float64) glm.Rect {
+	id := c.id(label)
+	s := c.state(id)
+
+	maxScroll := max(contentHeight-r.H(), 0)
+	if c.input.Scroll != 0 && c.mouseOver(r) {
+		s.scroll -= c.input.Scroll * c.Style.ScrollSpeed
+	}
+	s.scroll = glm.Clamp(0, maxScroll, s.scroll)
+
+	c.drawRect(r, c.Style.Panel)
+	if maxScroll > 0 {
+		// Scrollbar
+		bar := r
+		bar = bar.CutRight(c.Style.Padding)
+		thumbH := bar.H() * r.H() / contentHeight
+		top := bar.Max.Y - (bar.H()-thumbH)*(s.scroll/maxScroll)
+		c.drawRect(glm.R(bar.Min.X, top-thumbH, bar.Max.X, top), c.Style.Background.Get(StateHover))
+	}
+
+	c.pushClip(r)
+	c.scrollStack = append(c.scrollStack, scrollPanel{id: id, view: r, content: contentHeight})
+	top := r.Max.Y + s.scroll
+	return glm.R(r.Min.X, top-contentHeight, r.Max.X, top)
+}
+
+// Ends the scroll panel started by BeginScroll
+func (c *Context) EndScroll() {
+	panel := c.scrollStack[len(c.scrollStack)-1]
+	c.scrollStack = c.scrollStack[:len(c.scrollStack)-1]
+	c.popClip()
+
+	// Keep the focused widget visible when it is moved to with keyboard or gamepad navigation
+	if c.navDelta == 0 || panel.focused == nil {
+		return
+	}
+	s := c.state(panel.id)
+	f := *panel.focused
+	if f.Max.Y > panel.view.Max.Y {
+		s.scroll -= f.Max.Y - panel.view.Max.Y
+	} else if f.Min.Y < panel.view.Min.Y {
+		s.scroll += panel.view.Min.Y - f.Min.Y
+	}
+	s.scroll = glm.Clamp(0, max(panel.content-panel.view.H(), 0), s.scroll)
+}
+
+// Returns the scroll offset of a scroll panel
+func (c *Context) ScrollOffset(label string) float64 {
+	s, ok := c.states[c.id(label)]
+	if !ok {
+		return 0
+	}
+	return s.scroll
+}
+
+// Shows a tooltip for the last widget once it has been hovered (or focused) for Style.TooltipDelay frames
+func (c *Context) Tooltip(text string) {
+	s := c.state(c.last.id)
+	if !c.last.hovered && !c.last.focused {
+		s.hovered = 0
+		return
+	}
+	s.hovered++
+	if s.hovered < c.Style.TooltipDelay {
+		return
+	}
+
+	// Tooltips go under the mouse when hovered, and under the widget when focused
+	pos := glm.Vec2{c.last.rect.Min.X, c.last.rect.Min.Y}
+	if c.last.hovered {
+		pos = c.input.Mouse
+	}
+	w := c.Style.measure(text) + 2*c.Style.Padding
+	h := c.Style.ItemHeight
+	r := glm.R(pos.X, pos.Y-h, pos.X+w, pos.Y)
+	c.overlayRect(r, c.Style.Tooltip)
+	c.overlayText(r.Unpad(c.Style.pad()), text, c.Style.Text)
+}
+
+func (c *Context) overlayRect(r glm.Rect, color glm.RGBA) {
+	c.overlay = append(c.overlay, Command{Kind: CommandRect, Rect: r, Color: color})
+}
+
+func (c *Context) overlayText(r glm.Rect, text string, color glm.RGBA) {
+	c.overlay = append(c.overlay, Command{Kind: CommandText, Rect: r, Color: color, Text: text})
+}
@@ -0,0 +1,351 @@
+package utility
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/unitoftime/flow/asset"
+	"github.com/unitoftime/flow/ds"
+	"github.com/unitoftime/flow/glm"
+	"github.com/unitoftime/flow/interp"
+)
+
+// Brain is a utility AI asset. Every tick each action is scored by its considerations, and the action with the best score is chosen. Brains are written in YAML (or JSON) and loaded with the asset server:
+//
+//	name: goblin
+//	inertia: 0.1
+//	actions:
+//	  - name: attack
+//	    considerations:
+//	      - input: distance
+//	        args: {target: player}
+//	        min: 0
+//	        max: 10
+//	        curve: {type: line, slope: -1, intercept: 1}
+//	  - name: flee
+//	    weight: 1.5
+//	    considerations:
+//	      - input: health
+//	        curve: {type: bezier, points: [[0.1, 1], [0.3, 1]]}
+//
+// Each consideration reads an input, which is a function registered in Go by name. The input value is normalized from [min, max] to [0, 1] and passed through the curve, which gives a score from 0 to 1. The scores of an action's considerations are combined, then multiplied by the action's weight.
+type Brain struct {
+	Name    string   `yaml:"name,omitempty"`
+	Combine Combine  `yaml:"combine,omitempty"` // How consideration scores are combined. Defaults to multiply
+	Inertia float64  `yaml:"inertia,omitempty"` // Added to the score of the current action (unless it scored 0), so that agents don't flip between actions with close scores
+	Actions []Action `yaml:"actions"`
+}
+
+type Action struct {
+	Name           string          `yaml:"name"`
+	Weight         float64         `yaml:"weight,omitempty"` // Multiplies the score of the action. Defaults to 1
+	Considerations []Consideration `yaml:"considerations"`
+}
+
+type Consideration struct {
+	Input string   `yaml:"input"`
+	Args  ds.Args  `yaml:"args,omitempty"`
+	Min   float64  `yaml:"min,omitempty"`
+	Max   float64  `yaml:"max,omitempty"` // If min and max are both 0, the input is expected to already be in [0, 1]
+	Curve CurveDef `yaml:"curve,omitempty"`
+
+	// Optional: A curve built in Go, which is used instead of the curve definition
+	Func interp.Function `yaml:"-"`
+
+	input InputFunc
+}
+
+// Combine decides how the scores of an action's considerations are combined
+type Combine string
+
+const (
+	CombineMultiply Combine = "multiply" // Multiplies the scores, and compensates for the number of considerations so that actions with more considerations aren't penalized
+	CombineAverage  Combine = "average"
+	CombineMin      Combine = "min"
+)
+
+// Builds a brain in Go, and checks that every input is registered in the registry. If registry is nil, DefaultRegistry is used.
+func NewBrain(name string, actions []Action, registry *Registry) (*Brain, error) {
+	brain := &Brain{
+		Name:    name,
+		Actions: actions,
+	}
+	err := brain.compile(registry)
+	if err != nil {
+		return nil, err
+	}
+	return brain, nil
+}
+
+func (b *Brain) compile(registry *Registry) error {
+	if registry == nil {
+		registry = DefaultRegistry
+	}
+
+	switch b.Combine {
+	case "":
+		b.Combine = CombineMultiply
+	case CombineMultiply, CombineAverage, CombineMin:
+	default:
+		return fmt.Errorf("utility: unknown combine mode %q", b.Combine)
+	}
+
+	names := make(map[string]bool)
+	for a := range b.Actions {
+		action := &b.Actions[a]
+		if action.Name == "" {
+			return fmt.Errorf("utility: actions[%d]: action needs a name", a)
+		}
+		if names[action.Name] {
+			return fmt.Errorf("utility: actions[%d]: duplicate action %q", a, action.Name)
+		}
+		names[action.Name] = true
+		if action.Weight == 0 {
+			action.Weight = 1
+		}
+
+		for c := range action.Considerations {
+			con := &action.Considerations[c]
+			input, ok := registry.inputs[con.Input]
+			if !ok {
+				return fmt.Errorf("utility: %s/considerations[%d]: unregistered input %q", action.Name, c, con.Input)
+			}
+			con.input = input
+
+			if con.Func == nil {
+				curve, err := con.Curve.Build()
+				if err != nil {
+					return fmt.Errorf("utility: %s/considerations[%d]: %w", action.Name, c, err)
+				}
+				con.Func = curve
+			}
+		}
+	}
+	return nil
+}
+
+// Scores the consideration from 0 to 1, and returns the raw input value with it
+func (c *Consideration) score(ctx *Context) (float64, float64) {
+	ctx.Args = c.Args
+	value := c.input(ctx)
+
+	t := value
+	if c.Min != 0 || c.Max != 0 {
+		if c.Max == c.Min {
+			t = 0
+		} else {
+			t = (value - c.Min) / (c.Max - c.Min)
+		}
+	}
+	t = glm.Clamp(0, 1, t)
+
+	score := c.Func.Interp(t)
+	if math.IsNaN(score) {
+		score = 0
+	}
+	return value, glm.Clamp(0, 1, score)
+}
+
+// Scores every action of the brain. The current action gets the brain's inertia added to its score, unless it scored 0.
+func (b *Brain) Evaluate(ctx *Context, current string) []Score {
+	scores := make([]Score, len(b.Actions))
+	for a := range b.Actions {
+		scores[a] = b.evaluate(ctx, &b.Actions[a], current)
+	}
+	return scores
+}
+
+func (b *Brain) evaluate(ctx *Context, action *Action, current string) Score {
+	score := Score{
+		Action:         action.Name,
+		Considerations: make([]ConsiderationScore, len(action.Considerations)),
+	}
+
+	total := 1.0
+	switch b.Combine {
+	case CombineAverage:
+		total = 0
+	}
+	for c := range action.Considerations {
+		con := &action.Considerations[c]
+		value, s := con.score(ctx)
+		score.Considerations[c] = ConsiderationScore{
+			Input: con.Input,
+			Value: value,
+			Score: s,
+		}
+
+		switch b.Combine {
+		case CombineAverage:
+			total += s
+		case CombineMin:
+			total = min(total, s)
+		default:
+			total *= s
+		}
+	}
+
+	n := float64(len(action.Considerations))
+	if n > 0 {
+		switch b.Combine {
+		case CombineAverage:
+			total /= n
+		case CombineMultiply:
+			// Note: Every extra consideration can only lower a product, so give back part of what was lost
+			mod := 1 - 1/n
+			total += (1 - total) * mod * total
+		}
+	}
+
+	score.Score = total * action.Weight
+	if action.Name == current && score.Score > 0 {
+		score.Score += b.Inertia
+	}
+	return score
+}
+
+// Returns the index of the best score, or -1 if there are no positive scores. Ties go to the earlier action.
+func Best(scores []Score) int {
+	best := -1
+	for i := range scores {
+		if scores[i].Score <= 0 {
+			continue
+		}
+		if best < 0 || scores[i].Score > scores[best].Score {
+			best = i
+		}
+	}
+	return best
+}
+
+//--------------------------------------------------------------------------------
+// - Curves
+//--------------------------------------------------------------------------------
+
+// CurveDef defines a response curve, which is built from the interp functions:
+//   - line: interp.LineFunc{Slope, Intercept}. A missing curve is a line with slope 1, so the score is the normalized input
+//   - bezier: interp.BezFunc, through the points. Two points are the middle points of a curve from (0, 0) to (1, 1), four points are the whole curve
+//   - sine, cosine: interp.SinFunc or interp.CosFunc{Radius, Freq, ShiftY}
+//
+// Radius scales bezier and sine curves, and defaults to 1.
+type CurveDef struct {
+	Type      string       `yaml:"type,omitempty"`
+	Slope     float64      `yaml:"slope,omitempty"`
+	Intercept float64      `yaml:"intercept,omitempty"`
+	Points    [][2]float64 `yaml:"points,omitempty"`
+	Radius    float64      `yaml:"radius,omitempty"`
+	Freq      float64      `yaml:"freq,omitempty"`
+	Shift     float64      `yaml:"shift,omitempty"`
+}
+
+// Builds the curve function
+func (d CurveDef) Build() (interp.Function, error) {
+	radius := d.Radius
+	if radius == 0 {
+		radius = 1
+	}
+
+	switch d.Type {
+	case "":
+		if d.Slope == 0 && d.Intercept == 0 {
+			return interp.LineFunc{Slope: 1}, nil
+		}
+		return interp.LineFunc{Slope: d.Slope, Intercept: d.Intercept}, nil
+	case "line":
+		return interp.LineFunc{Slope: d.Slope, Intercept: d.Intercept}, nil
+	case "bezier":
+		p := make([]glm.Vec2, len(d.Points))
+		for i := range d.Points {
+			p[i] = glm.Vec2{d.Points[i][0], d.Points[i][1]}
+		}
+		switch len(p) {
+		case 2:
+			return interp.BezFunc{Radius: radius, Bezier: interp.NewCubicBezier(p[0], p[1])}, nil
+		case 4:
+			return interp.BezFunc{Radius: radius, Bezier: interp.NewBezier(p[0], p[1], p[2], p[3])}, nil
+		}
+		return nil, fmt.Errorf("bezier curve needs 2 or 4 points, got %d", len(p))
+	case "sine":
+		return interp.SinFunc{Radius: radius, Freq: d.Freq, ShiftY: d.Shift}, nil
+	case "cosine":
+		return interp.CosFunc{Radius: radius, Freq: d.Freq, ShiftY: d.Shift}, nil
+	}
+	return nil, fmt.Errorf("unknown curve type %q", d.Type)
+}
+
+//--------------------------------------------------------------------------------
+// - Loader
+//--------------------------------------------------------------------------------
+
+// Loader loads brains through the asset server
+type Loader struct {
+	Registry *Registry // Optional: The registry used to find inputs. Defaults to DefaultRegistry
+}
+
+func (l Loader) Ext() []string {
+	return []string{".brain.yaml", ".brain.yml", ".brain.json"}
+}
+
+func (l Loader) Load(server *asset.Server, data []byte) (*Brain, error) {
+	var brain Brain
+	err := yaml.Unmarshal(data, &brain)
+	if err != nil {
+		return nil, err
+	}
+	err = brain.compile(l.Registry)
+	if err != nil {
+		return nil, err
+	}
+	return &brain, nil
+}
+
+func (l Loader) Store(server *asset.Server, brain *Brain) ([]byte, error) {
+	return yaml.Marshal(brain)
+}
+
+//--------------------------------------------------------------------------------
+// - Registry
+//--------------------------------------------------------------------------------
+
+// InputFunc returns the raw value of an input for the entity, ie its distance to the target or its health. Inputs are read while reasoners are being iterated, so they shouldn't add or remove components.
+type InputFunc func(ctx *Context) float64
+
+// Registry maps the input names used in brain files to their functions
+type Registry struct {
+	inputs map[string]InputFunc
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		inputs: make(map[string]InputFunc),
+	}
+}
+
+// The registry used by the package level functions
+var DefaultRegistry = NewRegistry()
+
+// Registers an input into the default registry
+func Register(name string, fn InputFunc) {
+	DefaultRegistry.Register(name, fn)
+}
+
+// Registers an input. Panics if the name is already used.
+func (r *Registry) Register(name string, fn InputFunc) {
+	if _, exists := r.inputs[name]; exists {
+		panic(fmt.Sprintf("utility: input name already registered: %s", name))
+	}
+	r.inputs[name] = fn
+}
+
+// Returns the names of every registered input, in sorted order
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.inputs))
+	for name := range r.inputs {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
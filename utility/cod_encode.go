@@ -0,0 +1,15 @@
+package utility
+
+import (
+	"github.com/unitoftime/ecs"
+)
+
+var ReasonerComp = ecs.NewComp[Reasoner]()
+
+func (c Reasoner) CompId() ecs.CompId {
+	return ReasonerComp.CompId()
+}
+
+func (c Reasoner) CompWrite(w ecs.W) {
+	ReasonerComp.WriteVal(w, c)
+}
@@ -0,0 +1,183 @@
+package utility
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/unitoftime/ecs"
+	"github.com/unitoftime/flow"
+	"github.com/unitoftime/flow/asset"
+	"github.com/unitoftime/flow/ds"
+)
+
+//go:generate go run ../../cod/cmd/cod
+
+// Context is passed to inputs when they are read
+type Context struct {
+	World  *ecs.World
+	Entity ecs.Id
+	Args   ds.Args
+}
+
+// Score is the score of one action
+type Score struct {
+	Action         string
+	Score          float64
+	Considerations []ConsiderationScore
+}
+
+// ConsiderationScore is the score of one consideration of an action
+type ConsiderationScore struct {
+	Input string
+	Value float64 // The raw input value
+	Score float64 // The value after it was normalized and passed through the curve
+}
+
+// Chosen is sent when a reasoner switches to a different action
+type Chosen struct {
+	Entity   ecs.Id
+	Action   string // The new action, empty if no action had a positive score
+	Previous string
+}
+
+// Reasoner chooses the best action of a brain for its entity every tick. Other systems read the Action to carry it out.
+//
+//cod:component
+type Reasoner struct {
+	Brain    *asset.Handle[Brain]
+	Action   string // The current action, empty if no action has been chosen
+	Disabled bool
+
+	scores []Score
+}
+
+func NewReasoner(brain *asset.Handle[Brain]) Reasoner {
+	return Reasoner{
+		Brain: brain,
+	}
+}
+
+// Scores every action, and switches to the best one. Returns true if the action changed. Brains that are still loading (or failed their first load) aren't evaluated.
+func (r *Reasoner) Update(world *ecs.World, id ecs.Id) bool {
+	if r.Disabled || r.Brain == nil || !r.Brain.Done() {
+		return false
+	}
+	// Note: If a reload fails, the handle keeps the last brain that loaded
+	brain, _ := r.Brain.Get()
+	if brain == nil {
+		return false
+	}
+
+	ctx := &Context{
+		World:  world,
+		Entity: id,
+	}
+	r.scores = brain.Evaluate(ctx, r.Action)
+
+	action := ""
+	if best := Best(r.scores); best >= 0 {
+		action = r.scores[best].Action
+	}
+	changed := action != r.Action
+	r.Action = action
+	return changed
+}
+
+// Returns the scores from the last update, in the order of the brain's actions
+func (r *Reasoner) Scores() []Score {
+	return r.scores
+}
+
+// Returns the scores from the last update as text, with the best action first:
+//
+//	flee: 0.72 (chosen)
+//	  health = 20: 0.72
+//	attack: 0.35
+//	  distance = 6.5: 0.35
+func (r *Reasoner) DebugString() string {
+	order := make([]int, len(r.scores))
+	for i := range order {
+		order[i] = i
+	}
+	// Note: Stable, so that ties stay in the brain's order
+	slices.SortStableFunc(order, func(a, b int) int {
+		return cmp.Compare(r.scores[b].Score, r.scores[a].Score)
+	})
+
+	var b strings.Builder
+	for _, i := range order {
+		score := r.scores[i]
+		fmt.Fprintf(&b, "%s: %.2f", score.Action, score.Score)
+		if score.Action == r.Action {
+			b.WriteString(" (chosen)")
+		}
+		b.WriteString("\n")
+		for _, c := range score.Considerations {
+			fmt.Fprintf(&b, "  %s = %g: %.2f\n", c.Input, c.Value, c.Score)
+		}
+	}
+	return b.String()
+}
+
+// Returns a system which updates every reasoner. If server is set, then the brain files of every reasoner are checked for changes once per interval. Chosen events are sent when reasoners switch actions.
+func UpdateSystem(world *ecs.World, server *asset.Server, interval time.Duration) ecs.System {
+	query := ecs.Query1[Reasoner](world)
+	events := ecs.GetResource[flow.Events[Chosen]](world)
+	var elapsed time.Duration
+	return ecs.System{
+		Name: "utility.UpdateSystem",
+		Func: func(dt time.Duration) {
+			elapsed += dt
+			if server != nil && elapsed >= interval {
+				elapsed = 0
+				pollReasoners(server, query)
+			}
+
+			query.MapId(func(id ecs.Id, r *Reasoner) {
+				previous := r.Action
+				if r.Update(world, id) && events != nil {
+					events.Send(Chosen{
+						Entity:   id,
+						Action:   r.Action,
+						Previous: previous,
+					})
+				}
+			})
+		},
+	}
+}
+
+// Asks the asset server to reload every brain used by a reasoner. Reloads only happen if the file has changed.
+func pollReasoners(server *asset.Server, query *ecs.View1[Reasoner]) {
+	handles := make(map[*asset.Handle[Brain]]struct{})
+	query.MapId(func(id ecs.Id, r *Reasoner) {
+		if r.Brain != nil {
+			handles[r.Brain] = struct{}{}
+		}
+	})
+
+	for handle := range handles {
+		asset.Reload(server, handle)
+	}
+}
+
+// Plugin adds the Chosen event, and the system which updates reasoners in StageFixedUpdate
+// Note: The utility Loader must still be registered with your asset server: `asset.Register(server, utility.Loader{})`
+type Plugin struct {
+	Server         *asset.Server // Optional: If set, then the brain files of reasoners are checked for changes and hot reloaded
+	ReloadInterval time.Duration // How often to check for changes. Defaults to 1 second
+}
+
+func (p Plugin) Initialize(world *ecs.World) {
+	app := flow.MustApp(world)
+
+	interval := p.ReloadInterval
+	if interval <= 0 {
+		interval = 1 * time.Second
+	}
+	flow.AddEvent[Chosen](app)
+	app.AddSystems(ecs.StageFixedUpdate, UpdateSystem(world, p.Server, interval))
+}
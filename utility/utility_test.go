@@ -0,0 +1,256 @@
+package utility
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/unitoftime/ecs"
+	"github.com/unitoftime/flow"
+	"github.com/unitoftime/flow/asset"
+	"github.com/unitoftime/flow/ds"
+	"github.com/unitoftime/flow/interp"
+)
+
+type stats struct {
+	Health   float64
+	Distance float64
+}
+
+// Returns a registry with inputs that read the entity's stats, and a "const" input which returns its {value} arg
+func statsInputs() *Registry {
+	r := NewRegistry()
+	r.Register("health", func(ctx *Context) float64 {
+		s, _ := ecs.Read[stats](ctx.World, ctx.Entity)
+		return s.Health
+	})
+	r.Register("distance", func(ctx *Context) float64 {
+		s, _ := ecs.Read[stats](ctx.World, ctx.Entity)
+		return s.Distance
+	})
+	r.Register("const", func(ctx *Context) float64 {
+		return ctx.Args.Float("value", 0)
+	})
+	return r
+}
+
+// Returns an asset server which serves the brain files, and loads them with the stats inputs
+func brainServer(files fstest.MapFS) *asset.Server {
+	server := asset.NewServer()
+	server.RegisterFilesystem("", asset.NewFilesystem("", files))
+	asset.Register(server, Loader{Registry: statsInputs()})
+	return server
+}
+
+const goblin = `
+name: goblin
+inertia: 0.05
+actions:
+  - name: attack
+    considerations:
+      - input: distance
+        min: 0
+        max: 10
+        curve: {type: line, slope: -1, intercept: 1}
+  - name: flee
+    considerations:
+      - input: health
+        min: 0
+        max: 100
+        curve: {type: line, slope: -1, intercept: 1}
+`
+
+func approx(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestCurves(t *testing.T) {
+	tests := []struct {
+		def      CurveDef
+		expected [3]float64 // At 0, 0.5 and 1
+	}{
+		{CurveDef{}, [3]float64{0, 0.5, 1}},
+		{CurveDef{Type: "line", Slope: -1, Intercept: 1}, [3]float64{1, 0.5, 0}},
+		{CurveDef{Type: "bezier", Points: [][2]float64{{0, 0}, {1, 1}}}, [3]float64{0, 0.5, 1}},
+		{CurveDef{Type: "sine", Freq: math.Pi}, [3]float64{0, 1, 0}},
+		{CurveDef{Type: "cosine", Radius: 0.5, Freq: math.Pi, Shift: 1}, [3]float64{1, 0.5, 0}},
+	}
+	for _, test := range tests {
+		curve, err := test.def.Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, x := range []float64{0, 0.5, 1} {
+			if y := curve.Interp(x); !approx(y, test.expected[i]) {
+				t.Errorf("%+v at %v: expected %v, got %v", test.def, x, test.expected[i], y)
+			}
+		}
+	}
+
+	_, err := CurveDef{Type: "bezier", Points: [][2]float64{{0, 0}}}.Build()
+	if err == nil {
+		t.Errorf("expected bezier point count error")
+	}
+}
+
+func TestCombine(t *testing.T) {
+	actions := func() []Action {
+		return []Action{{
+			Name: "a",
+			Considerations: []Consideration{
+				{Input: "const", Args: ds.Args{"value": 0.5}},
+				{Input: "const", Args: ds.Args{"value": 0.8}},
+			},
+		}}
+	}
+	tests := []struct {
+		combine  Combine
+		expected float64
+	}{
+		{CombineMultiply, 0.4 + 0.6*0.5*0.4},
+		{CombineAverage, 0.65},
+		{CombineMin, 0.5},
+	}
+	for _, test := range tests {
+		brain, err := NewBrain("test", actions(), statsInputs())
+		if err != nil {
+			t.Fatal(err)
+		}
+		brain.Combine = test.combine
+		scores := brain.Evaluate(&Context{}, "")
+		if !approx(scores[0].Score, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.combine, test.expected, scores[0].Score)
+		}
+	}
+
+	// Go curves are used instead of the curve definition
+	brain, _ := NewBrain("test", []Action{{
+		Name:   "a",
+		Weight: 2,
+		Considerations: []Consideration{
+			{Input: "const", Args: ds.Args{"value": 0.5}, Func: interp.LineFunc{Slope: 0, Intercept: 0.25}},
+		},
+	}}, statsInputs())
+	if scores := brain.Evaluate(&Context{}, ""); !approx(scores[0].Score, 0.5) {
+		t.Errorf("expected weighted Go curve, got %v", scores[0].Score)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	consider := func(input string) []Consideration {
+		return []Consideration{{Input: input}}
+	}
+	tests := []struct {
+		actions []Action
+		err     string
+	}{
+		{[]Action{{Name: "a", Considerations: consider("ammo")}}, `a/considerations[0]: unregistered input "ammo"`},
+		{[]Action{{Name: "a", Considerations: []Consideration{{Input: "health", Curve: CurveDef{Type: "spline"}}}}}, `unknown curve type "spline"`},
+		{[]Action{{Name: "a"}, {Name: "a"}}, `duplicate action "a"`},
+		{[]Action{{Considerations: consider("health")}}, "action needs a name"},
+	}
+	for _, test := range tests {
+		_, err := NewBrain("bad", test.actions, statsInputs())
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("expected error containing %q, got %v", test.err, err)
+		}
+	}
+
+	// The combine mode can only be set by brain files
+	_, err := Loader{Registry: statsInputs()}.Load(nil, []byte("combine: max\nactions: []"))
+	if err == nil || !strings.Contains(err.Error(), "unknown combine mode") {
+		t.Errorf("expected combine mode error, got %v", err)
+	}
+}
+
+func TestReasoner(t *testing.T) {
+	server := brainServer(fstest.MapFS{"goblin.brain.yaml": {Data: []byte(goblin)}})
+	handle := asset.Load[Brain](server, "goblin.brain.yaml")
+	if _, err := handle.Get(); err != nil {
+		t.Fatal(err)
+	}
+
+	world := ecs.NewWorld()
+	id := world.NewId()
+	world.Write(id, ecs.C(stats{Health: 100, Distance: 2}))
+	r := NewReasoner(handle)
+
+	if !r.Update(world, id) || r.Action != "attack" {
+		t.Errorf("expected attack to be chosen, got %q", r.Action)
+	}
+
+	// Inertia keeps the current action while the scores are close
+	world.Write(id, ecs.C(stats{Health: 15, Distance: 1.7}))
+	r.Update(world, id)
+	scores := r.Scores()
+	if r.Action != "attack" || scores[1].Score <= scores[0].Score-0.05 {
+		t.Errorf("expected inertia to keep attack, got %q with %v", r.Action, scores)
+	}
+
+	world.Write(id, ecs.C(stats{Health: 5, Distance: 9.5}))
+	if !r.Update(world, id) || r.Action != "flee" {
+		t.Errorf("expected flee to be chosen, got %q", r.Action)
+	}
+
+	debug := r.DebugString()
+	if !strings.HasPrefix(debug, "flee: ") || !strings.Contains(debug, "(chosen)\n  health = 5: ") || !strings.Contains(debug, "  distance = 9.5: 0.05") {
+		t.Errorf("unexpected debug output:\n%s", debug)
+	}
+
+	// No positive scores means no action
+	world.Write(id, ecs.C(stats{Health: 100, Distance: 10}))
+	if !r.Update(world, id) || r.Action != "" {
+		t.Errorf("expected no action, got %q", r.Action)
+	}
+}
+
+func TestPluginReload(t *testing.T) {
+	files := fstest.MapFS{
+		"goblin.brain.yaml": {Data: []byte(goblin), ModTime: time.Unix(1, 0)},
+	}
+	server := brainServer(files)
+
+	app := flow.NewApp()
+	app.SetFixedTimeStep(10 * time.Millisecond)
+	app.AddPlugin(Plugin{Server: server, ReloadInterval: 10 * time.Millisecond})
+	err := app.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	world := app.World()
+	reader := flow.NewEventReader[Chosen](world)
+
+	handle := asset.Load[Brain](server, "goblin.brain.yaml")
+	handle.Wait()
+	id := world.NewId()
+	world.Write(id, ecs.C(stats{Health: 100, Distance: 2}), NewReasoner(handle))
+
+	// Runs one update, then waits for any reloads that it started, and returns the actions that were chosen
+	step := func() []Chosen {
+		app.Step(10 * time.Millisecond)
+		for server.Pending() > 0 {
+			time.Sleep(time.Millisecond)
+		}
+		var chosen []Chosen
+		for e := range reader.Read() {
+			chosen = append(chosen, e)
+		}
+		return chosen
+	}
+
+	if chosen := step(); len(chosen) != 1 || chosen[0] != (Chosen{id, "attack", ""}) {
+		t.Fatalf("expected attack to be chosen, got %v", chosen)
+	}
+
+	// Give flee a constant score
+	files["goblin.brain.yaml"] = &fstest.MapFile{
+		Data:    []byte(strings.Replace(goblin, "input: health", "input: const\n        args: {value: 1}", 1)),
+		ModTime: time.Unix(2, 0),
+	}
+	step() // Notices the change, and reloads the brain
+	if chosen := step(); len(chosen) != 1 || chosen[0] != (Chosen{id, "flee", "attack"}) {
+		t.Errorf("expected the reloaded brain to choose flee, got %v", chosen)
+	}
+}